@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditAction names a worker lifecycle action in the audit log.
+type AuditAction string
+
+const (
+	AuditWorkerStart   AuditAction = "start"
+	AuditWorkerReady   AuditAction = "ready"
+	AuditWorkerCrash   AuditAction = "crash"
+	AuditWorkerRestart AuditAction = "restart"
+	AuditWorkerKill    AuditAction = "kill"
+	AuditScaleUp       AuditAction = "scale_up"
+	AuditScaleDown     AuditAction = "scale_down"
+	AuditWorkerAdopt   AuditAction = "adopt"
+	AuditWorkerFail    AuditAction = "fail"
+)
+
+// AuditRecord is one line of the audit log.
+type AuditRecord struct {
+	Time     time.Time      `json:"time"`
+	Action   AuditAction    `json:"action"`
+	WorkerID int            `json:"worker_id"`
+	Port     int            `json:"port"`
+	Reason   string         `json:"reason,omitempty"`
+	Fields   map[string]any `json:"fields,omitempty"`
+}
+
+// auditFlushInterval bounds how long a record can sit in the write buffer.
+const auditFlushInterval = time.Second
+
+// AuditLog is an append-only, newline-delimited JSON record of worker
+// lifecycle actions, kept separate from the operational log. Writes are
+// buffered and flushed every auditFlushInterval and on Close.
+// A nil *AuditLog is valid and discards everything.
+type AuditLog struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+	buf  *bufio.Writer
+	done chan struct{}
+}
+
+// OpenAuditLog opens (or creates) path for appending and starts the
+// background flusher.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	f, err := openAuditFile(path)
+	if err != nil {
+		return nil, err
+	}
+	a := &AuditLog{path: path, f: f, buf: bufio.NewWriter(f), done: make(chan struct{})}
+	go a.flushLoop()
+	return a, nil
+}
+
+func openAuditFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	return f, nil
+}
+
+// Record appends an audit entry for w. kv is a list of alternating string
+// keys and values, as with slog.
+func (a *AuditLog) Record(action AuditAction, w *Worker, reason string, kv ...any) {
+	if a == nil {
+		return
+	}
+	rec := AuditRecord{Time: time.Now().UTC(), Action: action, WorkerID: w.ID, Port: w.Port, Reason: reason}
+	if len(kv) > 0 {
+		rec.Fields = make(map[string]any, len(kv)/2)
+		for i := 0; i+1 < len(kv); i += 2 {
+			if k, ok := kv[i].(string); ok {
+				rec.Fields[k] = kv[i+1]
+			}
+		}
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		logger("audit").Error("failed to encode audit record", "action", string(action), "error", err)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.buf == nil {
+		return // closed
+	}
+	// A single buffered write per record keeps lines whole even when the
+	// buffer is flushed mid-stream.
+	if _, err := a.buf.Write(append(line, '\n')); err != nil {
+		logger("audit").Error("failed to write audit record", "path", a.path, "error", err)
+	}
+}
+
+// Reopen flushes and reopens the file at the same path, so external log
+// rotation (rename, then signal) starts a fresh file. Called on SIGHUP.
+func (a *AuditLog) Reopen() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.buf == nil {
+		return nil
+	}
+	if err := a.buf.Flush(); err != nil {
+		return fmt.Errorf("flush audit log: %w", err)
+	}
+	f, err := openAuditFile(a.path)
+	if err != nil {
+		return err
+	}
+	old := a.f
+	a.f = f
+	a.buf.Reset(f)
+	return old.Close()
+}
+
+// Close flushes any buffered records and closes the file. Records after
+// Close are dropped.
+func (a *AuditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.buf == nil {
+		return nil
+	}
+	close(a.done)
+	err := a.buf.Flush()
+	if cerr := a.f.Close(); err == nil {
+		err = cerr
+	}
+	a.buf = nil
+	return err
+}
+
+func (a *AuditLog) flushLoop() {
+	ticker := time.NewTicker(auditFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-ticker.C:
+			a.mu.Lock()
+			if a.buf != nil && a.buf.Buffered() > 0 {
+				if err := a.buf.Flush(); err != nil {
+					logger("audit").Error("failed to flush audit log", "path", a.path, "error", err)
+				}
+			}
+			a.mu.Unlock()
+		}
+	}
+}
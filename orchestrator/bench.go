@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"steel-orchestrator/client"
+)
+
+// benchConfig holds the load shape for the bench subcommand.
+type benchConfig struct {
+	Concurrency    int
+	Sessions       int
+	Hold           time.Duration
+	StatusInterval time.Duration
+	Body           string
+}
+
+var benchOpts benchConfig
+
+func benchFlags(fs *flag.FlagSet) {
+	fs.IntVar(&benchOpts.Concurrency, "concurrency", 10, "concurrent session lifecycles (create → hold → delete)")
+	fs.IntVar(&benchOpts.Sessions, "sessions", 100, "total sessions to create")
+	fs.DurationVar(&benchOpts.Hold, "hold", time.Second, "how long each session is held before it is deleted")
+	fs.DurationVar(&benchOpts.StatusInterval, "status-interval", 250*time.Millisecond, "how often /status is sampled to track scaling")
+	fs.StringVar(&benchOpts.Body, "body", `{"bench":true}`, "JSON body sent with every create")
+
+	// A run usually outlasts the default request timeout of the other commands.
+	if t := fs.Lookup("timeout"); t != nil {
+		t.DefValue = "10m"
+		_ = t.Value.Set(t.DefValue)
+	}
+}
+
+// benchSample is one /status observation taken during a run.
+type benchSample struct {
+	AtMs           float64 `json:"at_ms"`
+	Workers        int     `json:"workers"`
+	Available      int     `json:"available"`
+	ActiveSessions int     `json:"active_sessions"`
+}
+
+// benchReport is the result of a bench run.
+type benchReport struct {
+	Concurrency int     `json:"concurrency"`
+	Sessions    int     `json:"sessions"`
+	HoldMs      float64 `json:"hold_ms"`
+	DurationMs  float64 `json:"duration_ms"`
+
+	Created        int            `json:"created"`
+	Failed         int            `json:"failed"`
+	ErrorsByStatus map[string]int `json:"errors_by_status"`
+	DeleteFailures int            `json:"delete_failures"`
+	CreatesPerSec  float64        `json:"creates_per_sec"`
+
+	CreateP50Ms float64 `json:"create_p50_ms"`
+	CreateP95Ms float64 `json:"create_p95_ms"`
+	CreateP99Ms float64 `json:"create_p99_ms"`
+	CreateMaxMs float64 `json:"create_max_ms"`
+
+	// Scaling as observed from /status samples. The *Ms fields are -1 when
+	// the pool never grew.
+	WorkersStart   int           `json:"workers_start"`
+	WorkersPeak    int           `json:"workers_peak"`
+	FirstScaleUpMs float64       `json:"first_scale_up_ms"`
+	PeakReachedMs  float64       `json:"peak_reached_ms"`
+	StatusErrors   int           `json:"status_errors"`
+	Samples        []benchSample `json:"samples"`
+}
+
+// cliBench drives the target orchestrator with Sessions create/hold/delete
+// lifecycles, Concurrency at a time, and reports latency, errors and scaling.
+func cliBench(ctx context.Context, c *client.Client, args []string, out io.Writer, asJSON bool) error {
+	opts := benchOpts
+	if len(args) != 0 || opts.Concurrency < 1 || opts.Sessions < 1 || opts.StatusInterval <= 0 || !json.Valid([]byte(opts.Body)) {
+		return usageError{}
+	}
+	// Errors must be counted, not hidden behind the client's retries.
+	c.MaxRetries = 0
+
+	rep := benchReport{
+		Concurrency:    opts.Concurrency,
+		Sessions:       opts.Sessions,
+		HoldMs:         ms(opts.Hold),
+		ErrorsByStatus: map[string]int{},
+	}
+	start := time.Now()
+
+	// Sample /status until the load finishes.
+	sampleCtx, stopSampling := context.WithCancel(ctx)
+	var sampler sync.WaitGroup
+	sampler.Add(1)
+	go func() {
+		defer sampler.Done()
+		ticker := time.NewTicker(opts.StatusInterval)
+		defer ticker.Stop()
+		for {
+			st, err := c.Status(sampleCtx)
+			if sampleCtx.Err() != nil {
+				return
+			}
+			if err != nil {
+				rep.StatusErrors++
+			} else {
+				rep.Samples = append(rep.Samples, benchSample{
+					AtMs: ms(time.Since(start)), Workers: st.WorkerCount,
+					Available: st.AvailableWorkers, ActiveSessions: st.ActiveSessions,
+				})
+			}
+			select {
+			case <-sampleCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		wg        sync.WaitGroup
+		sem       = make(chan struct{}, opts.Concurrency)
+	)
+	for i := 0; i < opts.Sessions && ctx.Err() == nil; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			t0 := time.Now()
+			s, err := c.CreateSession(ctx, json.RawMessage(opts.Body))
+			elapsed := time.Since(t0)
+			mu.Lock()
+			if err != nil {
+				rep.Failed++
+				rep.ErrorsByStatus[benchErrorClass(err)]++
+				mu.Unlock()
+				return
+			}
+			rep.Created++
+			latencies = append(latencies, elapsed)
+			mu.Unlock()
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(opts.Hold):
+			}
+			if err := c.DeleteSession(context.WithoutCancel(ctx), s.ID); err != nil {
+				mu.Lock()
+				rep.DeleteFailures++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	stopSampling()
+	sampler.Wait()
+
+	rep.DurationMs = ms(time.Since(start))
+	rep.CreatesPerSec = float64(rep.Created) / time.Since(start).Seconds()
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		rep.CreateP50Ms = ms(nearestRank(latencies, 0.50))
+		rep.CreateP95Ms = ms(nearestRank(latencies, 0.95))
+		rep.CreateP99Ms = ms(nearestRank(latencies, 0.99))
+		rep.CreateMaxMs = ms(latencies[len(latencies)-1])
+	}
+	rep.summarizeScaling()
+
+	if asJSON {
+		return printJSON(out, rep)
+	}
+	rep.print(out)
+	if ctx.Err() != nil {
+		return fmt.Errorf("run cut short: %w", ctx.Err())
+	}
+	return nil
+}
+
+// summarizeScaling derives pool growth from the /status samples.
+func (r *benchReport) summarizeScaling() {
+	r.FirstScaleUpMs, r.PeakReachedMs = -1, -1
+	if len(r.Samples) == 0 {
+		return
+	}
+	r.WorkersStart = r.Samples[0].Workers
+	r.WorkersPeak = r.WorkersStart
+	for _, s := range r.Samples {
+		if s.Workers > r.WorkersStart && r.FirstScaleUpMs < 0 {
+			r.FirstScaleUpMs = s.AtMs
+		}
+		if s.Workers > r.WorkersPeak {
+			r.WorkersPeak = s.Workers
+			r.PeakReachedMs = s.AtMs
+		}
+	}
+}
+
+func (r *benchReport) print(out io.Writer) {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "load\t%d sessions, %d concurrent, hold %s\n", r.Sessions, r.Concurrency, time.Duration(r.HoldMs*float64(time.Millisecond)))
+	fmt.Fprintf(tw, "duration\t%.1fs (%.1f creates/s)\n", r.DurationMs/1000, r.CreatesPerSec)
+	fmt.Fprintf(tw, "created\t%d ok, %d failed, %d delete failures\n", r.Created, r.Failed, r.DeleteFailures)
+	for _, class := range slices.Sorted(maps.Keys(r.ErrorsByStatus)) {
+		fmt.Fprintf(tw, "  %s\t%d\n", class, r.ErrorsByStatus[class])
+	}
+	fmt.Fprintf(tw, "create latency\tp50 %.0fms  p95 %.0fms  p99 %.0fms  max %.0fms\n", r.CreateP50Ms, r.CreateP95Ms, r.CreateP99Ms, r.CreateMaxMs)
+	switch {
+	case len(r.Samples) == 0:
+		fmt.Fprintf(tw, "scaling\tno /status samples (%d errors)\n", r.StatusErrors)
+	case r.FirstScaleUpMs < 0:
+		fmt.Fprintf(tw, "scaling\tno scale-up (%d workers throughout)\n", r.WorkersStart)
+	default:
+		fmt.Fprintf(tw, "scaling\t%d → %d workers; first scale-up at %.1fs, peak at %.1fs\n", r.WorkersStart, r.WorkersPeak, r.FirstScaleUpMs/1000, r.PeakReachedMs/1000)
+	}
+	tw.Flush()
+}
+
+// benchErrorClass buckets a create error by HTTP status, or "transport" when
+// no response came back.
+func benchErrorClass(err error) string {
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) {
+		return fmt.Sprint(apiErr.StatusCode)
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return "timeout"
+	}
+	return "transport"
+}
+
+func ms(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// chaosJitter spreads chaos kills uniformly over [mean*(1-j), mean*(1+j)] so
+// they do not line up with the health-check or scale ticks.
+const chaosJitter = 0.5
+
+// RunChaos kills one random healthy worker roughly every mean interval, as a
+// continuous soak test of the crash-recovery path (monitor restart,
+// CrashHandler session cleanup). A kill is skipped whenever it would leave
+// fewer than Min healthy workers. Runs until the process exits.
+func (p *Pool) RunChaos(mean time.Duration) {
+	log := logger("chaos")
+	log.Warn("CHAOS MODE ENABLED — workers will be killed at random", "mean_interval", mean.String(), "healthy_floor", p.Min())
+
+	for {
+		time.Sleep(chaosDelay(mean))
+
+		var healthy []*Worker
+		for _, w := range p.Workers() {
+			if s := w.State(); s == WorkerStateAvailable || s == WorkerStateBusy {
+				healthy = append(healthy, w)
+			}
+		}
+		if min := p.Min(); len(healthy) <= min {
+			log.Info("chaos: kill skipped — pool at healthy floor", "healthy", len(healthy), "healthy_floor", min)
+			continue
+		}
+
+		w := healthy[rand.IntN(len(healthy))]
+		ids := w.Sessions()
+		log.Warn("CHAOS: killing worker", "worker_id", w.ID, "port", w.Port, "session_ids", ids, "healthy", len(healthy))
+		p.events.Record(EventChaosKill, "worker_id", w.ID, "port", w.Port, "session_ids", ids)
+		w.Kill("chaos")
+	}
+}
+
+// chaosDelay returns mean with ±chaosJitter uniform jitter applied.
+func chaosDelay(mean time.Duration) time.Duration {
+	return jittered(mean, chaosJitter)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"steel-orchestrator/client"
+)
+
+// cliCommand is an operator subcommand that talks to a running orchestrator.
+type cliCommand struct {
+	usage string
+	run   func(ctx context.Context, c *client.Client, args []string, out io.Writer, asJSON bool) error
+	flags func(fs *flag.FlagSet) // optional command-specific flags
+}
+
+// cliCommands maps "<command> [<subcommand>]" to its implementation. Anything
+// else (including no command, "serve", or a leading flag) starts the server.
+var cliCommands = map[string]cliCommand{
+	"status":          {"status", cliStatus, nil},
+	"sessions list":   {"sessions list", cliSessionsList, nil},
+	"sessions delete": {"sessions delete <id>", cliSessionsDelete, nil},
+	"sessions alias":  {"sessions alias <id> <alias>", cliSessionsAlias, nil},
+	"workers list":    {"workers list", cliWorkersList, nil},
+	"bench":           {"bench", cliBench, benchFlags},
+}
+
+// isCLICommand reports whether name is the first word of a CLI subcommand.
+func isCLICommand(name string) bool {
+	for key := range cliCommands {
+		if strings.Fields(key)[0] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// runCLI executes a client subcommand and returns the process exit code:
+// 0 on success, 1 on API or network errors, 2 on usage errors.
+func runCLI(args []string, out, errOut io.Writer) int {
+	name := args[0]
+	rest := args[1:]
+	if _, ok := cliCommands[name]; !ok && len(rest) > 0 {
+		name += " " + rest[0]
+		rest = rest[1:]
+	}
+	cmd, ok := cliCommands[name]
+	if !ok {
+		fmt.Fprintf(errOut, "unknown command %q\n\ncommands:\n  serve [flags]\n", name)
+		for _, key := range slices.Sorted(maps.Keys(cliCommands)) {
+			fmt.Fprintf(errOut, "  %s\n", cliCommands[key].usage)
+		}
+		return 2
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	addr := fs.String("addr", envOr("ORCH_ADDR", "http://localhost:8080"), "orchestrator base URL (env ORCH_ADDR); use the admin listener for status and workers when --admin-port is set")
+	token := fs.String("token", os.Getenv("ORCH_TOKEN"), "bearer token (env ORCH_TOKEN)")
+	socket := fs.String("unix-socket", os.Getenv("ORCH_UNIX_SOCKET"), "connect over this Unix socket instead of TCP (env ORCH_UNIX_SOCKET); the host in --addr is then ignored")
+	asJSON := fs.Bool("json", false, "print raw JSON instead of a table")
+	timeout := fs.Duration("timeout", 30*time.Second, "overall request timeout")
+	if cmd.flags != nil {
+		cmd.flags(fs)
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(errOut, "usage: %s %s [flags]\n", os.Args[0], cmd.usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(rest); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	opts := []client.Option{client.WithToken(*token)}
+	if *socket != "" {
+		opts = append(opts, client.WithUnixSocket(*socket))
+	}
+	c, err := client.New(*addr, opts...)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 2
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err := cmd.run(ctx, c, fs.Args(), out, *asJSON); err != nil {
+		var usage usageError
+		if errors.As(err, &usage) {
+			fmt.Fprintf(errOut, "usage: %s %s\n", os.Args[0], cmd.usage)
+			return 2
+		}
+		fmt.Fprintf(errOut, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// usageError marks a subcommand called with the wrong arguments.
+type usageError struct{}
+
+func (usageError) Error() string { return "usage" }
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func printJSON(out io.Writer, v any) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func cliStatus(ctx context.Context, c *client.Client, args []string, out io.Writer, asJSON bool) error {
+	if len(args) != 0 {
+		return usageError{}
+	}
+	st, err := c.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(out, st)
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if st.SessionsPerWorker > 1 {
+		fmt.Fprintf(tw, "workers\t%d (min %d, max %d, %d free slots, %d sessions each)\n", st.WorkerCount, st.MinWorkers, st.MaxWorkers, st.AvailableWorkers, st.SessionsPerWorker)
+	} else {
+		fmt.Fprintf(tw, "workers\t%d (min %d, max %d, %d available)\n", st.WorkerCount, st.MinWorkers, st.MaxWorkers, st.AvailableWorkers)
+	}
+	if st.ScaleWindow != "" {
+		fmt.Fprintf(tw, "scale window\t%s (base min %d, max %d; active: %s)\n", st.ScaleWindow, st.BaseMinWorkers, st.BaseMaxWorkers, strings.Join(st.ScaleWindowsActive, ", "))
+	}
+	if st.MaxSessions > 0 {
+		fmt.Fprintf(tw, "sessions\t%d (max %d, %d rejected)\n", st.ActiveSessions, st.MaxSessions, st.SessionLimitRejections)
+	} else {
+		fmt.Fprintf(tw, "sessions\t%d\n", st.ActiveSessions)
+	}
+	if st.Degraded {
+		fmt.Fprintf(tw, "DEGRADED\tall workers unhealthy; creates fail fast\n")
+	}
+	fmt.Fprintf(tw, "scaling\t%s policy, every %s, down after %s, cooldown %s\n", st.Scaling.Policy, st.Scaling.Interval, st.Scaling.ScaleDownAfter, st.Scaling.ScaleUpCooldown)
+	fmt.Fprintf(tw, "in-flight\t%d / %s\n", st.InflightRequests, limitString(st.MaxInflight))
+	fmt.Fprintf(tw, "queue\t%d / %s  (%d rejected as full)\n", st.QueueDepth, limitString(int64(st.MaxQueueDepth)), st.QueueRejections)
+	fmt.Fprintf(tw, "create latency\tp50 %.0fms  p95 %.0fms  avg %.0fms\n", st.CreateLatencyP50Ms, st.CreateLatencyP95Ms, st.CreateLatencyAvgMs)
+	fmt.Fprintf(tw, "acquire wait\tp50 %.0fms  p95 %.0fms  p99 %.0fms  (%d timeouts)\n", st.AcquireWaitP50Ms, st.AcquireWaitP95Ms, st.AcquireWaitP99Ms, st.AcquireTimeouts)
+	fmt.Fprintf(tw, "readiness failures\t%d\n", st.ReadinessFailures)
+	return tw.Flush()
+}
+
+func limitString(n int64) string {
+	if n == 0 {
+		return "unlimited"
+	}
+	return fmt.Sprint(n)
+}
+
+func cliSessionsList(ctx context.Context, c *client.Client, args []string, out io.Writer, asJSON bool) error {
+	if len(args) != 0 {
+		return usageError{}
+	}
+	list, err := c.ListSessions(ctx)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(out, list)
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SESSION\tALIAS\tWORKER\tPOOL\tPORT\tIDLE")
+	for _, s := range list {
+		alias := s.Alias
+		if alias == "" {
+			alias = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%d\t%s\n", s.SessionID, alias, s.WorkerID, s.Pool, s.Port, time.Since(s.LastAccessed).Round(time.Second))
+	}
+	return tw.Flush()
+}
+
+func cliSessionsDelete(ctx context.Context, c *client.Client, args []string, out io.Writer, asJSON bool) error {
+	if len(args) != 1 {
+		return usageError{}
+	}
+	if err := c.DeleteSession(ctx, args[0]); err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(out, map[string]string{"deleted": args[0]})
+	}
+	fmt.Fprintf(out, "deleted %s\n", args[0])
+	return nil
+}
+
+func cliSessionsAlias(ctx context.Context, c *client.Client, args []string, out io.Writer, asJSON bool) error {
+	if len(args) != 2 {
+		return usageError{}
+	}
+	if err := c.SetSessionAlias(ctx, args[0], args[1]); err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(out, map[string]string{"session_id": args[0], "alias": args[1]})
+	}
+	fmt.Fprintf(out, "%s is now also %s\n", args[0], args[1])
+	return nil
+}
+
+func cliWorkersList(ctx context.Context, c *client.Client, args []string, out io.Writer, asJSON bool) error {
+	if len(args) != 0 {
+		return usageError{}
+	}
+	st, err := c.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(out, st.Workers)
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "POOL\tID\tPORT\tSTATE\tUPTIME\tRESTARTS\tCPU\tRSS\tSESSION")
+	for _, w := range st.Workers {
+		sid := strings.Join(w.Sessions, ",")
+		if sid == "" {
+			sid = "-"
+		}
+		rss := "-"
+		if w.RSSBytes > 0 {
+			rss = fmt.Sprintf("%.0fMiB", float64(w.RSSBytes)/(1<<20))
+		}
+		uptime := "-"
+		if w.UptimeSeconds > 0 {
+			uptime = (time.Duration(w.UptimeSeconds) * time.Second).String()
+		}
+		state := w.State
+		if w.Cordoned {
+			state += ",cordoned"
+		}
+		if w.Quarantined {
+			state += ",quarantined"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%d\t%.0f%%\t%s\t%s\n", w.Pool, w.ID, w.Port, state, uptime, w.Restarts, w.CPUPercent, rss, sid)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,531 @@
+// Package client is a typed Go client for the orchestrator HTTP API.
+//
+// Every call honors the context's deadline and cancellation. Responses of 429
+// and 503 (queue full, no worker available) are retried with exponential
+// backoff, respecting Retry-After when the server sends one. Other non-2xx
+// responses are returned as *APIError carrying the server's error message.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults for Client fields left at their zero value.
+const (
+	DefaultMaxRetries = 4
+	DefaultBaseDelay  = 250 * time.Millisecond
+	DefaultMaxDelay   = 5 * time.Second
+)
+
+// Client talks to one orchestrator. The zero value is not usable; create one
+// with New.
+type Client struct {
+	baseURL  string
+	token    string
+	priority string
+	group    string
+
+	// HTTPClient sends the requests. Timeouts should come from the context
+	// passed to each call rather than from the client.
+	HTTPClient *http.Client
+
+	// MaxRetries bounds the retries of a 429/503 response; 0 disables them.
+	MaxRetries int
+
+	// BaseDelay is the first backoff delay, doubled on every retry up to
+	// MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithToken sends "Authorization: Bearer <token>" on every request.
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithPriority sends "X-Priority: <priority>" ("high", "normal" or "low") so
+// creates that have to queue for a worker wait in that lane. "high" needs a
+// token the orchestrator lists in --priority-token.
+func WithPriority(priority string) Option {
+	return func(c *Client) { c.priority = priority }
+}
+
+// WithWorkerGroup sends "X-Worker-Group: <group>" so creates are served by
+// that --worker-pool instead of the default pool.
+func WithWorkerGroup(group string) Option {
+	return func(c *Client) { c.group = group }
+}
+
+// WithHTTPClient replaces the default http.Client.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.HTTPClient = hc }
+}
+
+// WithUnixSocket sends every request over the Unix domain socket at path,
+// for an orchestrator started with --unix-socket. The base URL still needs
+// an http scheme, but its host is ignored; "http://localhost" will do.
+func WithUnixSocket(path string) Option {
+	return func(c *Client) {
+		var d net.Dialer
+		c.HTTPClient = &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return d.DialContext(ctx, "unix", path)
+			},
+		}}
+	}
+}
+
+// New returns a Client for the orchestrator at baseURL, e.g.
+// "http://localhost:8080".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("base URL %q: scheme must be http or https", baseURL)
+	}
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+		MaxRetries: DefaultMaxRetries,
+		BaseDelay:  DefaultBaseDelay,
+		MaxDelay:   DefaultMaxDelay,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Session is a browser session as returned by the worker that holds it.
+type Session struct {
+	ID        string          `json:"id"`
+	CreatedAt string          `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+
+	// BaseURL is the worker's own address, set only by CreateDirectSession.
+	BaseURL string `json:"base_url,omitempty"`
+}
+
+// SessionInfo is one entry of ListSessions.
+type SessionInfo struct {
+	SessionID    string    `json:"session_id"`
+	WorkerID     int       `json:"worker_id"`
+	Pool         string    `json:"pool"` // "default" or a --worker-pool tag
+	Port         int       `json:"port"`
+	LastAccessed time.Time `json:"last_accessed"`
+	Alias        string    `json:"alias"` // "" = none
+}
+
+// WorkerStatus is one worker in Status.
+type WorkerStatus struct {
+	ID                  int        `json:"id"`
+	Pool                string     `json:"pool"` // "default" or a --worker-pool tag
+	Port                int        `json:"port"`
+	State               string     `json:"state"`
+	SessionID           string     `json:"session_id"`           // the first of Sessions, or ""
+	Sessions            []string   `json:"sessions"`             // every session on the worker; more than one with --sessions-per-worker
+	Binary              string     `json:"binary"`               // the binary the current process was started from
+	Cordoned            bool       `json:"cordoned"`             // takes no new sessions until uncordoned
+	Quarantined         bool       `json:"quarantined"`          // out of the queue after failing health checks, until it recovers or is killed
+	RSSBytes            int64      `json:"rss_bytes"`            // resident memory at the last health sweep; 0 = not sampled
+	CPUPercent          float64    `json:"cpu_percent"`          // CPU usage between the last two health sweeps; 100 = one core
+	AgeSeconds          float64    `json:"age_seconds"`          // since the current process was spawned or adopted
+	UptimeSeconds       float64    `json:"uptime_seconds"`       // same as AgeSeconds
+	IdleSeconds         float64    `json:"idle_seconds"`         // since its last session ended, while Available and empty; 0 otherwise
+	Requests            int64      `json:"requests"`             // requests proxied to the worker, across restarts
+	Restarts            int        `json:"restarts"`             // restart attempts over the worker's life
+	Failures            int        `json:"consecutive_failures"` // crashes or failed restarts in a row; reset once a process runs a minute
+	LastError           string     `json:"last_error"`           // the most recent of those
+	LastHealthCheck     *time.Time `json:"last_health_check"`    // when the last health probe started; nil before the first
+	HealthCheckMs       float64    `json:"health_check_ms"`      // how long it took
+	HealthCheckOK       bool       `json:"health_check_ok"`
+	HealthFailure       string     `json:"health_failure"`        // refused, timeout, bad_status or error; "" if the last probe passed
+	HealthFailureStreak int        `json:"health_failure_streak"` // failed probes in a row; the worker is killed at --health-failures
+}
+
+// BinaryRollout is a pool's worker binary and any canary in progress, as set
+// by PUT /pool/binary.
+type BinaryRollout struct {
+	Binary          string `json:"binary"`
+	Canary          string `json:"canary,omitempty"`
+	CanaryPercent   int    `json:"canary_percent,omitempty"`
+	WorkersOnBinary int    `json:"workers_on_binary"`
+	WorkersOnCanary int    `json:"workers_on_canary"`
+	WorkersOnOther  int    `json:"workers_on_other"` // still on a binary replaced since they started
+}
+
+// FailedWorker is a worker the orchestrator removed after it crash-looped
+// or restarted too often, as listed in Status.
+type FailedWorker struct {
+	ID        int       `json:"id"`
+	Port      int       `json:"port"`
+	State     string    `json:"state"`
+	Reason    string    `json:"reason"` // "crash loop" or "restart rate exceeded"
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+// Status is the response of GET /status.
+type Status struct {
+	ActiveSessions         int                    `json:"active_sessions"`
+	MaxSessions            int                    `json:"max_sessions"` // 0 = unlimited
+	SessionLimitRejections int64                  `json:"session_limit_rejections"`
+	WorkerCount            int                    `json:"worker_count"`
+	AvailableWorkers       int                    `json:"available_workers"` // free session slots in the default pool
+	SessionsPerWorker      int                    `json:"sessions_per_worker"`
+	MinWorkers             int                    `json:"min_workers"`
+	ReadyWorkers           int                    `json:"ready_workers"` // Available or Busy; below ConfiguredMin while workers boot or restart
+	ConfiguredMin          int                    `json:"configured_min"`
+	Condition              string                 `json:"condition"` // "ok", "degraded" (ReadyWorkers below ConfiguredMin) or "critical" (below half of it)
+	WorkersByState         map[string]int         `json:"workers_by_state"`
+	MaxWorkers             int                    `json:"max_workers"`
+	BaseMinWorkers         int                    `json:"base_min_workers"`     // min_workers outside every scale window
+	BaseMaxWorkers         int                    `json:"base_max_workers"`     // max_workers outside every scale window
+	ScaleWindow            string                 `json:"scale_window"`         // the scale window whose limits are in force; "" for none
+	ScaleWindowsActive     []string               `json:"scale_windows_active"` // every scale window that applies now, ScaleWindow first
+	CreateLatencyP50Ms     float64                `json:"create_latency_p50_ms"`
+	CreateLatencyP95Ms     float64                `json:"create_latency_p95_ms"`
+	CreateLatencyAvgMs     float64                `json:"create_latency_avg_ms"`
+	AcquireWaitP50Ms       float64                `json:"acquire_wait_p50_ms"`
+	AcquireWaitP95Ms       float64                `json:"acquire_wait_p95_ms"`
+	AcquireWaitP99Ms       float64                `json:"acquire_wait_p99_ms"`
+	AcquireTimeouts        int64                  `json:"acquire_timeouts"`
+	QueueDepth             int                    `json:"queue_depth"`     // callers blocked in Acquire
+	MaxQueueDepth          int                    `json:"max_queue_depth"` // 0 = unbounded
+	QueueRejections        int64                  `json:"queue_rejections"`
+	QueueByPriority        map[string]int         `json:"queue_depth_by_priority"` // callers blocked in Acquire per X-Priority lane
+	ReadinessFailures      int64                  `json:"readiness_failures"`
+	QuarantinedWorkers     int                    `json:"quarantined_workers"` // default pool workers out of the queue after failing health checks
+	Degraded               bool                   `json:"degraded"`            // every worker is failing; creates fail fast
+	BinaryRollout          BinaryRollout          `json:"binary_rollout"`      // the default pool's
+	InflightRequests       int64                  `json:"inflight_requests"`
+	MaxInflight            int64                  `json:"max_inflight"`
+	Scaling                ScaleStatus            `json:"scaling"`
+	Stats                  PoolStats              `json:"stats"`
+	Pools                  map[string]PoolSummary `json:"pools"`   // by tag, "default" included
+	Workers                []WorkerStatus         `json:"workers"` // every pool's
+	FailedWorkers          []FailedWorker         `json:"failed_workers"`
+}
+
+// PoolSummary is one worker pool's size and load, as listed in Status.Pools.
+// The other top-level Status fields describe the default pool.
+type PoolSummary struct {
+	WorkerCount      int            `json:"worker_count"`
+	AvailableWorkers int            `json:"available_workers"`
+	MinWorkers       int            `json:"min_workers"`
+	ReadyWorkers     int            `json:"ready_workers"`
+	ConfiguredMin    int            `json:"configured_min"`
+	Condition        string         `json:"condition"`
+	WorkersByState   map[string]int `json:"workers_by_state"`
+	MaxWorkers       int            `json:"max_workers"`
+	QueueDepth       int            `json:"queue_depth"`
+	Degraded         bool           `json:"degraded"`
+	Quarantined      int            `json:"quarantined"`
+}
+
+// PoolStats are the pool's lifetime counters reported in Status. They reset
+// only when the orchestrator restarts.
+type PoolStats struct {
+	Acquires          int64 `json:"acquires"`
+	Releases          int64 `json:"releases"`
+	ScaleUps          int64 `json:"scale_ups"`
+	ScaleDowns        int64 `json:"scale_downs"`
+	Crashes           int64 `json:"crashes"`
+	Restarts          int64 `json:"restarts"`
+	FailedWorkers     int64 `json:"failed_workers"`
+	AcquireTimeouts   int64 `json:"acquire_timeouts"`
+	QueueRejections   int64 `json:"queue_rejections"`
+	DegradedRejects   int64 `json:"degraded_rejections"`
+	ReadinessFailures int64 `json:"readiness_failures"`
+	SmokeTestFailures int64 `json:"smoke_test_failures"`
+	Quarantines       int64 `json:"quarantines"`
+	Readmissions      int64 `json:"readmissions"`
+	DeepCheckFailures int64 `json:"deep_check_failures"`
+	PendingAcquires   int64 `json:"pending_acquires"`
+}
+
+// ScaleStatus is the autoscaler configuration reported in Status. Durations
+// are Go duration strings such as "10s".
+type ScaleStatus struct {
+	Policy            string     `json:"policy"`
+	Interval          string     `json:"interval"`
+	ScaleDownAfter    string     `json:"scale_down_after"`
+	ScaleUpCooldown   string     `json:"scale_up_cooldown"`
+	ScaleDownFraction float64    `json:"scale_down_fraction"` // share of the idle surplus one tick may remove; 0 = one worker
+	LastScaleUp       *time.Time `json:"last_scale_up"`
+}
+
+// APIError is a non-2xx response from the orchestrator.
+type APIError struct {
+	StatusCode int
+	Message    string // the "error" field of the JSON envelope, or the raw body
+	Reason     string // the "reason" field, sent with 410 (e.g. "memory_limit")
+
+	// Queue is set on a 503 from a create that timed out waiting for a
+	// worker.
+	Queue *QueueInfo
+}
+
+// QueueInfo describes the orchestrator's waiter queue when a create gave up.
+type QueueInfo struct {
+	Position   int `json:"queue_position"` // 1-based place in line; 0 if not queued
+	Depth      int `json:"queue_depth"`    // creates waiting, including this one
+	Workers    int `json:"workers"`
+	Booting    int `json:"booting"`
+	MaxWorkers int `json:"max_workers"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("orchestrator returned %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 from the orchestrator, e.g. an
+// unknown or expired session.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsGone reports whether err is a 410 from the orchestrator: the session was
+// lost to a worker fault, named by APIError.Reason.
+func IsGone(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusGone
+}
+
+// IsConflict reports whether err is a 409 from the orchestrator: a create
+// returned a session ID that is already live on another worker.
+func IsConflict(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict
+}
+
+// CreateSession creates a session with body as its data. body must be a JSON
+// value; nil sends an empty object.
+func (c *Client) CreateSession(ctx context.Context, body json.RawMessage) (Session, error) {
+	if body == nil {
+		body = json.RawMessage("{}")
+	}
+	var s Session
+	err := c.do(ctx, http.MethodPost, "/sessions", body, &s)
+	return s, err
+}
+
+// CreateDirectSession is CreateSession with ?direct=true: the returned
+// Session carries the serving worker's BaseURL so high-throughput traffic can
+// bypass the proxy. Direct calls do not refresh the session's TTL; keep it
+// alive with periodic GetSession calls through the orchestrator.
+func (c *Client) CreateDirectSession(ctx context.Context, body json.RawMessage) (Session, error) {
+	if body == nil {
+		body = json.RawMessage("{}")
+	}
+	var s Session
+	err := c.do(ctx, http.MethodPost, "/sessions?direct=true", body, &s)
+	return s, err
+}
+
+// GetSession fetches a session and refreshes its TTL.
+func (c *Client) GetSession(ctx context.Context, id string) (Session, error) {
+	var s Session
+	err := c.do(ctx, http.MethodGet, "/sessions/"+url.PathEscape(id), nil, &s)
+	return s, err
+}
+
+// Keepalive refreshes a session's TTL with a GetSession every interval
+// until ctx is done, for sessions used directly on their worker (see
+// CreateDirectSession) or simply held open between calls. Pick an interval
+// well under the orchestrator's --session-ttl. It returns nil once ctx is
+// done, and otherwise the first failed refresh, after GetSession's own
+// retries: IsNotFound or IsGone if the session has ended.
+func (c *Client) Keepalive(ctx context.Context, id string, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("keepalive interval must be positive, got %s", interval)
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+		if _, err := c.GetSession(ctx, id); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// DeleteSession deletes a session and frees its worker.
+func (c *Client) DeleteSession(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/sessions/"+url.PathEscape(id), nil, nil)
+}
+
+// SetSessionAlias gives a session an alias that the session calls accept in
+// place of its ID, replacing any alias it had. An alias held by another
+// session fails with an *APIError whose StatusCode is 409.
+func (c *Client) SetSessionAlias(ctx context.Context, id, alias string) error {
+	body, err := json.Marshal(map[string]string{"alias": alias})
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, "/sessions/"+url.PathEscape(id)+"/alias", body, nil)
+}
+
+// DeleteAllSessions terminates every live session and reports how many were
+// deleted and how many worker-side deletes failed (those mappings are
+// removed regardless).
+func (c *Client) DeleteAllSessions(ctx context.Context) (deleted, failed int, err error) {
+	var resp struct {
+		Deleted int `json:"deleted"`
+		Failed  int `json:"failed"`
+	}
+	err = c.do(ctx, http.MethodDelete, "/sessions", nil, &resp)
+	return resp.Deleted, resp.Failed, err
+}
+
+// ListSessions returns every live session, ordered by session ID.
+func (c *Client) ListSessions(ctx context.Context) ([]SessionInfo, error) {
+	var resp struct {
+		Sessions []SessionInfo `json:"sessions"`
+	}
+	err := c.do(ctx, http.MethodGet, "/sessions", nil, &resp)
+	return resp.Sessions, err
+}
+
+// Status returns pool and worker state. When the orchestrator runs with
+// --admin-port, point a separate Client at the admin listener for this.
+func (c *Client) Status(ctx context.Context) (Status, error) {
+	var s Status
+	err := c.do(ctx, http.MethodGet, "/status", nil, &s)
+	return s, err
+}
+
+// do sends one API call, retrying 429/503 with backoff, and decodes a 2xx
+// JSON response into out (if non-nil).
+func (c *Client) do(ctx context.Context, method, path string, body []byte, out any) error {
+	delay := c.BaseDelay
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, body)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("%s %s: read response: %w", method, path, err)
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			if out == nil || len(data) == 0 {
+				return nil
+			}
+			if err := json.Unmarshal(data, out); err != nil {
+				return fmt.Errorf("%s %s: decode response: %w", method, path, err)
+			}
+			return nil
+		}
+
+		apiErr := parseAPIError(resp.StatusCode, data)
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+		if !retryable || attempt >= c.MaxRetries {
+			return apiErr
+		}
+
+		wait := delay
+		if ra, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			wait = ra
+		}
+		if c.MaxDelay > 0 && wait > c.MaxDelay {
+			wait = c.MaxDelay
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("%w (last response: %v)", ctx.Err(), apiErr)
+		case <-t.C:
+		}
+		delay *= 2
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, rd)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.group != "" {
+		req.Header.Set("X-Worker-Group", c.group)
+	}
+	if c.priority != "" {
+		req.Header.Set("X-Priority", c.priority)
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	return resp, nil
+}
+
+// parseAPIError builds an APIError from the {"error": "...", ...} envelope,
+// falling back to the trimmed body for responses that do not use it.
+func parseAPIError(status int, data []byte) *APIError {
+	var env struct {
+		Error  string `json:"error"`
+		Reason string `json:"reason"`
+		QueueInfo
+	}
+	if json.Unmarshal(data, &env) != nil || env.Error == "" {
+		return &APIError{StatusCode: status, Message: strings.TrimSpace(string(data))}
+	}
+	apiErr := &APIError{StatusCode: status, Message: env.Error, Reason: env.Reason}
+	if env.Depth > 0 { // the depth counts the caller itself, so it is never 0 when sent
+		apiErr.Queue = &env.QueueInfo
+	}
+	return apiErr
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,154 @@
+// Package clocktest provides a manually advanced clock.Clock for tests.
+package clocktest
+
+import (
+	"sync"
+	"time"
+
+	"steel-orchestrator/clock"
+)
+
+// Fake is a clock.Clock whose time only moves when Advance is called.
+// Tickers, After channels and Sleep calls fire as Advance passes their
+// deadlines. It is safe for concurrent use.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+	changed chan struct{} // closed and replaced whenever waiters changes
+}
+
+// waiter is a pending After/Sleep (period 0) or a ticker (period > 0).
+type waiter struct {
+	at     time.Time
+	period time.Duration
+	ch     chan time.Time
+}
+
+// NewFake returns a Fake whose current time is start.
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start, changed: make(chan struct{})}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) Since(t time.Time) time.Duration { return f.Now().Sub(t) }
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &waiter{at: f.now.Add(d), ch: make(chan time.Time, 1)}
+	f.addLocked(w)
+	return w.ch
+}
+
+func (f *Fake) Sleep(d time.Duration) { <-f.After(d) }
+
+func (f *Fake) NewTicker(d time.Duration) clock.Ticker {
+	if d <= 0 {
+		panic("clocktest: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &waiter{at: f.now.Add(d), period: d, ch: make(chan time.Time, 1)}
+	f.addLocked(w)
+	return &fakeTicker{f: f, w: w}
+}
+
+// Advance moves the clock forward by d, firing every ticker, After and Sleep
+// whose deadline falls within it in deadline order. A ticker that is due
+// several times fires once per period, dropping ticks its reader has not
+// consumed, like time.Ticker.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	end := f.now.Add(d)
+	for {
+		next := f.nextLocked(end)
+		if next == nil {
+			break
+		}
+		f.now = next.at
+		select {
+		case next.ch <- f.now:
+		default:
+		}
+		if next.period > 0 {
+			next.at = next.at.Add(next.period)
+		} else {
+			f.removeLocked(next)
+		}
+	}
+	f.now = end
+}
+
+// BlockUntil waits until at least n tickers, After channels or sleepers are
+// pending. Use it before Advance when the code under test starts its ticker
+// in a goroutine.
+func (f *Fake) BlockUntil(n int) {
+	for {
+		f.mu.Lock()
+		if len(f.waiters) >= n {
+			f.mu.Unlock()
+			return
+		}
+		changed := f.changed
+		f.mu.Unlock()
+		<-changed
+	}
+}
+
+func (f *Fake) nextLocked(end time.Time) *waiter {
+	var next *waiter
+	for _, w := range f.waiters {
+		if !w.at.After(end) && (next == nil || w.at.Before(next.at)) {
+			next = w
+		}
+	}
+	return next
+}
+
+func (f *Fake) addLocked(w *waiter) {
+	f.waiters = append(f.waiters, w)
+	f.notifyLocked()
+}
+
+func (f *Fake) removeLocked(w *waiter) {
+	for i, existing := range f.waiters {
+		if existing == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			f.notifyLocked()
+			return
+		}
+	}
+}
+
+func (f *Fake) notifyLocked() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+type fakeTicker struct {
+	f *Fake
+	w *waiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.w.ch }
+
+func (t *fakeTicker) Stop() {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.removeLocked(t.w)
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.removeLocked(t.w)
+	t.w.at, t.w.period = t.f.now.Add(d), d
+	t.f.addLocked(t.w)
+}
@@ -0,0 +1,92 @@
+package main
+
+import "time"
+
+// Pool conditions, from ready workers against the configured minimum. They
+// are separate from Degraded, which is the narrower "every worker is
+// failing" state that makes Acquire fail fast.
+const (
+	conditionOK       = "ok"       // at least min workers ready
+	conditionDegraded = "degraded" // some, but fewer than min, ready
+	conditionCritical = "critical" // fewer than half of min ready
+)
+
+// PoolCondition summarises a pool's health for /status and /readyz: its
+// condition and how many workers are in each state, so dashboards need not
+// walk the workers array.
+type PoolCondition struct {
+	Condition     string         `json:"condition"`
+	ReadyWorkers  int            `json:"ready_workers"` // Available or Busy
+	ConfiguredMin int            `json:"configured_min"`
+	States        map[string]int `json:"workers_by_state"`
+}
+
+// ConditionSnapshot is the pool's condition as computed by a health sweep.
+type ConditionSnapshot struct {
+	PoolCondition
+	At time.Time `json:"checked_at"`
+}
+
+// SweptCondition returns the condition recorded by the latest health sweep,
+// without locking or scanning the workers, for endpoints that are polled
+// often. Before the first sweep it computes one on the spot.
+func (p *Pool) SweptCondition() ConditionSnapshot {
+	if c := p.swept.Load(); c != nil {
+		return *c
+	}
+	return ConditionSnapshot{PoolCondition: p.Condition(), At: p.clk.Now()}
+}
+
+// Condition computes the pool's condition and, when it differs from the last
+// one seen, logs the change and records a pool_condition event. Changes are
+// only tracked once the initial workers are up. The health sweep calls it
+// every interval, so a change is reported even when nobody is polling
+// /status.
+func (p *Pool) Condition() PoolCondition {
+	c := PoolCondition{
+		ConfiguredMin: p.Min(),
+		States: map[string]int{
+			WorkerStateStarting.String():  0,
+			WorkerStateAvailable.String(): 0,
+			WorkerStateBusy.String():      0,
+			WorkerStateUnhealthy.String(): 0,
+			WorkerStateDead.String():      0,
+		},
+	}
+	for _, w := range p.Workers() {
+		s := w.State()
+		c.States[s.String()]++
+		if s == WorkerStateAvailable || s == WorkerStateBusy {
+			c.ReadyWorkers++
+		}
+	}
+	switch {
+	case c.ReadyWorkers >= c.ConfiguredMin:
+		c.Condition = conditionOK
+	case c.ReadyWorkers*2 < c.ConfiguredMin:
+		c.Condition = conditionCritical
+	default:
+		c.Condition = conditionDegraded
+	}
+
+	select {
+	case <-p.readyCh:
+	default:
+		return c // initial workers still booting: not a change worth reporting
+	}
+	p.mu.Lock()
+	prev := p.condition
+	p.condition = c.Condition
+	p.mu.Unlock()
+	if prev != c.Condition {
+		log := p.logger().With("from", prev, "to", c.Condition, "ready_workers", c.ReadyWorkers, "min_workers", c.ConfiguredMin)
+		if c.Condition == conditionOK {
+			log.Info("pool condition changed")
+		} else {
+			log.Warn("pool condition changed")
+		}
+		p.events.Record(EventPoolCondition, "pool", p.Tag(), "from", prev, "to", c.Condition,
+			"ready_workers", c.ReadyWorkers, "min_workers", c.ConfiguredMin)
+	}
+	return c
+}
@@ -0,0 +1,900 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is a time.Duration that reads and writes as a Go duration string
+// ("60s", "5m") in config files.
+type Duration time.Duration
+
+func (d Duration) String() string { return time.Duration(d).String() }
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"60s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Config holds every orchestrator setting. Each field is settable by a flag
+// (named in its flag tag), by the matching key in the --config JSON file, and
+// by an ORCH_-prefixed environment variable. Precedence, highest first:
+// explicit flag, environment, config file, default.
+type Config struct {
+	ConfigPath string `json:"-" flag:"config"`
+
+	MinWorkers  int `json:"min_workers" flag:"min-workers"`
+	StartQuorum int `json:"start_quorum" flag:"start-quorum"`
+	MaxWorkers  int `json:"max_workers" flag:"max-workers"`
+	WarmBuffer  int `json:"warm_buffer" flag:"warm-buffer"`
+	MaxBoots    int `json:"max_concurrent_boots" flag:"max-concurrent-boots"`
+
+	CrashLoopLimit    int      `json:"crash_loop_limit" flag:"crash-loop-limit"`
+	RestartDelay      Duration `json:"restart_delay" flag:"restart-delay"`
+	MaxRestartsPerMin int      `json:"max_restarts_per_minute" flag:"max-restarts-per-minute"`
+
+	ScalePolicy          string   `json:"scale_policy" flag:"scale-policy"`
+	ScaleInterval        Duration `json:"scale_interval" flag:"scale-interval"`
+	ScaleDownAfter       Duration `json:"scale_down_after" flag:"scale-down-after"`
+	ScaleUpCooldown      Duration `json:"scale_up_cooldown" flag:"scale-up-cooldown"`
+	ScaleTargetWait      Duration `json:"scale_target_wait" flag:"scale-target-wait"`
+	ScaleDownUtilization float64  `json:"scale_down_utilization" flag:"scale-down-utilization"`
+	ScaleDownFraction    float64  `json:"scale_down_fraction" flag:"scale-down-fraction"`
+	ScaleWindows         []string `json:"scale_windows" flag:"scale-window"`
+	ScaleWindowLead      Duration `json:"scale_window_lead" flag:"scale-window-lead"`
+
+	Port       int    `json:"port" flag:"port"`
+	AdminPort  int    `json:"admin_port" flag:"admin-port"`
+	UnixSocket string `json:"unix_socket" flag:"unix-socket"`
+	PortRange  string `json:"port_range" flag:"port-range"`
+	Binary     string `json:"binary" flag:"binary"`
+
+	WorkerPools   []string `json:"worker_pools" flag:"worker-pool"`
+	WorkerPoolEnv []string `json:"worker_pool_env" flag:"worker-pool-env"`
+
+	WorkerArgs        []string `json:"worker_args" flag:"worker-arg"`
+	WorkerEnv         []string `json:"worker_env" flag:"worker-env"`
+	WorkerMemoryMaxMB int64    `json:"worker_memory_max_mb" flag:"worker-memory-max-mb"`
+	WorkerMemLimitMB  int64    `json:"worker_memory_limit_mb" flag:"worker-memory-limit-mb"`
+	WorkerMemEstMB    int64    `json:"worker_memory_estimate_mb" flag:"worker-memory-estimate-mb"`
+	PoolMemBudgetMB   int64    `json:"pool_memory_budget_mb" flag:"pool-memory-budget-mb"`
+	WorkerCPUMax      float64  `json:"worker_cpu_max" flag:"worker-cpu-max"`
+	WorkerCgroup      string   `json:"worker_cgroup" flag:"worker-cgroup"`
+
+	SessionTTL          Duration `json:"session_ttl" flag:"session-ttl"`
+	SweepInterval       Duration `json:"sweep_interval" flag:"sweep-interval"`
+	HealthCheckInterval Duration `json:"health_check_interval" flag:"health-check-interval"`
+	HealthGrace         Duration `json:"health_grace" flag:"health-grace"`
+	HealthFailures      int      `json:"health_failures" flag:"health-failures"`
+	QuarantineTimeout   Duration `json:"quarantine_timeout" flag:"quarantine-timeout"`
+	QuarantineInterval  Duration `json:"quarantine_interval" flag:"quarantine-interval"`
+	QuarantineSuccesses int      `json:"quarantine_successes" flag:"quarantine-successes"`
+	DeepCheckEvery      int      `json:"deep_check_every" flag:"deep-check-every"`
+	DeepCheckPath       string   `json:"deep_check_path" flag:"deep-check-path"`
+	MaxWorkerAge        Duration `json:"max_worker_age" flag:"max-worker-age"`
+	ReadyTimeout        Duration `json:"ready_timeout" flag:"ready-timeout"`
+	WaitReady           Duration `json:"wait_ready_at_startup" flag:"wait-ready-at-startup"`
+
+	HealthPath     string `json:"health_path" flag:"health-path"`
+	HealthStatus   []int  `json:"health_status" flag:"health-status"`
+	ReadySmokeTest string `json:"ready_smoke_test" flag:"ready-smoke-test"`
+
+	WorkerCreateTimeout Duration `json:"worker_create_timeout" flag:"worker-create-timeout"`
+	WorkerGetTimeout    Duration `json:"worker_get_timeout" flag:"worker-get-timeout"`
+	WorkerDeleteTimeout Duration `json:"worker_delete_timeout" flag:"worker-delete-timeout"`
+
+	ProxyMaxIdleConns        int      `json:"proxy_max_idle_conns" flag:"proxy-max-idle-conns"`
+	ProxyMaxIdleConnsPerHost int      `json:"proxy_max_idle_conns_per_host" flag:"proxy-max-idle-conns-per-host"`
+	ProxyIdleConnTimeout     Duration `json:"proxy_idle_conn_timeout" flag:"proxy-idle-conn-timeout"`
+
+	ReadHeaderTimeout Duration `json:"read_header_timeout" flag:"read-header-timeout"`
+	ReadTimeout       Duration `json:"read_timeout" flag:"read-timeout"`
+	WriteTimeout      Duration `json:"write_timeout" flag:"write-timeout"`
+	IdleTimeout       Duration `json:"idle_timeout" flag:"idle-timeout"`
+
+	MaxBodyBytes  int64 `json:"max_body_bytes" flag:"max-body-bytes"`
+	MaxInFlight   int   `json:"max_inflight" flag:"max-inflight"`
+	MaxQueueDepth int   `json:"max_queue_depth" flag:"max-queue-depth"`
+	MaxSessions   int   `json:"max_sessions" flag:"max-sessions"`
+
+	SessionsPerWorker int `json:"sessions_per_worker" flag:"sessions-per-worker"`
+
+	CreateRequired []string `json:"create_required_fields" flag:"create-required-field"`
+	PriorityTokens []string `json:"priority_tokens" flag:"priority-token" secret:"true"`
+
+	Debug         bool     `json:"debug" flag:"debug"`
+	Chaos         bool     `json:"chaos" flag:"chaos"`
+	ChaosInterval Duration `json:"chaos_interval" flag:"chaos-interval"`
+
+	LogFormat string `json:"log_format" flag:"log-format"`
+	LogLevel  string `json:"log_level" flag:"log-level"`
+	AuditLog  string `json:"audit_log" flag:"audit-log"`
+	StateFile string `json:"state_file" flag:"state-file"`
+
+	OtelEndpoint string `json:"otel_endpoint" flag:"otel-endpoint"`
+
+	ShutdownTimeout   Duration `json:"shutdown_timeout" flag:"shutdown-timeout"`
+	WorkerStopTimeout Duration `json:"worker_stop_timeout" flag:"worker-stop-timeout"`
+	ShutdownGrace     Duration `json:"shutdown_grace" flag:"shutdown-grace"`
+
+	// Sources records where each flag's effective value came from:
+	// "flag", "env", "file" or "default". Keyed by flag name.
+	Sources map[string]string `json:"-" flag:"-"`
+}
+
+// Config value sources, in increasing order of precedence.
+const (
+	sourceDefault = "default"
+	sourceFile    = "file"
+	sourceEnv     = "env"
+	sourceFlag    = "flag"
+)
+
+// envPrefix is prepended to the upper-cased, underscored flag name to form the
+// environment variable consulted when a flag is not given explicitly.
+const envPrefix = "ORCH_"
+
+// envName returns the environment variable for a flag, e.g. min-workers → ORCH_MIN_WORKERS.
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		MinWorkers:               2,
+		MaxWorkers:               10,
+		Port:                     8080,
+		Binary:                   "./steel-browser",
+		WarmBuffer:               1,
+		MaxBoots:                 2,
+		CrashLoopLimit:           5,
+		RestartDelay:             Duration(time.Second),
+		MaxRestartsPerMin:        5,
+		WorkerMemEstMB:           512,
+		ScalePolicy:              ScalePolicyIdle,
+		ScaleInterval:            Duration(10 * time.Second),
+		ScaleDownAfter:           Duration(20 * time.Second),
+		ScaleTargetWait:          Duration(2 * time.Second),
+		ScaleDownUtilization:     0.5,
+		ScaleWindowLead:          Duration(defaultScaleWindowLead),
+		SessionTTL:               Duration(60 * time.Second),
+		SweepInterval:            Duration(5 * time.Second),
+		HealthCheckInterval:      Duration(5 * time.Second),
+		HealthFailures:           3,
+		QuarantineInterval:       Duration(time.Second),
+		QuarantineSuccesses:      3,
+		ReadyTimeout:             Duration(6 * time.Second),
+		HealthPath:               "/health",
+		HealthStatus:             []int{200},
+		ShutdownTimeout:          Duration(10 * time.Second),
+		WorkerStopTimeout:        Duration(2 * time.Second),
+		ShutdownGrace:            Duration(3 * time.Second),
+		WorkerCreateTimeout:      Duration(10 * time.Second),
+		WorkerGetTimeout:         Duration(5 * time.Second),
+		WorkerDeleteTimeout:      Duration(5 * time.Second),
+		ProxyMaxIdleConns:        1024,
+		ProxyMaxIdleConnsPerHost: 128,
+		ProxyIdleConnTimeout:     Duration(90 * time.Second),
+		ReadHeaderTimeout:        Duration(10 * time.Second),
+		ReadTimeout:              Duration(30 * time.Second),
+		IdleTimeout:              Duration(2 * time.Minute),
+		MaxBodyBytes:             1 << 20,
+		MaxInFlight:              512,
+		SessionsPerWorker:        1,
+		ChaosInterval:            Duration(30 * time.Second),
+		LogFormat:                "text",
+		LogLevel:                 "info",
+	}
+}
+
+// newFlagSet binds every flag to the corresponding field of cfg, using the
+// field's current value as the default.
+func newFlagSet(cfg *Config, errOut io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("orchestrator", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	fs.StringVar(&cfg.ConfigPath, "config", cfg.ConfigPath, "path to a JSON config file mirroring these flags (reloaded on SIGHUP)")
+	fs.IntVar(&cfg.MinWorkers, "min-workers", cfg.MinWorkers, "minimum (starting) number of worker processes; 0 = scale to zero when idle and cold-start a worker on the next create")
+	fs.IntVar(&cfg.StartQuorum, "start-quorum", cfg.StartQuorum, "initial workers that must spawn for startup to succeed; 0 = all of min-workers")
+	fs.IntVar(&cfg.MaxWorkers, "max-workers", cfg.MaxWorkers, "maximum number of worker processes (auto-scaling ceiling)")
+	fs.IntVar(&cfg.WarmBuffer, "warm-buffer", cfg.WarmBuffer, "idle workers kept ready ahead of demand while below max-workers; 0 = scale up only when a request finds the pool empty")
+	fs.IntVar(&cfg.MaxBoots, "max-concurrent-boots", cfg.MaxBoots, "most scale-up workers whose processes start and boot at once; the rest of a batch keeps its reserved slot and starts as these become ready (0 = unlimited)")
+	fs.IntVar(&cfg.CrashLoopLimit, "crash-loop-limit", cfg.CrashLoopLimit, "consecutive crashes or failed restarts after which a worker is removed and replaced by a new one (0 = keep restarting)")
+	fs.Var((*durationFlag)(&cfg.RestartDelay), "restart-delay", "delay before restarting an exited worker; doubles with each consecutive failure up to 30s")
+	fs.IntVar(&cfg.MaxRestartsPerMin, "max-restarts-per-minute", cfg.MaxRestartsPerMin, "restarts one worker may make per minute, as a token bucket; past it the worker is removed and replaced by a new one (0 = unlimited)")
+	fs.StringVar(&cfg.ScalePolicy, "scale-policy", cfg.ScalePolicy, "autoscaling policy: idle (remove workers idle past scale-down-after) or latency (track scale-target-wait)")
+	fs.Var((*durationFlag)(&cfg.ScaleInterval), "scale-interval", "how often the autoscaler evaluates the pool")
+	fs.Var((*durationFlag)(&cfg.ScaleDownAfter), "scale-down-after", "idle policy: how long a worker must hold no session before it is removed")
+	fs.Var((*durationFlag)(&cfg.ScaleUpCooldown), "scale-up-cooldown", "no scale-down for this long after the last scale-up (0 = none)")
+	fs.Var((*durationFlag)(&cfg.ScaleTargetWait), "scale-target-wait", "latency policy: p95 Acquire wait above which workers are added")
+	fs.Float64Var(&cfg.ScaleDownUtilization, "scale-down-utilization", cfg.ScaleDownUtilization, "latency policy: busy/total fraction below which idle workers are removed")
+	fs.Float64Var(&cfg.ScaleDownFraction, "scale-down-fraction", cfg.ScaleDownFraction, "share of the idle surplus (idle workers beyond warm-buffer and min-workers) one scale-down tick may remove, newest first; 0 = one worker per tick")
+	fs.Var(&listFlag{dst: &cfg.ScaleWindows}, "scale-window", "daily window overriding min-workers and max-workers as name=HH:MM-HH:MM min=N max=N [days=mon-fri], in local time; overlapping windows take the larger min (repeatable)")
+	fs.Var((*durationFlag)(&cfg.ScaleWindowLead), "scale-window-lead", "how long before a scale-window opens its limits apply, so its workers are ready when it does")
+	fs.IntVar(&cfg.Port, "port", cfg.Port, "orchestrator listen port; 0 = none, serving on unix-socket only")
+	fs.Var(&listFlag{dst: &cfg.WorkerPools}, "worker-pool", "extra tagged pool of workers as tag:min:max[:binary], chosen by POST /sessions {\"pool\": \"<tag>\"} or X-Worker-Group: <tag> (repeatable)")
+	fs.Var(&listFlag{dst: &cfg.WorkerPoolEnv}, "worker-pool-env", "extra environment variable for one --worker-pool's workers as tag:KEY=VALUE (repeatable)")
+	fs.StringVar(&cfg.PortRange, "port-range", cfg.PortRange, "assign worker ports from this range, e.g. 20000-20999, tracking which are in use (default: ports chosen by the OS)")
+	fs.StringVar(&cfg.UnixSocket, "unix-socket", cfg.UnixSocket, "also serve the API on this Unix domain socket path, e.g. for a sidecar client; with port 0, serve on the socket only")
+	fs.IntVar(&cfg.AdminPort, "admin-port", cfg.AdminPort, "serve /status, /metrics, /events/* and /debug/* on 127.0.0.1 at this port instead of the public one; 0 = single listener")
+	fs.StringVar(&cfg.Binary, "binary", cfg.Binary, "path to the steel-browser binary")
+	fs.Var(&listFlag{dst: &cfg.WorkerArgs}, "worker-arg", "extra argument passed to every worker process (repeatable)")
+	fs.Var(&listFlag{dst: &cfg.WorkerEnv}, "worker-env", "extra KEY=VALUE environment variable for every worker process (repeatable)")
+	fs.Int64Var(&cfg.WorkerMemoryMaxMB, "worker-memory-max-mb", cfg.WorkerMemoryMaxMB, "per-worker memory cap in MiB (cgroup memory.max, or RLIMIT_AS without a cgroup); 0 = unlimited")
+	fs.Int64Var(&cfg.WorkerMemLimitMB, "worker-memory-limit-mb", cfg.WorkerMemLimitMB, "per-worker RSS in MiB above which the health check kills and restarts the worker; a session it held answers 410 (Linux only; 0 = off)")
+	fs.Int64Var(&cfg.WorkerMemEstMB, "worker-memory-estimate-mb", cfg.WorkerMemEstMB, "expected memory per worker in MiB, used to apply pool-memory-budget-mb")
+	fs.Int64Var(&cfg.PoolMemBudgetMB, "pool-memory-budget-mb", cfg.PoolMemBudgetMB, "total memory in MiB the pool may use; scale-up stops at budget / worker-memory-estimate-mb workers even below max-workers (0 = no budget)")
+	fs.Float64Var(&cfg.WorkerCPUMax, "worker-cpu-max", cfg.WorkerCPUMax, "per-worker CPU cap in cores (requires -worker-cgroup); 0 = unlimited")
+	fs.StringVar(&cfg.WorkerCgroup, "worker-cgroup", cfg.WorkerCgroup, "cgroup v2 directory to create per-worker cgroups under (Linux only)")
+	fs.Var((*durationFlag)(&cfg.SessionTTL), "session-ttl", "idle time after which a session expires")
+	fs.Var((*durationFlag)(&cfg.SweepInterval), "sweep-interval", "how often expired sessions are reaped (must be well below session-ttl)")
+	fs.Var((*durationFlag)(&cfg.HealthCheckInterval), "health-check-interval", "interval between worker health-check sweeps")
+	fs.IntVar(&cfg.HealthFailures, "health-failures", cfg.HealthFailures, "failed health probes in a row before a worker is killed, or quarantined if it holds sessions and quarantine-timeout is set; a refused connection kills at once")
+	fs.Var((*durationFlag)(&cfg.QuarantineTimeout), "quarantine-timeout", "how long a worker taken out of the queue for failing health checks has to recover before it is killed; idle workers are quarantined on their first failure (0 = no quarantine, e.g. 30s to enable it)")
+	fs.Var((*durationFlag)(&cfg.QuarantineInterval), "quarantine-interval", "how often a quarantined worker is probed")
+	fs.IntVar(&cfg.QuarantineSuccesses, "quarantine-successes", cfg.QuarantineSuccesses, "healthy probes in a row that re-admit a quarantined worker")
+	fs.IntVar(&cfg.DeepCheckEvery, "deep-check-every", cfg.DeepCheckEvery, "on every Nth health sweep, also check each worker that passes its probe can serve: GET deep-check-path, or each of its sessions, where a 404 kills it (0 = never)")
+	fs.StringVar(&cfg.DeepCheckPath, "deep-check-path", cfg.DeepCheckPath, "worker path the deep check GETs, expecting 2xx, e.g. /v1/health/deep (default: GET /sessions/{id} for each session the worker holds)")
+	fs.Var((*durationFlag)(&cfg.HealthGrace), "health-grace", "how long after becoming ready a worker is exempt from periodic health checks (0 = no grace)")
+	fs.Var((*durationFlag)(&cfg.MaxWorkerAge), "max-worker-age", "restart idle workers whose process is older than this, one at a time; busy ones are restarted when their session ends (0 = never)")
+	fs.Var((*durationFlag)(&cfg.WaitReady), "wait-ready-at-startup", "block startup until min-workers workers are ready, failing it after this long with the workers that never came up (0 = serve at once while they boot)")
+	fs.Var((*durationFlag)(&cfg.ReadyTimeout), "ready-timeout", "how long a starting worker has to pass its health probe before it is marked unhealthy")
+	fs.StringVar(&cfg.HealthPath, "health-path", cfg.HealthPath, "worker path probed for readiness and periodic health checks")
+	fs.Var(&intListFlag{dst: &cfg.HealthStatus}, "health-status", "HTTP status the health probe accepts as healthy (repeatable or comma-separated, e.g. 200,204)")
+	fs.StringVar(&cfg.ReadySmokeTest, "ready-smoke-test", cfg.ReadySmokeTest, "after a booting worker passes its health probe, check it can serve before marking it available: \"create\" (create and delete a session) or a path to GET expecting 2xx; failures kill and restart it (default: none)")
+	fs.Var((*durationFlag)(&cfg.WorkerCreateTimeout), "worker-create-timeout", "timeout for proxied POST /sessions calls to a worker")
+	fs.Var((*durationFlag)(&cfg.WorkerGetTimeout), "worker-get-timeout", "timeout for proxied GET /sessions/{id} calls to a worker")
+	fs.Var((*durationFlag)(&cfg.WorkerDeleteTimeout), "worker-delete-timeout", "timeout for proxied DELETE /sessions/{id} calls to a worker")
+	fs.IntVar(&cfg.ProxyMaxIdleConns, "proxy-max-idle-conns", cfg.ProxyMaxIdleConns, "idle keep-alive connections to workers kept across all workers (0 = unlimited)")
+	fs.IntVar(&cfg.ProxyMaxIdleConnsPerHost, "proxy-max-idle-conns-per-host", cfg.ProxyMaxIdleConnsPerHost, "idle keep-alive connections kept per worker; concurrent calls beyond it open and close a connection each")
+	fs.Var((*durationFlag)(&cfg.ProxyIdleConnTimeout), "proxy-idle-conn-timeout", "how long an idle connection to a worker is kept (0 = forever)")
+	fs.Var((*durationFlag)(&cfg.ReadHeaderTimeout), "read-header-timeout", "how long a client may take to send request headers (guards against Slowloris)")
+	fs.Var((*durationFlag)(&cfg.ReadTimeout), "read-timeout", "how long a client may take to send a whole request, body included (0 = no limit)")
+	fs.Var((*durationFlag)(&cfg.WriteTimeout), "write-timeout", "how long a handler may take to write its response, measured from the end of the request headers; must cover the 5m a create may queue for a worker (0 = no limit)")
+	fs.Var((*durationFlag)(&cfg.IdleTimeout), "idle-timeout", "how long an idle keep-alive connection is kept open (0 = read-timeout)")
+	fs.Int64Var(&cfg.MaxBodyBytes, "max-body-bytes", cfg.MaxBodyBytes, "maximum size of a POST /sessions request body; larger bodies get 413")
+	fs.Var(&listFlag{dst: &cfg.PriorityTokens}, "priority-token", "bearer token allowed to create sessions with X-Priority: high (repeatable; prefer ORCH_PRIORITY_TOKEN to keep it out of ps)")
+	fs.Var(&listFlag{dst: &cfg.CreateRequired}, "create-required-field", "top-level field a POST /sessions body must contain (non-null) or get 400 before a worker is acquired (repeatable)")
+	fs.IntVar(&cfg.MaxInFlight, "max-inflight", cfg.MaxInFlight, "maximum concurrent proxied session requests across all workers; excess gets 429 (0 = unlimited)")
+	fs.IntVar(&cfg.MaxSessions, "max-sessions", cfg.MaxSessions, "maximum live sessions across all pools; a create past it gets 503 before a worker is acquired (0 = unlimited)")
+	fs.IntVar(&cfg.SessionsPerWorker, "sessions-per-worker", cfg.SessionsPerWorker, "concurrent sessions one worker process may hold; a worker takes creates until it is full")
+	fs.IntVar(&cfg.MaxQueueDepth, "max-queue-depth", cfg.MaxQueueDepth, "maximum creates waiting for a worker; one more gets 503 \"queue full\" at once (0 = unbounded)")
+	fs.BoolVar(&cfg.Debug, "debug", cfg.Debug, "enable debugging aids: a create may pin itself to a worker with X-Force-Worker: <id>")
+	fs.BoolVar(&cfg.Chaos, "chaos", cfg.Chaos, "soak-test mode: kill a random healthy worker every ~chaos-interval (never below min-workers healthy)")
+	fs.Var((*durationFlag)(&cfg.ChaosInterval), "chaos-interval", "mean time between chaos kills (jittered ±50%)")
+	fs.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "log output format: text or json")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "minimum log level: debug, info, warn or error")
+	fs.StringVar(&cfg.StateFile, "state-file", cfg.StateFile, "snapshot session-to-worker mappings to this file and re-attach to surviving workers on startup; busy workers are left running on shutdown")
+	fs.Var((*durationFlag)(&cfg.ShutdownTimeout), "shutdown-timeout", "how long a SIGINT/SIGTERM shutdown may take to drain HTTP requests and stop workers before the process force-exits")
+	fs.Var((*durationFlag)(&cfg.ShutdownGrace), "shutdown-grace", "on shutdown, how long to spend deleting live sessions on their workers before the workers are stopped (0 = skip; ignored with --state-file)")
+	fs.Var((*durationFlag)(&cfg.WorkerStopTimeout), "worker-stop-timeout", "on shutdown, how long each worker has to exit after SIGTERM before it gets SIGKILL (0 = SIGKILL at once)")
+	fs.StringVar(&cfg.AuditLog, "audit-log", cfg.AuditLog, "append newline-delimited JSON worker lifecycle audit records to this file (reopened on SIGHUP)")
+	fs.StringVar(&cfg.OtelEndpoint, "otel-endpoint", cfg.OtelEndpoint, "export OpenTelemetry spans for session requests to this OTLP/HTTP collector, e.g. http://localhost:4318 (empty = tracing off)")
+	return fs
+}
+
+// loadConfig resolves the effective configuration from defaults, the optional
+// config file, ORCH_* environment variables, and command-line flags, in
+// increasing order of precedence, recording the source of every value.
+func loadConfig(args []string, errOut io.Writer) (*Config, error) {
+	// First pass: learn which flags were given explicitly and where the
+	// config file lives.
+	probe := defaultConfig()
+	fs := newFlagSet(probe, errOut)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	if !explicit["config"] {
+		probe.ConfigPath = os.Getenv(envName("config"))
+	}
+
+	cfg := defaultConfig()
+	cfg.ConfigPath = probe.ConfigPath
+	cfg.Sources = map[string]string{}
+	fs = newFlagSet(cfg, io.Discard)
+	fs.VisitAll(func(f *flag.Flag) { cfg.Sources[f.Name] = sourceDefault })
+
+	if cfg.ConfigPath != "" {
+		keys, err := readConfigFile(cfg.ConfigPath, cfg)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range keys {
+			cfg.Sources[name] = sourceFile
+		}
+	}
+
+	var envErrs []error
+	fs.VisitAll(func(f *flag.Flag) {
+		if explicit[f.Name] {
+			return
+		}
+		v, ok := os.LookupEnv(envName(f.Name))
+		if !ok {
+			return
+		}
+		if err := setFromEnv(f, v); err != nil {
+			envErrs = append(envErrs, fmt.Errorf("%s: %w", envName(f.Name), err))
+			return
+		}
+		cfg.Sources[f.Name] = sourceEnv
+	})
+	if err := errors.Join(envErrs...); err != nil {
+		return nil, err
+	}
+
+	// Explicit flags win over everything else.
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	for name := range explicit {
+		cfg.Sources[name] = sourceFlag
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// setFromEnv applies an environment value to a flag. Repeatable flags take a
+// comma-separated list.
+func setFromEnv(f *flag.Flag, v string) error {
+	if _, isList := f.Value.(*listFlag); isList {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				if err := f.Value.Set(item); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return f.Value.Set(v)
+}
+
+// logEffectiveConfig logs every setting with its value and source so
+// misconfiguration can be traced to a flag, env var, file, or default.
+// Settings tagged secret are logged only as how many values are set.
+func logEffectiveConfig(cfg *Config) {
+	log := logger("config")
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("flag")
+		if name == "" || name == "-" {
+			continue
+		}
+		value := fmt.Sprint(v.Field(i).Interface())
+		if t.Field(i).Tag.Get("secret") == "true" {
+			value = fmt.Sprintf("(%d redacted)", v.Field(i).Len())
+		}
+		log.Info("effective setting", "setting", name, "value", value, "source", cfg.Sources[name])
+	}
+}
+
+// readConfigFile decodes a JSON config file over cfg and returns the flag
+// names of the keys it set. Unknown keys are rejected so typos don't silently
+// fall back to defaults.
+func readConfigFile(path string, cfg *Config) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("open config: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+
+	var present map[string]json.RawMessage
+	if err := json.Unmarshal(data, &present); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	var keys []string
+	t := reflect.TypeOf(*cfg)
+	for i := 0; i < t.NumField(); i++ {
+		jsonKey, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if _, ok := present[jsonKey]; ok {
+			keys = append(keys, t.Field(i).Tag.Get("flag"))
+		}
+	}
+	return keys, nil
+}
+
+// Validate checks the configuration for values the orchestrator cannot run with.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.MinWorkers < 0 {
+		errs = append(errs, errors.New("min-workers must be >= 0"))
+	}
+	if c.StartQuorum < 0 || c.StartQuorum > c.MinWorkers {
+		errs = append(errs, errors.New("start-quorum must be between 0 (all) and min-workers"))
+	}
+	if c.MaxBoots < 0 {
+		errs = append(errs, errors.New("max-concurrent-boots must be >= 0"))
+	}
+	if c.CrashLoopLimit < 0 {
+		errs = append(errs, errors.New("crash-loop-limit must be >= 0"))
+	}
+	if c.RestartDelay < 0 || time.Duration(c.RestartDelay) > restartMaxDelay {
+		errs = append(errs, fmt.Errorf("restart-delay must be between 0 and %s", restartMaxDelay))
+	}
+	if c.MaxRestartsPerMin < 0 {
+		errs = append(errs, errors.New("max-restarts-per-minute must be >= 0"))
+	}
+	if _, err := newScalePolicy(c.ScalePolicy, time.Duration(c.ScaleDownAfter), time.Duration(c.ScaleTargetWait), c.ScaleDownUtilization); err != nil {
+		errs = append(errs, err)
+	}
+	if time.Duration(c.ScaleInterval) < minScaleInterval {
+		errs = append(errs, fmt.Errorf("scale-interval must be at least %s, got %s", minScaleInterval, c.ScaleInterval))
+	}
+	if c.ScaleDownAfter < c.ScaleInterval {
+		errs = append(errs, fmt.Errorf("scale-down-after (%s) must be at least scale-interval (%s)", c.ScaleDownAfter, c.ScaleInterval))
+	}
+	if c.ScaleUpCooldown < 0 {
+		errs = append(errs, errors.New("scale-up-cooldown must be >= 0"))
+	}
+	if c.ScaleTargetWait <= 0 {
+		errs = append(errs, errors.New("scale-target-wait must be positive"))
+	}
+	if c.ScaleDownUtilization <= 0 || c.ScaleDownUtilization > 1 {
+		errs = append(errs, errors.New("scale-down-utilization must be in (0, 1]"))
+	}
+	if c.ScaleDownFraction < 0 || c.ScaleDownFraction > 1 {
+		errs = append(errs, errors.New("scale-down-fraction must be in [0, 1]"))
+	}
+	if c.WarmBuffer < 0 {
+		errs = append(errs, errors.New("warm-buffer must be >= 0"))
+	}
+	if c.MaxWorkers < 1 || c.MaxWorkers < c.MinWorkers {
+		errs = append(errs, errors.New("max-workers must be >= 1 and >= min-workers"))
+	}
+	if c.MaxWorkers > maxPoolCapacity {
+		errs = append(errs, fmt.Errorf("max-workers must be <= %d", maxPoolCapacity))
+	}
+	if c.SessionTTL <= 0 {
+		errs = append(errs, errors.New("session-ttl must be positive"))
+	}
+	if c.SweepInterval <= 0 || c.SweepInterval > c.SessionTTL/2 {
+		errs = append(errs, fmt.Errorf("sweep-interval must be positive and at most half of session-ttl (%s), got %s", c.SessionTTL, c.SweepInterval))
+	}
+	if c.HealthCheckInterval <= 0 {
+		errs = append(errs, errors.New("health-check-interval must be positive"))
+	}
+	if c.HealthGrace < 0 {
+		errs = append(errs, errors.New("health-grace must be >= 0"))
+	}
+	if c.HealthFailures < 1 {
+		errs = append(errs, errors.New("health-failures must be >= 1"))
+	}
+	if c.QuarantineTimeout < 0 {
+		errs = append(errs, errors.New("quarantine-timeout must be >= 0"))
+	}
+	if c.QuarantineTimeout > 0 && (c.QuarantineInterval <= 0 || c.QuarantineInterval > c.QuarantineTimeout) {
+		errs = append(errs, errors.New("quarantine-interval must be positive and at most quarantine-timeout"))
+	}
+	if c.QuarantineSuccesses < 1 {
+		errs = append(errs, errors.New("quarantine-successes must be >= 1"))
+	}
+	if c.DeepCheckEvery < 0 {
+		errs = append(errs, errors.New("deep-check-every must be >= 0"))
+	}
+	if c.DeepCheckPath != "" && !strings.HasPrefix(c.DeepCheckPath, "/") {
+		errs = append(errs, errors.New("deep-check-path must start with /"))
+	}
+	if c.WorkerMemLimitMB < 0 || (c.WorkerMemLimitMB > 0 && !procSampling) {
+		errs = append(errs, errors.New("worker-memory-limit-mb must be >= 0, and is only supported on Linux"))
+	}
+	if c.WorkerMemEstMB <= 0 {
+		errs = append(errs, errors.New("worker-memory-estimate-mb must be positive"))
+	} else if c.PoolMemBudgetMB < 0 || (c.PoolMemBudgetMB > 0 && c.PoolMemBudgetMB < int64(max(c.MinWorkers, 1))*c.WorkerMemEstMB) {
+		errs = append(errs, fmt.Errorf("pool-memory-budget-mb must be 0 (none) or cover min-workers × worker-memory-estimate-mb (%d MiB)",
+			int64(max(c.MinWorkers, 1))*c.WorkerMemEstMB))
+	}
+	if c.MaxWorkerAge < 0 {
+		errs = append(errs, errors.New("max-worker-age must be >= 0"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdown-timeout must be positive"))
+	}
+	if c.WorkerStopTimeout < 0 || c.ShutdownGrace < 0 {
+		errs = append(errs, errors.New("worker-stop-timeout and shutdown-grace must be >= 0"))
+	}
+	if c.WaitReady < 0 {
+		errs = append(errs, errors.New("wait-ready-at-startup must be >= 0"))
+	}
+	if time.Duration(c.ReadyTimeout) < minReadyTimeout {
+		errs = append(errs, fmt.Errorf("ready-timeout must be at least %s to cover browser boot, got %s", minReadyTimeout, c.ReadyTimeout))
+	}
+	if c.ProxyMaxIdleConns < 0 || c.ProxyMaxIdleConnsPerHost < 1 || c.ProxyIdleConnTimeout < 0 {
+		errs = append(errs, errors.New("proxy-max-idle-conns and proxy-idle-conn-timeout must be >= 0, proxy-max-idle-conns-per-host >= 1"))
+	}
+	if c.WorkerCreateTimeout <= 0 || c.WorkerGetTimeout <= 0 || c.WorkerDeleteTimeout <= 0 {
+		errs = append(errs, errors.New("worker-create-timeout, worker-get-timeout and worker-delete-timeout must be positive"))
+	}
+	if c.AdminPort < 0 || (c.AdminPort != 0 && c.AdminPort == c.Port) {
+		errs = append(errs, errors.New("admin-port must be 0 (disabled) or differ from port"))
+	}
+	if c.Port < 0 || (c.Port == 0 && c.UnixSocket == "") {
+		errs = append(errs, errors.New("port must be positive, or 0 with unix-socket set"))
+	}
+	specs, err := c.WorkerPoolSpecs()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	windows, err := c.ScaleWindowSpecs()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if c.ScaleWindowLead < 0 || time.Duration(c.ScaleWindowLead) >= 24*time.Hour {
+		errs = append(errs, errors.New("scale-window-lead must be between 0 and 24h"))
+	}
+	totalMax := c.MaxWorkers
+	for _, sw := range windows {
+		totalMax = max(totalMax, sw.Max)
+	}
+	for _, s := range specs {
+		totalMax += s.Max
+	}
+	if lo, hi, err := parsePortRange(c.PortRange); err != nil {
+		errs = append(errs, err)
+	} else if lo != 0 && hi-lo+1 < totalMax {
+		errs = append(errs, fmt.Errorf("port-range %s holds fewer ports than max-workers across all pools (%d)", c.PortRange, totalMax))
+	} else if lo != 0 && ((c.Port >= lo && c.Port <= hi) || (c.AdminPort >= lo && c.AdminPort <= hi)) {
+		errs = append(errs, errors.New("port-range must not include port or admin-port"))
+	}
+	if c.Chaos && c.ChaosInterval <= 0 {
+		errs = append(errs, errors.New("chaos-interval must be positive"))
+	}
+	if !strings.HasPrefix(c.HealthPath, "/") {
+		errs = append(errs, fmt.Errorf("health-path must start with /, got %q", c.HealthPath))
+	}
+	if t := c.ReadySmokeTest; t != "" && t != smokeTestCreate && !strings.HasPrefix(t, "/") {
+		errs = append(errs, fmt.Errorf("ready-smoke-test must be empty, %q or a path starting with /, got %q", smokeTestCreate, t))
+	}
+	if len(c.HealthStatus) == 0 {
+		errs = append(errs, errors.New("health-status needs at least one status code"))
+	}
+	for _, code := range c.HealthStatus {
+		if code < 100 || code > 599 {
+			errs = append(errs, fmt.Errorf("health-status %d is not an HTTP status code", code))
+		}
+	}
+	if c.ReadHeaderTimeout <= 0 {
+		errs = append(errs, errors.New("read-header-timeout must be positive"))
+	}
+	if c.ReadTimeout < 0 || c.WriteTimeout < 0 || c.IdleTimeout < 0 {
+		errs = append(errs, errors.New("read-timeout, write-timeout and idle-timeout must be >= 0"))
+	}
+	if c.MaxInFlight < 0 {
+		errs = append(errs, errors.New("max-inflight must be >= 0"))
+	}
+	if c.SessionsPerWorker < 1 {
+		errs = append(errs, errors.New("sessions-per-worker must be >= 1"))
+	}
+	if c.MaxQueueDepth < 0 || c.MaxSessions < 0 {
+		errs = append(errs, errors.New("max-queue-depth and max-sessions must be >= 0"))
+	}
+	if c.MaxBodyBytes <= 0 {
+		errs = append(errs, errors.New("max-body-bytes must be positive"))
+	}
+	for _, kv := range c.WorkerEnv {
+		if !strings.Contains(kv, "=") {
+			errs = append(errs, fmt.Errorf("worker-env %q: want KEY=VALUE", kv))
+		}
+	}
+	if _, err := parseLogLevel(c.LogLevel); err != nil {
+		errs = append(errs, err)
+	}
+	if c.OtelEndpoint != "" {
+		if u, err := url.Parse(c.OtelEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("otel-endpoint %q: want an http:// or https:// URL", c.OtelEndpoint))
+		}
+	}
+	if err := c.Limits().Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// minScaleInterval keeps the autoscaler from spinning; each tick walks the
+// worker list under the pool lock.
+const minScaleInterval = 100 * time.Millisecond
+
+// minReadyTimeout is the shortest readiness window accepted; a steel-browser
+// process typically needs a couple of seconds to boot Chrome.
+const minReadyTimeout = 2 * time.Second
+
+// Timeouts returns the per-operation worker call timeouts.
+func (c *Config) Timeouts() WorkerTimeouts {
+	return WorkerTimeouts{
+		Create: time.Duration(c.WorkerCreateTimeout),
+		Get:    time.Duration(c.WorkerGetTimeout),
+		Delete: time.Duration(c.WorkerDeleteTimeout),
+	}
+}
+
+// Limits returns the configured per-worker resource limits.
+func (c *Config) Limits() ResourceLimits {
+	return ResourceLimits{MemoryBytes: c.WorkerMemoryMaxMB << 20, CPUs: c.WorkerCPUMax, CgroupParent: c.WorkerCgroup}
+}
+
+// Launch returns how worker processes should be started.
+func (c *Config) Launch() LaunchConfig {
+	return LaunchConfig{
+		BinaryPath:   c.Binary,
+		Args:         c.WorkerArgs,
+		Env:          c.WorkerEnv,
+		Limits:       c.Limits(),
+		ReadyTimeout: time.Duration(c.ReadyTimeout),
+		Health:       HealthProbe{Path: c.HealthPath, Statuses: c.HealthStatus},
+		SmokeTest:    c.ReadySmokeTest,
+	}
+}
+
+// ScaleWindowSpecs parses --scale-window, rejecting names given twice.
+func (c *Config) ScaleWindowSpecs() ([]ScaleWindow, error) {
+	var windows []ScaleWindow
+	for _, s := range c.ScaleWindows {
+		sw, err := parseScaleWindow(s)
+		if err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(windows, func(o ScaleWindow) bool { return o.Name == sw.Name }) {
+			return nil, fmt.Errorf("scale-window name %q is given twice", sw.Name)
+		}
+		windows = append(windows, sw)
+	}
+	return windows, nil
+}
+
+// WorkerPoolSpecs parses --worker-pool and attaches each --worker-pool-env
+// entry to its pool, rejecting duplicate tags and env for unknown pools.
+func (c *Config) WorkerPoolSpecs() ([]WorkerPoolSpec, error) {
+	var specs []WorkerPoolSpec
+	index := make(map[string]int)
+	for _, s := range c.WorkerPools {
+		spec, err := parseWorkerPool(s)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := index[spec.Tag]; dup {
+			return nil, fmt.Errorf("worker-pool tag %q is given twice", spec.Tag)
+		}
+		index[spec.Tag] = len(specs)
+		specs = append(specs, spec)
+	}
+	for _, s := range c.WorkerPoolEnv {
+		tag, kv, _ := strings.Cut(s, ":")
+		i, ok := index[tag]
+		switch {
+		case !strings.Contains(kv, "=") || strings.HasPrefix(kv, "="):
+			return nil, fmt.Errorf("worker-pool-env %q: want tag:KEY=VALUE", s)
+		case !ok:
+			return nil, fmt.Errorf("worker-pool-env %q: no --worker-pool with tag %q", s, tag)
+		}
+		specs[i].Env = append(specs[i].Env, kv)
+	}
+	return specs, nil
+}
+
+// reloadableFields are the Config fields that reloadConfig applies to a
+// running orchestrator. Any other field that changes is reported and ignored.
+var reloadableFields = map[string]bool{
+	"SessionTTL":          true,
+	"HealthCheckInterval": true,
+	"MaxWorkers":          true,
+	"LogLevel":            true,
+	"WorkerPools":         true,
+}
+
+// reloadConfig re-reads the config file (with the original command-line flags
+// still taking precedence), applies the runtime-safe settings, and returns the
+// new effective config. On error the running config is left untouched.
+// The health-check interval applies to every pool, max_workers to the
+// default pool, and worker_pools to the tagged pools' min and max.
+func reloadConfig(cur *Config, args []string, pools *PoolSet, sessions *SessionManager) (*Config, error) {
+	if cur.ConfigPath == "" {
+		return cur, errors.New("no -config file to reload")
+	}
+	next, err := loadConfig(args, io.Discard)
+	if err != nil {
+		return cur, err
+	}
+
+	log := logger("config")
+	applied := *cur
+	curV, nextV := reflect.ValueOf(cur).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < curV.NumField(); i++ {
+		field := curV.Type().Field(i)
+		if field.Tag.Get("json") == "-" {
+			continue
+		}
+		name := field.Name
+		oldVal, newVal := curV.Field(i).Interface(), nextV.Field(i).Interface()
+		if reflect.DeepEqual(oldVal, newVal) {
+			continue
+		}
+		if !reloadableFields[name] {
+			running, file := fmt.Sprint(oldVal), fmt.Sprint(newVal)
+			if field.Tag.Get("secret") == "true" {
+				running, file = "(redacted)", "(redacted)"
+			}
+			log.Warn("setting changed in config but requires a restart — ignored", "setting", name, "running", running, "file", file)
+			continue
+		}
+
+		switch name {
+		case "SessionTTL":
+			sessions.SetTTL(time.Duration(next.SessionTTL))
+			applied.SessionTTL = next.SessionTTL
+		case "HealthCheckInterval":
+			for _, p := range pools.All() {
+				p.SetHealthCheckInterval(time.Duration(next.HealthCheckInterval))
+			}
+			applied.HealthCheckInterval = next.HealthCheckInterval
+		case "MaxWorkers":
+			if err := pools.Default().SetMax(next.MaxWorkers); err != nil {
+				log.Warn("could not apply setting", "setting", name, "error", err)
+				continue
+			}
+			applied.MaxWorkers = next.MaxWorkers
+		case "LogLevel":
+			lvl, _ := parseLogLevel(next.LogLevel) // validated by loadConfig
+			logLevel.Set(lvl)
+			applied.LogLevel = next.LogLevel
+		case "WorkerPools":
+			if err := reloadWorkerPools(cur, next, pools); err != nil {
+				log.Warn("could not apply setting", "setting", name, "error", err)
+				continue
+			}
+			applied.WorkerPools = next.WorkerPools
+		}
+		log.Info("setting reloaded", "setting", name, "old", fmt.Sprint(oldVal), "new", fmt.Sprint(newVal))
+	}
+	return &applied, nil
+}
+
+// reloadWorkerPools applies each --worker-pool's min and max to its running
+// pool, through SetMin and SetMax as for max_workers. Adding or removing a
+// pool, or changing its binary, needs a restart and fails the whole reload.
+func reloadWorkerPools(cur, next *Config, pools *PoolSet) error {
+	curSpecs, err := cur.WorkerPoolSpecs()
+	if err != nil {
+		return err
+	}
+	specs, err := next.WorkerPoolSpecs() // validated by loadConfig
+	if err != nil {
+		return err
+	}
+	running := make(map[string]WorkerPoolSpec, len(curSpecs))
+	for _, spec := range curSpecs {
+		running[spec.Tag] = spec
+	}
+	if len(specs) != len(running) {
+		return errors.New("adding or removing a worker pool requires a restart")
+	}
+	for _, spec := range specs {
+		old, ok := running[spec.Tag]
+		switch {
+		case !ok:
+			return fmt.Errorf("worker pool %q is not running; adding a pool requires a restart", spec.Tag)
+		case spec.Binary != old.Binary:
+			return fmt.Errorf("worker pool %q: changing its binary requires a restart", spec.Tag)
+		}
+	}
+
+	var errs []error
+	for _, spec := range specs {
+		p, _ := pools.Get(spec.Tag)
+		// Move whichever bound keeps min <= max at every step.
+		var err error
+		if _, baseMax := p.BaseLimits(); spec.Min > baseMax {
+			err = errors.Join(p.SetMax(spec.Max), p.SetMin(spec.Min))
+		} else {
+			err = errors.Join(p.SetMin(spec.Min), p.SetMax(spec.Max))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("worker pool %q: %w", spec.Tag, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// listFlag is a repeatable string flag. The first occurrence on the command
+// line replaces any value from the config file instead of appending to it.
+type listFlag struct {
+	dst *[]string
+	set bool
+}
+
+func (l *listFlag) String() string {
+	if l.dst == nil {
+		return ""
+	}
+	return strings.Join(*l.dst, ",")
+}
+
+func (l *listFlag) Set(v string) error {
+	if !l.set {
+		*l.dst = nil
+		l.set = true
+	}
+	*l.dst = append(*l.dst, v)
+	return nil
+}
+
+// intListFlag is a repeatable integer flag that also accepts a
+// comma-separated list. Like listFlag, the first occurrence replaces any
+// value from the config file.
+type intListFlag struct {
+	dst *[]int
+	set bool
+}
+
+func (l *intListFlag) String() string {
+	if l.dst == nil {
+		return ""
+	}
+	parts := make([]string, len(*l.dst))
+	for i, n := range *l.dst {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *intListFlag) Set(v string) error {
+	if !l.set {
+		*l.dst = nil
+		l.set = true
+	}
+	for _, item := range strings.Split(v, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", item)
+		}
+		*l.dst = append(*l.dst, n)
+	}
+	return nil
+}
+
+// durationFlag adapts Duration to flag.Value.
+type durationFlag Duration
+
+func (d *durationFlag) String() string { return time.Duration(*d).String() }
+
+func (d *durationFlag) Set(v string) error {
+	p, err := time.ParseDuration(v)
+	if err != nil {
+		return err
+	}
+	*d = durationFlag(p)
+	return nil
+}
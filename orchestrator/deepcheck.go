@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// deepCheckSettle is how long a deep check waits after a worker denies
+// knowing a session before it believes it: a DELETE in flight removes the
+// session on the worker just before the orchestrator forgets it.
+const deepCheckSettle = 500 * time.Millisecond
+
+// deepCheckSettings are PoolConfig's DeepCheck* fields.
+type deepCheckSettings struct {
+	every int    // run on every every-th health sweep; 0 = never
+	path  string // GET this instead of each session; "" = the sessions
+}
+
+// probeSessionMissing is the deep check's failure for a worker that answers
+// 404 for a session the orchestrator maps to it: its session API has lost
+// track, so the worker is killed at once like a refused probe.
+const probeSessionMissing = "session_missing"
+
+// deepCheck verifies a worker that has just passed its health probe can
+// still serve sessions, catching a worker whose /health answers while the
+// browser behind it is wedged. With a path it expects a 2xx from GET path.
+// Without one it GETs /sessions/{id} for every session the worker holds,
+// so an idle worker passes; a 404 there is a mismatch between the worker
+// and the session map. A failure is recorded as the worker's latest probe,
+// with its own streak of failed deep checks in a row.
+func (w *Worker) deepCheck(ctx context.Context, path string) probeResult {
+	ok := func(status int) bool { return status/100 == 2 }
+	res := probeResult{At: w.clock().Now(), Healthy: true}
+	if path != "" {
+		res, _ = w.probe(ctx, path, ok)
+	} else {
+		for _, id := range w.Sessions() {
+			r, status := w.probe(ctx, "/sessions/"+url.PathEscape(id), ok)
+			if status == http.StatusNotFound {
+				w.clock().Sleep(deepCheckSettle)
+				if !slices.Contains(w.Sessions(), id) {
+					continue // ended meanwhile
+				}
+				w.logger().Warn("deep check: worker has no record of a session mapped to it", "session_id", id)
+				r.Failure = probeSessionMissing
+			}
+			if !r.Healthy {
+				res = r
+				break
+			}
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if res.Healthy {
+		w.deepStreak = 0
+		return res
+	}
+	w.deepStreak++
+	res.Streak = w.deepStreak
+	w.lastProbe = res
+	return res
+}
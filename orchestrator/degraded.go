@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// degradedFailuresPerWorker is how many worker failures per worker, with no
+// worker becoming ready in between, mark the pool degraded: every worker has
+// on average failed, been restarted and failed again.
+const degradedFailuresPerWorker = 2
+
+// ErrPoolDegraded is returned by Acquire while every worker is failing, so
+// creates fail at once instead of waiting out their timeout.
+var ErrPoolDegraded = errors.New("all workers unhealthy")
+
+// degradedState tracks whether the whole pool is failing. It is guarded by
+// Pool.mu.
+type degradedState struct {
+	streak    int       // worker failures since any worker last became ready
+	lastError string    // the most recent of those failures
+	on        bool      // the pool is degraded
+	since     time.Time // when it became degraded
+
+	// ctx is cancelled when the pool becomes degraded, waking callers parked
+	// in Acquire; recovery replaces it with a fresh one.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (d *degradedState) reset() {
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+}
+
+// DegradedStatus describes the pool's degraded state for /readyz and /status.
+type DegradedStatus struct {
+	Degraded  bool      `json:"degraded"`
+	Since     time.Time `json:"degraded_since,omitzero"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Degraded reports whether every worker is failing.
+func (p *Pool) Degraded() DegradedStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.degraded.on {
+		return DegradedStatus{}
+	}
+	return DegradedStatus{Degraded: true, Since: p.degraded.since, LastError: p.degraded.lastError}
+}
+
+// degradedContext returns a context that is cancelled when the pool becomes
+// degraded.
+func (p *Pool) degradedContext() context.Context {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.degraded.ctx
+}
+
+// noteWorkerFailure counts an unplanned exit or failed restart of w. Once
+// the failures since any worker was last ready reach
+// degradedFailuresPerWorker per worker, and no worker is Available or Busy,
+// the pool is marked degraded and parked Acquire callers are woken.
+func (p *Pool) noteWorkerFailure(w *Worker, msg string) {
+	p.mu.Lock()
+	d := &p.degraded
+	d.streak++
+	d.lastError = msg
+	if d.on || d.streak < degradedFailuresPerWorker*len(p.workers) || p.servingLocked() {
+		p.mu.Unlock()
+		return
+	}
+	d.on = true
+	d.since = p.clk.Now()
+	d.cancel()
+	workers, streak := len(p.workers), d.streak
+	p.mu.Unlock()
+
+	p.logger().Error("all workers unhealthy — failing creates fast until one becomes ready",
+		"workers", workers, "failures", streak, "last_worker_id", w.ID, "last_error", msg)
+	p.events.Record(EventPoolDegraded, "workers", workers, "failures", streak, "last_error", msg)
+}
+
+// noteWorkerReady resets the failure streak when any worker passes its
+// readiness check, ending a degraded period.
+func (p *Pool) noteWorkerReady(w *Worker) {
+	p.mu.Lock()
+	d := &p.degraded
+	d.streak = 0
+	if !d.on {
+		p.mu.Unlock()
+		return
+	}
+	d.on = false
+	d.reset()
+	lasted := p.clk.Since(d.since)
+	p.mu.Unlock()
+
+	p.logger().Info("worker ready — pool no longer degraded", "worker_id", w.ID, "degraded_for", lasted.Round(time.Second).String())
+	p.events.Record(EventPoolRecovered, "worker_id", w.ID, "degraded_seconds", lasted.Seconds())
+}
+
+// servingLocked reports whether any worker is Available or Busy.
+// Caller holds p.mu.
+func (p *Pool) servingLocked() bool {
+	for _, w := range p.workers {
+		if s := w.State(); s == WorkerStateAvailable || s == WorkerStateBusy {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType names a significant lifecycle event.
+type EventType string
+
+const (
+	EventWorkerStarted     EventType = "worker_started"
+	EventWorkerCrashed     EventType = "worker_crashed"
+	EventWorkerRestarted   EventType = "worker_restarted"
+	EventScaleUp           EventType = "scale_up"
+	EventScaleDown         EventType = "scale_down"
+	EventSessionExpired    EventType = "session_expired"
+	EventSessionTerminated EventType = "session_terminated"
+	EventChaosKill         EventType = "chaos_kill"
+	EventWorkerAdopted     EventType = "worker_adopted"
+	EventWorkerFailed      EventType = "worker_failed"
+	EventPoolDegraded      EventType = "pool_degraded"
+	EventPoolRecovered     EventType = "pool_recovered"
+	EventPoolCondition     EventType = "pool_condition"
+	EventScaleWindow       EventType = "scale_window"
+	EventWorkerQuarantined EventType = "worker_quarantined"
+	EventWorkerReadmitted  EventType = "worker_readmitted"
+)
+
+// Event is a single entry in the event history. The same struct is used for
+// pull-based history queries and any push-based stream built on top of it.
+type Event struct {
+	ID     uint64         `json:"id"`
+	Time   time.Time      `json:"time"`
+	Type   EventType      `json:"type"`
+	Fields map[string]any `json:"fields,omitempty"`
+}
+
+const defaultEventHistorySize = 1000
+
+// EventLog is a bounded, in-memory ring buffer of recent events.
+// A nil *EventLog is valid and discards everything, so components can record
+// events without checking whether history is wired up.
+type EventLog struct {
+	mu     sync.Mutex
+	buf    []Event
+	start  int // index of the oldest entry once the buffer has wrapped
+	nextID uint64
+}
+
+// NewEventLog creates an event log that keeps the last capacity entries.
+func NewEventLog(capacity int) *EventLog {
+	if capacity <= 0 {
+		capacity = defaultEventHistorySize
+	}
+	return &EventLog{buf: make([]Event, 0, capacity)}
+}
+
+// Record appends an event, evicting the oldest one when full.
+// kv is a list of alternating string keys and values, as with slog.
+func (l *EventLog) Record(typ EventType, kv ...any) {
+	if l == nil {
+		return
+	}
+	fields := make(map[string]any, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		if k, ok := kv[i].(string); ok {
+			fields[k] = kv[i+1]
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.nextID++
+	ev := Event{ID: l.nextID, Time: time.Now(), Type: typ, Fields: fields}
+	if len(l.buf) < cap(l.buf) {
+		l.buf = append(l.buf, ev)
+		return
+	}
+	l.buf[l.start] = ev
+	l.start = (l.start + 1) % len(l.buf)
+}
+
+// History returns events in chronological order, optionally filtered by type
+// (empty matches all) and to those recorded after since (zero matches all).
+func (l *EventLog) History(typ EventType, since time.Time) []Event {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	out := make([]Event, 0, len(l.buf))
+	for i := 0; i < len(l.buf); i++ {
+		ev := l.buf[(l.start+i)%len(l.buf)]
+		if typ != "" && ev.Type != typ {
+			continue
+		}
+		if !since.IsZero() && !ev.Time.After(since) {
+			continue
+		}
+		out = append(out, ev)
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// logLevel is shared by the default handler so the level can be changed
+// after startup without rebuilding the logger.
+var logLevel = new(slog.LevelVar)
+
+// debugEnabled reports whether debug logs are on. Hot paths (Acquire,
+// Release) check it first so a disabled line costs no logger or attribute
+// allocation, nor the locks taken to compute its values.
+func debugEnabled() bool {
+	return logLevel.Level() <= slog.LevelDebug
+}
+
+// setupLogger installs the process-wide slog default.
+// format is "text" or "json"; level is debug, info, warn or error.
+func setupLogger(out io.Writer, format, level string) error {
+	lvl, err := parseLogLevel(level)
+	if err != nil {
+		return err
+	}
+	logLevel.Set(lvl)
+
+	opts := &slog.HandlerOptions{Level: logLevel}
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "text", "":
+		h = slog.NewTextHandler(out, opts)
+	case "json":
+		h = slog.NewJSONHandler(out, opts)
+	default:
+		return fmt.Errorf("unknown log format %q (want text or json)", format)
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// parseLogLevel maps a flag value to a slog level.
+func parseLogLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", s)
+	}
+}
+
+// logger returns the default logger tagged with a component name
+// (pool, worker, session, handler, proxy). Resolved lazily so it always
+// reflects the handler installed by setupLogger.
+func logger(component string) *slog.Logger {
+	return slog.Default().With("component", component)
+}
+
+type requestIDKey struct{}
+
+// withRequestID assigns every request an ID (reusing an incoming X-Request-ID
+// if the client sent one), echoes it in the response, and stores it in the
+// request context for handler logs.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requestID returns the ID assigned by withRequestID, or "" outside a request.
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestLogger returns the handler logger tagged with the request's ID.
+func requestLogger(r *http.Request) *slog.Logger {
+	return logger("handler").With("request_id", requestID(r.Context()))
+}
+
+func newRequestID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -1,79 +1,450 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"text/tabwriter"
 	"time"
 )
 
 func main() {
-	minWorkers := flag.Int("min-workers", 2, "minimum (starting) number of worker processes")
-	maxWorkers := flag.Int("max-workers", 10, "maximum number of worker processes (auto-scaling ceiling)")
-	port := flag.Int("port", 8080, "orchestrator listen port")
-	binary := flag.String("binary", "./steel-browser", "path to the steel-browser binary")
-	flag.Parse()
+	// The binary is a multi-command tool: "serve" (the default when the first
+	// argument is absent or a flag) runs the orchestrator, the operator
+	// subcommands in cli.go call a running one, and mockworker stands in for
+	// steel-browser.
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch {
+		case args[0] == mockWorkerCommand:
+			if err := runMockWorker(); err != nil {
+				fmt.Fprintf(os.Stderr, "mock worker: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		case args[0] == "serve":
+			args = args[1:]
+		case isCLICommand(args[0]):
+			os.Exit(runCLI(args, os.Stdout, os.Stderr))
+		}
+	}
+
+	cfg, err := loadConfig(args, os.Stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "invalid configuration: %v\n", err)
+		os.Exit(2)
+	}
+
+	if err := setupLogger(os.Stderr, cfg.LogFormat, cfg.LogLevel); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid logging flags: %v\n", err)
+		os.Exit(2)
+	}
+	slog.Info("starting orchestrator", "min_workers", cfg.MinWorkers, "max_workers", cfg.MaxWorkers, "port", cfg.Port, "binary", cfg.Binary, "config", cfg.ConfigPath)
+	logEffectiveConfig(cfg)
+
+	workerTimeouts = cfg.Timeouts()
+	httpClient.Transport = newProxyTransport(cfg.ProxyMaxIdleConns, cfg.ProxyMaxIdleConnsPerHost, time.Duration(cfg.ProxyIdleConnTimeout))
+	proxyLimit = newInflightLimiter(cfg.MaxInFlight)
+	if cfg.OtelEndpoint != "" {
+		tracer = newTracer(cfg.OtelEndpoint)
+		slog.Info("exporting traces", "endpoint", tracer.url)
+	}
+
+	// Event history shared by the pool, workers and session manager
+	events := NewEventLog(defaultEventHistorySize)
+
+	// Optional append-only worker lifecycle audit trail
+	var audit *AuditLog
+	if cfg.AuditLog != "" {
+		audit, err = OpenAuditLog(cfg.AuditLog)
+		if err != nil {
+			fatal("failed to open audit log", err)
+		}
+	}
 
-	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
-	log.Printf("Starting orchestrator: min-workers=%d, max-workers=%d, port=%d, binary=%s", *minWorkers, *maxWorkers, *port, *binary)
+	policy, err := newScalePolicy(cfg.ScalePolicy, time.Duration(cfg.ScaleDownAfter), time.Duration(cfg.ScaleTargetWait), cfg.ScaleDownUtilization)
+	if err != nil {
+		fatal("invalid scale policy", err)
+	}
+	portMin, portMax, err := parsePortRange(cfg.PortRange)
+	if err != nil {
+		fatal("invalid port range", err)
+	}
 
-	// Create pool
-	pool, err := NewPool(*minWorkers, *maxWorkers, *binary)
+	// Create the default pool, then one per --worker-pool. All of them draw
+	// ports from one allocator.
+	specs, err := cfg.WorkerPoolSpecs()
+	if err != nil {
+		fatal("invalid worker pool", err)
+	}
+	scaleWindows, err := cfg.ScaleWindowSpecs()
+	if err != nil {
+		fatal("invalid scale window", err)
+	}
+	pcfg := PoolConfig{
+		Min:                 cfg.MinWorkers,
+		Max:                 cfg.MaxWorkers,
+		Launch:              cfg.Launch(),
+		HealthCheckInterval: time.Duration(cfg.HealthCheckInterval),
+		HealthGrace:         time.Duration(cfg.HealthGrace),
+		HealthFailures:      cfg.HealthFailures,
+		QuarantineTimeout:   time.Duration(cfg.QuarantineTimeout),
+		QuarantineInterval:  time.Duration(cfg.QuarantineInterval),
+		QuarantineSuccesses: cfg.QuarantineSuccesses,
+		DeepCheckEvery:      cfg.DeepCheckEvery,
+		DeepCheckPath:       cfg.DeepCheckPath,
+		MaxWorkerAge:        time.Duration(cfg.MaxWorkerAge),
+		MemoryLimit:         cfg.WorkerMemLimitMB << 20,
+		CrashLoopLimit:      cfg.CrashLoopLimit,
+		RestartDelay:        time.Duration(cfg.RestartDelay),
+		MaxRestartsPerMin:   cfg.MaxRestartsPerMin,
+		Audit:               audit,
+		StartQuorum:         cfg.StartQuorum,
+		WaitReady:           time.Duration(cfg.WaitReady),
+		WarmBuffer:          cfg.WarmBuffer,
+		MaxBoots:            cfg.MaxBoots,
+		MaxQueueDepth:       cfg.MaxQueueDepth,
+		SessionsPerWorker:   cfg.SessionsPerWorker,
+		StopTimeout:         time.Duration(cfg.WorkerStopTimeout),
+		Policy:              policy,
+		ScaleInterval:       time.Duration(cfg.ScaleInterval),
+		ScaleUpCooldown:     time.Duration(cfg.ScaleUpCooldown),
+		ScaleDownFraction:   cfg.ScaleDownFraction,
+		ScaleWindows:        scaleWindows,
+		ScaleWindowLead:     time.Duration(cfg.ScaleWindowLead),
+
+		MemoryBudget:         cfg.PoolMemBudgetMB << 20,
+		Ports:                newPortAllocator(portMin, portMax),
+		WorkerMemoryEstimate: cfg.WorkerMemEstMB << 20,
+	}
+	pool, err := NewPool(pcfg, events)
 	if err != nil {
-		log.Fatalf("Failed to create worker pool: %v", err)
+		fatal("failed to create worker pool", err)
+	}
+	pools := newPoolSet(pool)
+	for _, spec := range specs {
+		tagged, err := NewPool(spec.poolConfig(pcfg, cfg), events)
+		if err != nil {
+			fatal("failed to create worker pool "+spec.Tag, err)
+		}
+		pools.add(tagged)
+		slog.Info("worker pool started", "pool", spec.Tag, "min_workers", spec.Min, "max_workers", spec.Max, "binary", tagged.launch.BinaryPath)
 	}
 
 	// create session manager
-	sessions, err := NewSessionManager()
+	sessions, err := NewSessionManager(time.Duration(cfg.SessionTTL), time.Duration(cfg.SweepInterval), events, nil)
 	if err != nil {
-		log.Fatalf("Failed to create session manager: %v", err)
+		fatal("failed to create session manager", err)
 	}
+	sessions.SetMaxSessions(cfg.MaxSessions)
+	sessions.SetWebhooks(newWebhookNotifier())
 
 	// Wire crash handler for both initial and future scaled-up workers.
-	// pool.CrashHandler is picked up by addWorker(); apply it to initial workers too.
-	pool.CrashHandler = func(sessionID string) {
-		log.Printf("[session] removing stale session %s (worker crashed)", sessionID)
-		sessions.Remove(sessionID)
+	// pool.CrashHandler is picked up by scale-up and Adopt; apply it to initial workers too.
+	crashHandler := func(sessionIDs []string, kind exitKind) {
+		for _, sessionID := range sessionIDs {
+			if kind == exitMemoryLimit {
+				logger("session").Info("session lost (worker exceeded memory limit)", "session_id", sessionID)
+				sessions.Fail(sessionID, string(kind))
+				continue
+			}
+			logger("session").Info("removing stale session (worker crashed)", "session_id", sessionID)
+			sessions.Lost(sessionID)
+		}
 	}
-	for _, w := range pool.Workers() {
-		w.OnCrash = pool.CrashHandler
+	for _, p := range pools.All() {
+		p.CrashHandler = crashHandler
+		for _, w := range p.Workers() {
+			w.OnCrash = crashHandler
+		}
 	}
 
-	// Wire up HTTP handlers
-	mux := http.NewServeMux()
+	// Re-attach sessions whose workers outlived the previous orchestrator.
+	if cfg.StateFile != "" {
+		restored, dropped, err := restoreState(cfg.StateFile, pools, sessions)
+		if err != nil {
+			fatal("failed to restore state", err)
+		}
+		slog.Info("restored sessions from state file", "path", cfg.StateFile, "restored", restored, "dropped", dropped)
+		go sessions.RunStateSnapshots(cfg.StateFile)
+	}
+
+	// systemd integration (no-ops unless NOTIFY_SOCKET is set): READY=1 once
+	// every pool's initial workers are up, WATCHDOG=1 once every pool has
+	// finished a health sweep.
+	if wd := sdWatchdogInterval(); wd > 0 {
+		if hc := time.Duration(cfg.HealthCheckInterval); hc > wd/2 {
+			slog.Warn("health-check interval is too long for the systemd watchdog", "health_check_interval", hc.String(), "watchdog", wd.String())
+		}
+		// Every pool must have swept since the last ping, so a health loop
+		// stuck in any pool lets the watchdog fire.
+		all := pools.All()
+		var mu sync.Mutex
+		swept := make(map[*Pool]bool, len(all))
+		for _, p := range all {
+			p.OnHealthSweep = func() {
+				mu.Lock()
+				swept[p] = true
+				ping := len(swept) == len(all)
+				if ping {
+					clear(swept)
+				}
+				mu.Unlock()
+				if !ping {
+					return
+				}
+				if err := sdNotify("WATCHDOG=1"); err != nil {
+					slog.Warn("sd_notify WATCHDOG failed", "error", err)
+				}
+			}
+		}
+	}
+	go func() {
+		for _, p := range pools.All() {
+			<-p.Ready()
+		}
+		slog.Info("initial workers ready", "min_workers", cfg.MinWorkers)
+		if err := sdNotify("READY=1"); err != nil {
+			slog.Warn("sd_notify READY failed", "error", err)
+		}
+	}()
+
+	if cfg.Chaos {
+		for _, p := range pools.All() {
+			go p.RunChaos(time.Duration(cfg.ChaosInterval))
+		}
+	}
+
+	// The session API always lives on mux; operational routes join it unless
+	// --admin-port serves them on a separate localhost-only listener.
+	mux := newMux(cfg, pools, sessions)
+	var admin http.Handler
+	if cfg.AdminPort != 0 {
+		admin = newAdminMux(pools, sessions)
+	}
+
+	// Reload runtime-safe settings from the config file on SIGHUP
+	go func() {
+		cur := cfg
+		hupCh := make(chan os.Signal, 1)
+		signal.Notify(hupCh, syscall.SIGHUP)
+		for range hupCh {
+			slog.Info("received SIGHUP, reloading config", "config", cur.ConfigPath)
+			if err := audit.Reopen(); err != nil {
+				slog.Error("audit log reopen failed", "error", err)
+			}
+			next, err := reloadConfig(cur, args, pools, sessions)
+			if err != nil {
+				slog.Error("config reload failed", "error", err)
+				continue
+			}
+			cur = next
+		}
+	}()
 
+	// Every request context derives from baseCtx, so cancelling it aborts
+	// handlers still running (e.g. parked in Acquire) when the shutdown
+	// drain runs out of time.
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+	// Every listener is opened before any is served, so an address in use
+	// fails startup here rather than from a serving goroutine.
+	type listener struct {
+		name string
+		srv  *http.Server
+		ln   net.Listener
+	}
+	var listeners []listener
+	listen := func(name, network, addr string, h http.Handler) {
+		var ln net.Listener
+		var err error
+		if network == "unix" {
+			ln, err = listenUnix(addr)
+		} else {
+			ln, err = net.Listen(network, addr)
+		}
+		if err != nil {
+			fatal("failed to open "+name+" listener", err)
+		}
+		listeners = append(listeners, listener{name, newServer(addr, withRequestID(h), cfg, baseCtx), ln})
+	}
+	if cfg.Port != 0 {
+		listen("api", "tcp", fmt.Sprintf(":%d", cfg.Port), mux)
+	}
+	if cfg.UnixSocket != "" {
+		listen("unix", "unix", cfg.UnixSocket, mux)
+	}
+	if admin != nil {
+		listen("admin", "tcp", fmt.Sprintf("127.0.0.1:%d", cfg.AdminPort), admin)
+	}
+
+	serveErr := make(chan error, len(listeners))
+	for _, l := range listeners {
+		slog.Info("orchestrator listening", "listener", l.name, "addr", l.ln.Addr().String())
+		go func() {
+			if err := l.srv.Serve(l.ln); !errors.Is(err, http.ErrServerClosed) {
+				serveErr <- fmt.Errorf("%s listener: %w", l.name, err)
+			}
+		}()
+	}
+
+	// Graceful shutdown on SIGINT/SIGTERM
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-serveErr:
+		fatal("server failed", err)
+	case sig := <-sigCh:
+		slog.Info("received signal, shutting down", "signal", sig.String())
+	}
+	if err := sdNotify("STOPPING=1"); err != nil {
+		slog.Warn("sd_notify STOPPING failed", "error", err)
+	}
+
+	// The whole shutdown — draining requests, saving state, stopping
+	// workers — shares one --shutdown-timeout deadline. In-flight requests
+	// get the first three quarters of it so the workers behind them always
+	// have some time left to exit.
+	timeout := time.Duration(cfg.ShutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	// Backstop for a step that hangs without ever checking ctx (a wedged
+	// Kill, a stuck state-file write).
+	time.AfterFunc(timeout+shutdownForceGrace, func() {
+		slog.Error("shutdown hung past its timeout, forcing exit", "timeout", timeout)
+		os.Exit(1)
+	})
+
+	// Stop accepting new requests and let in-flight ones finish before the
+	// workers behind them go away.
+	drainCtx, cancelDrain := context.WithTimeout(ctx, timeout*3/4)
+	defer cancelDrain()
+	// Closing a Unix listener also removes its socket file.
+	for _, l := range listeners {
+		if err := l.srv.Shutdown(drainCtx); err != nil {
+			slog.Warn("listener did not drain in time; cancelling in-flight requests", "addr", l.srv.Addr, "error", err)
+			cancelBase()
+			l.srv.Close()
+		}
+	}
+	// With a state file, live sessions are left running for the next
+	// orchestrator to adopt.
+	keepSessions := false
+	if cfg.StateFile != "" {
+		if err := sessions.SaveState(cfg.StateFile); err != nil {
+			slog.Error("failed to save state", "path", cfg.StateFile, "error", err)
+		} else {
+			keepSessions = true
+		}
+	}
+	if !keepSessions {
+		teardownSessions(ctx, sessions, time.Duration(cfg.ShutdownGrace))
+	}
+	stopErr := pools.Shutdown(ctx, keepSessions)
+	if err := audit.Close(); err != nil {
+		slog.Error("failed to close audit log", "error", err)
+	}
+	tracer.Shutdown(ctx)
+	if stopErr != nil {
+		slog.Error("workers did not shut down cleanly", "timeout", timeout, "error", stopErr)
+		os.Exit(1)
+	}
+	slog.Info("shutdown complete")
+}
+
+// newMux returns the handler for the session API listeners. The operational
+// routes (see addAdminRoutes) are served from it too unless --admin-port
+// gives them their own listener.
+func newMux(cfg *Config, pools *PoolSet, sessions *SessionManager) http.Handler {
+	mux := http.NewServeMux()
 	mux.HandleFunc("/sessions/", func(w http.ResponseWriter, r *http.Request) {
 		// Extract session ID from path: /sessions/{id}
 		sessionID := strings.TrimPrefix(r.URL.Path, "/sessions/")
 		if sessionID == "" {
-			http.Error(w, "session ID required", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, "session ID required")
 			return
 		}
 
+		// Sub-resources: /sessions/{id}/worker, /sessions/{id}/alias. Any
+		// {id} may also be the session's alias.
+		if id, sub, ok := strings.Cut(sessionID, "/"); ok {
+			id = sessions.Resolve(id)
+			switch {
+			case sub == "worker" && r.Method == http.MethodGet:
+				handleGetSessionWorker(w, sessions, id)
+			case sub == "alias" && r.Method == http.MethodPost:
+				handleSetSessionAlias(w, r, sessions, id)
+			case sub == "worker" || sub == "alias":
+				writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			default:
+				writeError(w, http.StatusNotFound, "not found")
+			}
+			return
+		}
+		sessionID = sessions.Resolve(sessionID)
+
 		switch r.Method {
 		case http.MethodGet:
-			handleGetSession(w, r, sessions, sessionID)
+			serveTraced(w, r, "GET /sessions/{id}", func(w http.ResponseWriter, r *http.Request) {
+				if !admitProxied(w, r) {
+					return
+				}
+				defer proxyLimit.Release()
+				handleGetSession(w, r, sessions, sessionID)
+			})
 		case http.MethodDelete:
-			handleDeleteSession(w, r, sessions, sessionID)
+			serveTraced(w, r, "DELETE /sessions/{id}", func(w http.ResponseWriter, r *http.Request) {
+				if !admitProxied(w, r) {
+					return
+				}
+				defer proxyLimit.Release()
+				handleDeleteSession(w, r, sessions, sessionID)
+			})
 		default:
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		}
 	})
 
 	mux.HandleFunc("/sessions", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
+		case http.MethodGet:
+			handleListSessions(w, sessions)
 		case http.MethodPost:
-			handleCreateSession(w, r, pool, sessions)
+			serveTraced(w, r, "POST /sessions", func(w http.ResponseWriter, r *http.Request) {
+				if !admitProxied(w, r) {
+					return
+				}
+				defer proxyLimit.Release()
+				handleCreateSession(w, r, pools, sessions, cfg.MaxBodyBytes, cfg.CreateRequired, cfg.PriorityTokens, cfg.Debug)
+			})
+		case http.MethodDelete:
+			serveTraced(w, r, "DELETE /sessions", func(w http.ResponseWriter, r *http.Request) {
+				if !admitProxied(w, r) {
+					return
+				}
+				defer proxyLimit.Release()
+				handleDeleteAllSessions(w, r, sessions)
+			})
 		default:
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		}
 	})
 
@@ -82,131 +453,832 @@ func main() {
 		fmt.Fprint(w, "ok")
 	})
 
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		handleReadyz(w, pools)
+	})
+
+	if cfg.AdminPort == 0 {
+		addAdminRoutes(mux, pools, sessions)
+	}
+	return mux
+}
+
+// newAdminMux returns the handler for the --admin-port listener.
+func newAdminMux(pools *PoolSet, sessions *SessionManager) http.Handler {
+	mux := http.NewServeMux()
+	addAdminRoutes(mux, pools, sessions)
+	return mux
+}
+
+// addAdminRoutes registers the operational routes: status, metrics, events,
+// capacity, binary rollout, rolling restart, cordon and the debug crash hook.
+func addAdminRoutes(mux *http.ServeMux, pools *PoolSet, sessions *SessionManager) {
 	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
-		handleStatus(w, pool, sessions)
+		handleStatus(w, r, pools, sessions)
+	})
+
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		handleMetrics(w, pools, sessions)
+	})
+
+	mux.HandleFunc("/events/history", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		handleEventHistory(w, r, sessions.events)
+	})
+
+	mux.HandleFunc("/admin/capacity", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		handleCapacity(w, r, pools)
+	})
+
+	mux.HandleFunc("/pool/binary", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		handleSetBinary(w, r, pools)
+	})
+
+	mux.HandleFunc("/pool/rolling-restart", func(w http.ResponseWriter, r *http.Request) {
+		handleRollingRestart(w, r, pools)
+	})
+
+	mux.HandleFunc("/workers/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		handleCordon(w, r, pools)
 	})
 
 	// Debug endpoint — kills the worker holding the given session (for testing only)
 	mux.HandleFunc("/debug/crash-worker", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 			return
 		}
 		sessionID := r.URL.Query().Get("session_id")
 		if sessionID == "" {
-			http.Error(w, "session_id required", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, "session_id required")
 			return
 		}
-		worker, ok := pool.FindBySession(sessionID)
+		worker, ok := pools.FindBySession(sessionID)
 		if !ok {
-			http.Error(w, "session not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, "session not found")
 			return
 		}
-		log.Printf("[debug] killing worker %d holding session %s", worker.ID, sessionID)
-		worker.Kill()
+		requestLogger(r).Warn("debug: killing worker", "worker_id", worker.ID, "port", worker.Port, "session_id", sessionID)
+		worker.Kill("debug crash-worker")
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprint(w, "worker killed")
 	})
+}
 
-	// Graceful shutdown on SIGINT/SIGTERM
-	go func() {
-		sigCh := make(chan os.Signal, 1)
-		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-		sig := <-sigCh
-		log.Printf("Received %s, shutting down...", sig)
-		pool.Shutdown()
-		os.Exit(0)
-	}()
+// newServer returns an http.Server for addr with the configured timeouts and
+// request contexts derived from base.
+func newServer(addr string, h http.Handler, cfg *Config, base context.Context) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadHeaderTimeout: time.Duration(cfg.ReadHeaderTimeout),
+		ReadTimeout:       time.Duration(cfg.ReadTimeout),
+		WriteTimeout:      time.Duration(cfg.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.IdleTimeout),
+		BaseContext:       func(net.Listener) context.Context { return base },
+	}
+}
+
+// listenUnix listens on the Unix socket at path. A socket file left behind
+// by an orchestrator that did not shut down cleanly is replaced; one that
+// still accepts connections, or any other file at path, fails the listen.
+func listenUnix(path string) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if c, err := net.Dial("unix", path); err == nil {
+			c.Close()
+			return nil, fmt.Errorf("%s is in use by another process", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket: %w", err)
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// shutdownForceGrace is how far past --shutdown-timeout the backstop timer
+// fires, leaving the ordinary path time to report stuck workers itself.
+const shutdownForceGrace = time.Second
+
+// writeError sends the JSON error envelope used by every orchestrator
+// endpoint: {"error": "<message>"}.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeErrorFields(w, status, msg, nil)
+}
+
+// writeErrorFields is writeError with extra top-level fields in the envelope
+// alongside "error", for clients that act on more than the message.
+func writeErrorFields(w http.ResponseWriter, status int, msg string, fields map[string]any) {
+	body := map[string]any{"error": msg}
+	for k, v := range fields {
+		body[k] = v
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeStatusText renders /status?format=text.
+func writeStatusText(w http.ResponseWriter, pools *PoolSet, sessions *SessionManager) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if m := sessions.MaxSessions(); m > 0 {
+		fmt.Fprintf(tw, "sessions\t%d (max %d, %d rejected)\n", sessions.Count(), m, sessions.LimitRejections())
+	} else {
+		fmt.Fprintf(tw, "sessions\t%d\n", sessions.Count())
+	}
+	fmt.Fprintf(tw, "in-flight\t%d\n", proxyLimit.InFlight())
+	// With tagged pools each gets its own indented block under a pool line.
+	all := pools.All()
+	indent := ""
+	if len(all) > 1 {
+		indent = "  "
+	}
+	for _, p := range all {
+		if indent != "" {
+			fmt.Fprintf(tw, "pool\t%s\n", p.Tag())
+		}
+		writePoolStatusText(tw, p, indent)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "POOL\tID\tPORT\tSTATE\tSESSION\tUPTIME\tRESTARTS\tREQUESTS")
+	for _, wr := range pools.Workers() {
+		sid := strings.Join(wr.Sessions(), ",")
+		if sid == "" {
+			sid = "-"
+		}
+		age := "-"
+		if started := wr.StartedAt(); !started.IsZero() {
+			age = time.Since(started).Round(time.Second).String()
+		}
+		state := wr.State().String()
+		if wr.Cordoned() {
+			state += ",cordoned"
+		}
+		if !wr.QuarantinedAt().IsZero() {
+			state += ",quarantined"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%d\t%d\n", wr.Tag(), wr.ID, wr.Port, state, sid, age, wr.Restarts(), wr.Requests())
+	}
+	tw.Flush()
+}
+
+// writePoolStatusText writes one pool's summary lines, each prefixed by indent.
+func writePoolStatusText(tw io.Writer, pool *Pool, indent string) {
+	workers := pool.Workers()
+	create := pool.CreateLatency()
+	wait := pool.AcquireWait()
 
-	addr := fmt.Sprintf(":%d", *port)
-	log.Printf("Orchestrator listening on %s", addr)
-	if err := http.ListenAndServe(addr, mux); err != nil {
-		log.Fatalf("Server failed: %v", err)
+	if spw := pool.SessionsPerWorker(); spw > 1 {
+		fmt.Fprintf(tw, "%sworkers\t%d (%d ready, min %d, max %d, %d free slots, %d sessions each)\n", indent, len(workers), pool.ReadyWorkers(), pool.Min(), pool.Max(), pool.FreeSlots(), spw)
+	} else {
+		fmt.Fprintf(tw, "%sworkers\t%d (%d ready, min %d, max %d, %d available)\n", indent, len(workers), pool.ReadyWorkers(), pool.Min(), pool.Max(), pool.QueueDepth())
+	}
+	if n := pool.Quarantined(); n > 0 {
+		fmt.Fprintf(tw, "%squarantined\t%d (out of the queue after failing health checks)\n", indent, n)
 	}
+	if c := pool.Condition(); c.Condition != conditionOK {
+		fmt.Fprintf(tw, "%scondition\t%s (%d of %d ready)\n", indent, c.Condition, c.ReadyWorkers, c.ConfiguredMin)
+	}
+	if d := pool.Degraded(); d.Degraded {
+		fmt.Fprintf(tw, "%sDEGRADED\tall workers unhealthy since %s: %s\n", indent, d.Since.Format(time.RFC3339), d.LastError)
+	}
+	fmt.Fprintf(tw, "%squeue\t%d / %s  (%d rejected as full)\n", indent, pool.Waiting(), limitString(int64(pool.MaxQueueDepth())), pool.QueueRejections())
+	fmt.Fprintf(tw, "%screate latency\tp50 %.0fms  p95 %.0fms  avg %.0fms\n", indent, create.Quantile(0.50), create.Quantile(0.95), create.AvgMs)
+	fmt.Fprintf(tw, "%sacquire wait\tp50 %.0fms  p95 %.0fms  p99 %.0fms  (%d timeouts)\n", indent, wait.P50Ms, wait.P95Ms, wait.P99Ms, pool.AcquireTimeouts())
+	fmt.Fprintf(tw, "%sreadiness failures\t%d\n", indent, pool.ReadinessFailures())
+}
+
+// writeSessionNotFound answers a request for a session that has no mapping:
+// 410 Gone with the reason if the session was lost to a worker fault the
+// client should know about, otherwise 404.
+func writeSessionNotFound(w http.ResponseWriter, sessions *SessionManager, sessionID string) {
+	reason, gone := sessions.Gone(sessionID)
+	if !gone {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	writeErrorFields(w, http.StatusGone, "session lost: "+goneMessages[reason], map[string]any{"reason": reason})
+}
+
+// goneMessages explains each Fail reason in the 410 error message.
+var goneMessages = map[string]string{
+	string(exitMemoryLimit): "its worker exceeded the memory limit and was restarted",
+}
+
+// fatal logs err at error level and exits, standing in for log.Fatalf.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
 
 const maxCreateRetries = 3
 
+// errPriorityForbidden is requestPriority's error for X-Priority: high
+// without an allowed token.
+var errPriorityForbidden = errors.New("X-Priority: high requires an authorized bearer token")
+
+// requestPriority reads a create's X-Priority header. High priority is only
+// granted to requests whose Authorization bearer token is in tokens.
+func requestPriority(r *http.Request, tokens []string) (Priority, error) {
+	prio, err := parsePriority(strings.ToLower(strings.TrimSpace(r.Header.Get("X-Priority"))))
+	if err != nil || prio != PriorityHigh {
+		return prio, err
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return 0, errPriorityForbidden
+	}
+	for _, allowed := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(allowed)) == 1 {
+			return prio, nil
+		}
+	}
+	return 0, errPriorityForbidden
+}
+
+// validateCreateBody checks a POST /sessions body without a worker: it must
+// be a JSON object holding each required top-level field with a non-null
+// value. An empty body is passed through as before unless fields are
+// required.
+func validateCreateBody(body []byte, required []string) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		if len(required) > 0 {
+			return fmt.Errorf("request body must be a JSON object with %s", strings.Join(required, ", "))
+		}
+		return nil
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		if json.Valid(body) {
+			return errors.New("request body must be a JSON object")
+		}
+		return errors.New("request body is not valid JSON")
+	}
+	for _, field := range required {
+		if v, ok := obj[field]; !ok || string(v) == "null" {
+			return fmt.Errorf("request body is missing required field %q", field)
+		}
+	}
+	return nil
+}
+
+// takePoolField removes the top-level "pool" field, and its alias "group",
+// from a validated create body and returns the value, so the worker never
+// sees it. A body with neither is returned unchanged with tag "".
+func takePoolField(body []byte) (tag string, rest []byte, err error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return "", body, nil
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return "", nil, err
+	}
+	found := false
+	for _, key := range []string{"pool", "group"} {
+		raw, ok := obj[key]
+		if !ok {
+			continue
+		}
+		var v string
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return "", nil, fmt.Errorf("%s must be a string", key)
+		}
+		if found && v != tag {
+			return "", nil, errors.New("pool and group name different pools")
+		}
+		tag, found = v, true
+		delete(obj, key)
+	}
+	if !found {
+		return "", body, nil
+	}
+	rest, err = json.Marshal(obj)
+	return tag, rest, err
+}
+
+// takeWebhookField removes the top-level "webhook_url" field from a
+// validated create body and returns it, checked, so the worker never sees
+// it. A body without one is returned unchanged with target "".
+func takeWebhookField(body []byte) (target string, rest []byte, err error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return "", body, nil
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return "", nil, err
+	}
+	raw, ok := obj["webhook_url"]
+	if !ok {
+		return "", body, nil
+	}
+	if err := json.Unmarshal(raw, &target); err != nil {
+		return "", nil, errors.New("webhook_url must be a string")
+	}
+	if err := checkWebhookURL(target); err != nil {
+		return "", nil, err
+	}
+	delete(obj, "webhook_url")
+	rest, err = json.Marshal(obj)
+	return target, rest, err
+}
+
 // handleCreateSession handles POST /sessions
-// Retries with a new worker if the first one fails (EOF, crash, etc.)
-func handleCreateSession(w http.ResponseWriter, r *http.Request, pool *Pool, sessions *SessionManager) {
+// Retries with a new worker if the first one fails (EOF, crash, 5xx, etc.);
+// a 4xx from the worker is relayed to the client without a retry.
+// Bodies larger than maxBody bytes are rejected with 413, and bodies that are
+// not a JSON object with every required field with 400, before a worker is
+// acquired. X-Priority picks the caller's lane in the waiter queue; high
+// requires a bearer token listed in priorityTokens. A "pool" (or "group")
+// field in the body, or an X-Worker-Group header, picks a --worker-pool by
+// tag; the field is not forwarded, nor is "webhook_url", which is called
+// when the session expires or is lost with its worker. With --max-sessions
+// reached, the create gets 503 before it queues for a worker.
+func handleCreateSession(w http.ResponseWriter, r *http.Request, pools *PoolSet, sessions *SessionManager, maxBody int64, required, priorityTokens []string, debug bool) {
+	prio, err := requestPriority(r, priorityTokens)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errPriorityForbidden) {
+			status = http.StatusForbidden
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+
+	// X-Force-Worker pins the create to one worker, with no queueing and
+	// no retry elsewhere (--debug only).
+	forceWorker := -1
+	if h := r.Header.Get("X-Force-Worker"); h != "" {
+		if !debug {
+			writeError(w, http.StatusForbidden, "X-Force-Worker requires --debug")
+			return
+		}
+		if forceWorker, err = strconv.Atoi(h); err != nil || forceWorker < 0 {
+			writeError(w, http.StatusBadRequest, "X-Force-Worker must be a worker id")
+			return
+		}
+	}
+
 	// Read request body
-	body, err := io.ReadAll(r.Body)
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
 	if err != nil {
-		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+		writeError(w, http.StatusBadRequest, "failed to read request body")
 		return
 	}
 	defer r.Body.Close()
+	if err := validateCreateBody(body, required); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	tag, body, err := takePoolField(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	webhook, body, err := takeWebhookField(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if h := r.Header.Get("X-Worker-Group"); h != "" {
+		if tag != "" && tag != h {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("X-Worker-Group %q and body pool %q differ", h, tag))
+			return
+		}
+		tag = h
+	}
+	pool, ok := pools.Get(tag)
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown pool %q", tag))
+		return
+	}
+
+	// ?direct=true adds the worker's base_url so the client can bypass the proxy.
+	direct := false
+	if v := r.URL.Query().Get("direct"); v != "" {
+		if direct, err = strconv.ParseBool(v); err != nil {
+			writeError(w, http.StatusBadRequest, "direct must be true or false")
+			return
+		}
+	}
+
+	// Claim a --max-sessions slot before queueing for a worker.
+	release, err := sessions.Reserve()
+	if err != nil {
+		requestLogger(r).Warn("rejecting create — session limit reached", "max_sessions", sessions.MaxSessions())
+		w.Header().Set("Retry-After", "1")
+		writeErrorFields(w, http.StatusServiceUnavailable, err.Error(), map[string]any{
+			"sessions":     sessions.Count(),
+			"max_sessions": sessions.MaxSessions(),
+		})
+		return
+	}
+	defer release()
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
 	defer cancel()
 
+	log := requestLogger(r)
+	maxAttempts := maxCreateRetries
+	if forceWorker >= 0 {
+		maxAttempts = 1
+	}
 	var lastErr error
-	for attempt := 0; attempt < maxCreateRetries; attempt++ {
-		worker, err := pool.Acquire(ctx)
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		// The acquire span is the queue wait, the worker span that follows
+		// is the create itself.
+		_, acquireSpan := startSpan(ctx, "pool.acquire", spanKindInternal, "pool", pool.Tag(), "priority", prio.String(), "attempt", attempt+1)
+		var worker *Worker
+		if forceWorker >= 0 {
+			worker, err = pool.AcquireWorker(forceWorker)
+		} else {
+			worker, err = pool.Acquire(ctx, prio)
+		}
 		if err != nil {
-			http.Error(w, fmt.Sprintf("failed to acquire worker: %v", err), http.StatusServiceUnavailable)
-			http.Error(w, "no workers available (queue timeout)", http.StatusServiceUnavailable)
+			acquireSpan.SetError(err)
+			acquireSpan.End()
+			switch {
+			case errors.Is(err, errWorkerNotFound):
+				writeError(w, http.StatusNotFound, fmt.Sprintf("worker %d not found", forceWorker))
+			case errors.Is(err, errWorkerUnavailable):
+				writeError(w, http.StatusConflict, fmt.Sprintf("worker %d is busy or not ready", forceWorker))
+			default:
+				writeAcquireError(w, pool, err)
+			}
 			return
 		}
+		if forceWorker >= 0 {
+			log.Info("create forced onto worker", "worker_id", worker.ID, "port", worker.Port)
+		}
+		acquireSpan.SetAttr("worker_id", worker.ID, "port", worker.Port)
+		acquireSpan.End()
 
-		respBody, statusCode, err := forwardCreateSession(worker, body)
+		start := time.Now()
+		respBody, statusCode, err := forwardCreateSession(r.Context(), worker, body)
 		if err != nil {
-			log.Printf("[handler] create attempt %d/%d failed on worker %d: %v", attempt+1, maxCreateRetries, worker.ID, err)
+			log.Warn("create attempt failed", "attempt", attempt+1, "max_attempts", maxCreateRetries, "worker_id", worker.ID, "port", worker.Port, "error", err)
 			lastErr = err
-			worker.Kill() // force restart — monitor goroutine handles recovery
+			dropClaim(worker, "create forward failed") // force restart — monitor goroutine handles recovery
 			continue
 		}
 
+		// A 5xx means the worker itself is in trouble: recycle it and retry.
+		if statusCode >= http.StatusInternalServerError {
+			log.Warn("create attempt got server error", "attempt", attempt+1, "max_attempts", maxCreateRetries, "worker_id", worker.ID, "port", worker.Port, "status", statusCode)
+			lastErr = fmt.Errorf("worker returned %d", statusCode)
+			dropClaim(worker, "create returned server error")
+			continue
+		}
+
+		// Any other non-2xx (typically 4xx) is the client's problem, not the
+		// worker's: hand the worker back untouched and relay the response as is.
+		if statusCode < 200 || statusCode >= 300 {
+			log.Info("worker rejected create request", "worker_id", worker.ID, "port", worker.Port, "status", statusCode)
+			worker.Unclaim()
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(statusCode)
+			writeBody(w, r, respBody)
+			return
+		}
+
 		// Parse response to extract session ID
 		var sessionResp struct {
 			ID        string          `json:"id"`
 			CreatedAt json.RawMessage `json:"created_at"`
 			Data      json.RawMessage `json:"data"`
 		}
-		if err := json.Unmarshal(respBody, &sessionResp); err != nil {
-			log.Printf("[handler] create attempt %d/%d: bad response from worker %d: %s", attempt+1, maxCreateRetries, worker.ID, string(respBody))
+		if err := json.Unmarshal(respBody, &sessionResp); err != nil || sessionResp.ID == "" {
+			log.Warn("create attempt got bad response", "attempt", attempt+1, "max_attempts", maxCreateRetries, "worker_id", worker.ID, "port", worker.Port, "body", string(respBody))
 			lastErr = fmt.Errorf("failed to parse worker response")
-			worker.Kill()
+			dropClaim(worker, "unparseable create response")
 			continue
 		}
 
-		// Success — register the session and return
-		sessions.Add(sessionResp.ID, worker)
-		worker.SetSessionID(sessionResp.ID)
+		// Success — record latency, register the session and return
+		pool.ObserveCreateLatency(time.Since(start))
+		if err := sessions.Add(sessionResp.ID, worker, webhook); err != nil {
+			// The ID is live on another worker. Undo the new session and
+			// keep the old one rather than orphan it.
+			if _, derr := deleteSessionFromWorker(r.Context(), worker, sessionResp.ID); derr != nil {
+				log.Warn("could not delete duplicate session from worker", "session_id", sessionResp.ID, "worker_id", worker.ID, "error", derr)
+			}
+			worker.Unclaim()
+			writeError(w, http.StatusConflict, fmt.Sprintf("session %s already exists", sessionResp.ID))
+			return
+		}
+		worker.AddSession(sessionResp.ID)
+		log.Info("session created", "session_id", sessionResp.ID, "worker_id", worker.ID, "pool", pool.Tag(), "port", worker.Port, "direct", direct)
 
+		if direct {
+			if merged, err := withField(respBody, "base_url", worker.BaseURL()); err == nil {
+				respBody = merged
+			} else {
+				log.Warn("could not add base_url to create response", "session_id", sessionResp.ID, "error", err)
+			}
+		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(statusCode)
-		w.Write(respBody)
+		if err := writeBody(w, r, respBody); err != nil {
+			// The client never learned the session ID, so nobody can use
+			// or delete it: undo it now rather than hold a worker slot
+			// until the TTL sweeper gets to it.
+			undoUndeliveredCreate(r, sessions, sessionResp.ID)
+		}
 		return
 	}
 
 	// All retries exhausted
-	http.Error(w, fmt.Sprintf("all workers failed: %v", lastErr), http.StatusBadGateway)
+	writeError(w, http.StatusBadGateway, fmt.Sprintf("all workers failed: %v", lastErr))
+}
+
+// writeBody writes a relayed worker response body and flushes it, so a
+// client that has gone away shows up as an error here rather than being
+// lost in the server's buffer. The failure is logged; by now the status line
+// is out and nothing else can be sent.
+func writeBody(w http.ResponseWriter, r *http.Request, body []byte) error {
+	_, err := w.Write(body)
+	if err == nil {
+		err = http.NewResponseController(w).Flush()
+	}
+	if err == nil {
+		err = r.Context().Err() // hung up while the worker was answering
+	}
+	if err != nil {
+		requestLogger(r).Warn("could not deliver response — client gone", "path", r.URL.Path, "bytes", len(body), "error", err)
+	}
+	return err
+}
+
+// undoUndeliveredCreate deletes a session whose create response never
+// reached the client, from both the session table and its worker.
+func undoUndeliveredCreate(r *http.Request, sessions *SessionManager, sessionID string) {
+	log := requestLogger(r).With("session_id", sessionID)
+	worker := sessions.Remove(sessionID)
+	if worker == nil {
+		return // already deleted or reaped
+	}
+	if _, err := deleteSessionFromWorker(r.Context(), worker, sessionID); err != nil {
+		log.Warn("could not delete undelivered session from worker", "worker_id", worker.ID, "port", worker.Port, "error", err)
+	}
+	worker.RemoveSession(sessionID)
+	log.Info("deleted session whose create response was not delivered", "worker_id", worker.ID)
+}
+
+// dropClaim gives up the slot a failed create attempt claimed on worker. A
+// worker holding no other session is restarted, since it may be what failed;
+// one still serving other sessions is left to the health check so they are
+// not lost with it.
+func dropClaim(worker *Worker, reason string) {
+	if len(worker.Sessions()) == 0 {
+		worker.Kill(reason)
+		return
+	}
+	worker.Unclaim()
+}
+
+// writeAcquireError answers a create that gave up waiting for a worker: 503
+// with the caller's queue position and the pool's load in the body, and the
+// position in X-Queue-Position, so clients can make informed retries. A create
+// turned away by --max-queue-depth gets 503 "queue full" and Retry-After, and
+// one failed fast by a degraded pool gets 503 with the latest worker error.
+func writeAcquireError(w http.ResponseWriter, pool *Pool, err error) {
+	if errors.Is(err, ErrPoolDegraded) {
+		d := pool.Degraded()
+		writeErrorFields(w, http.StatusServiceUnavailable, ErrPoolDegraded.Error(), map[string]any{
+			"degraded":       true,
+			"degraded_since": d.Since,
+			"last_error":     d.LastError,
+		})
+		return
+	}
+	if errors.Is(err, ErrQueueFull) {
+		w.Header().Set("Retry-After", "1")
+		writeErrorFields(w, http.StatusServiceUnavailable, "queue full", map[string]any{
+			"queue_depth":     pool.Waiting(),
+			"max_queue_depth": pool.MaxQueueDepth(),
+		})
+		return
+	}
+	msg := fmt.Sprintf("no workers available: %v", err)
+	var ae *AcquireError
+	if !errors.As(err, &ae) {
+		writeError(w, http.StatusServiceUnavailable, msg)
+		return
+	}
+	w.Header().Set("X-Queue-Position", strconv.Itoa(ae.Position))
+	writeErrorFields(w, http.StatusServiceUnavailable, msg, map[string]any{
+		"queue_position": ae.Position,
+		"queue_depth":    ae.QueueDepth,
+		"workers":        ae.Workers,
+		"booting":        ae.Booting,
+		"max_workers":    ae.MaxWorkers,
+	})
+}
+
+// poolReadiness is one pool's entry in /readyz.
+type poolReadiness struct {
+	Pool  string `json:"pool"`
+	Ready bool   `json:"ready"`
+	DegradedStatus
+	ConditionSnapshot
+}
+
+func readinessOf(pool *Pool) poolReadiness {
+	ready := false
+	select {
+	case <-pool.Ready():
+		ready = true
+	default:
+	}
+	d := pool.Degraded()
+	return poolReadiness{pool.Tag(), ready && !d.Degraded, d, pool.SweptCondition()}
+}
+
+// handleReadyz handles GET /readyz: 200 once every pool's initial workers
+// are up and none is degraded (every worker failing), 503 otherwise, with
+// the latest worker error. The top-level fields describe the default pool;
+// with tagged pools, pools lists each of them.
+// The condition and worker counts are those of the latest health sweep, so
+// frequent scrapes never scan the workers.
+func handleReadyz(w http.ResponseWriter, pools *PoolSet) {
+	all := pools.All()
+	entries := make([]poolReadiness, len(all))
+	ready := true
+	for i, p := range all {
+		entries[i] = readinessOf(p)
+		ready = ready && entries[i].Ready
+	}
+	def := entries[0]
+	if len(entries) == 1 {
+		entries = nil
+	}
+	code := http.StatusOK
+	if !ready {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(struct {
+		Ready bool `json:"ready"`
+		DegradedStatus
+		ConditionSnapshot
+		Pools []poolReadiness `json:"pools,omitempty"`
+	}{ready, def.DegradedStatus, def.ConditionSnapshot, entries})
 }
 
 // handleGetSession handles GET /sessions/:id
-// If the worker holding the session is dead, cleans up the stale mapping and returns 404.
+// A failed forward is retried once if the session has meanwhile moved to
+// another worker. If its worker has died the session is lost with it (404);
+// if the worker is alive but did not answer, it is left to the health
+// checker, since it may hold other sessions, and the call returns 502.
 func handleGetSession(w http.ResponseWriter, r *http.Request, sessions *SessionManager, sessionID string) {
 	worker := sessions.Get(sessionID)
 	if worker == nil {
-		http.Error(w, "session not found", http.StatusNotFound)
+		writeSessionNotFound(w, sessions, sessionID)
 		return
 	}
 
-	respBody, statusCode, err := forwardGetSession(worker, sessionID)
-	if err != nil {
-		// Worker is dead — session is lost. Clean up the stale mapping.
-		log.Printf("[handler] GET forward failed, session %s lost (worker %d dead): %v", sessionID, worker.ID, err)
-		sessions.Remove(sessionID)
-		worker.Kill()
-		http.Error(w, "session not found", http.StatusNotFound)
+	for attempt := 1; ; attempt++ {
+		respBody, statusCode, err := forwardGetSession(r.Context(), worker, sessionID)
+		if err == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(statusCode)
+			writeBody(w, r, respBody)
+			return
+		}
+
+		// A read is safe to repeat: if the session was moved to another
+		// worker while this one was failing, ask the new worker instead.
+		next := sessions.Get(sessionID)
+		if next != nil && next != worker && attempt < getSessionAttempts && r.Context().Err() == nil {
+			requestLogger(r).Info("GET forward failed, retrying on the session's new worker", "session_id", sessionID,
+				"worker_id", worker.ID, "new_worker_id", next.ID, "error", err)
+			worker = next
+			continue
+		}
+
+		log := requestLogger(r).With("session_id", sessionID, "worker_id", worker.ID, "port", worker.Port, "attempt", attempt, "error", err)
+		if next == nil || worker.State() == WorkerStateDead {
+			// The worker died; its crash handler drops its sessions.
+			log.Warn("GET forward failed, session lost with its worker")
+			writeError(w, http.StatusNotFound, "session not found")
+			return
+		}
+		// The worker may still serve its other sessions: whether it is
+		// unhealthy is for the health checker to decide, not one GET.
+		log.Warn("GET forward failed, worker left to the health checker")
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("session's worker did not answer: %v", err))
+		return
+	}
+}
+
+// getSessionAttempts bounds the forwards one GET /sessions/:id makes: the
+// first, plus one retry if the session has meanwhile been remapped to a
+// different worker. A failure on the worker the session still maps to is
+// final.
+const getSessionAttempts = 2
+
+// handleGetSessionWorker handles GET /sessions/:id/worker, returning which
+// worker serves the session. It does not refresh the session's TTL.
+func handleGetSessionWorker(w http.ResponseWriter, sessions *SessionManager, sessionID string) {
+	entry, ok := sessions.Lookup(sessionID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "session not found")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	w.Write(respBody)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"session_id":    entry.SessionID,
+		"worker_id":     entry.Worker.ID,
+		"pool":          entry.Worker.Tag(),
+		"port":          entry.Worker.Port,
+		"base_url":      entry.Worker.BaseURL(),
+		"state":         entry.Worker.State().String(),
+		"last_accessed": entry.LastAccessed,
+		"alias":         entry.Alias,
+	})
+}
+
+// handleSetSessionAlias handles POST /sessions/:id/alias with
+// {"alias": "my-job"}, after which /sessions/my-job routes reach the
+// session. 409 if the alias is another session's.
+func handleSetSessionAlias(w http.ResponseWriter, r *http.Request, sessions *SessionManager, sessionID string) {
+	var req struct {
+		Alias string `json:"alias"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "body must be a JSON object with an alias")
+		return
+	}
+	if err := validateAlias(req.Alias); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	switch err := sessions.SetAlias(sessionID, req.Alias); {
+	case errors.Is(err, errNoSession):
+		writeSessionNotFound(w, sessions, sessionID)
+		return
+	case errors.Is(err, errAliasTaken):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	requestLogger(r).Info("session alias set", "session_id", sessionID, "alias", req.Alias)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"session_id": sessionID,
+		"alias":      req.Alias,
+	})
+}
+
+// handleListSessions handles GET /sessions: every live session with its
+// worker, pool and last access time, ordered by session ID. Does not refresh TTLs.
+func handleListSessions(w http.ResponseWriter, sessions *SessionManager) {
+	entries := sessions.List()
+	list := make([]map[string]interface{}, len(entries))
+	for i, e := range entries {
+		list[i] = map[string]interface{}{
+			"session_id":    e.SessionID,
+			"worker_id":     e.Worker.ID,
+			"pool":          e.Worker.Tag(),
+			"port":          e.Worker.Port,
+			"last_accessed": e.LastAccessed,
+			"alias":         e.Alias,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"count":    len(list),
+		"sessions": list,
+	})
 }
 
 // handleDeleteSession handles DELETE /sessions/:id
@@ -214,48 +1286,445 @@ func handleDeleteSession(w http.ResponseWriter, r *http.Request, sessions *Sessi
 	// Look up and remove the session mapping
 	worker := sessions.Remove(sessionID)
 	if worker == nil {
-		http.Error(w, "session not found", http.StatusNotFound)
+		writeSessionNotFound(w, sessions, sessionID)
 		return
 	}
 
 	// Forward delete to the worker
-	statusCode, err := deleteSessionFromWorker(worker, sessionID)
+	statusCode, err := deleteSessionFromWorker(r.Context(), worker, sessionID)
 	if err != nil {
 		// Session already removed from our mapping; worker might be down
-		log.Printf("[handler] DELETE forward failed for session %s: %v", sessionID, err)
-		worker.SetSessionID("")
+		requestLogger(r).Warn("DELETE forward failed", "session_id", sessionID, "worker_id", worker.ID, "port", worker.Port, "error", err)
+		worker.RemoveSession(sessionID)
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
 	// Free the worker
-	worker.SetSessionID("")
+	worker.RemoveSession(sessionID)
 
 	w.WriteHeader(statusCode)
 }
 
-// handleStatus returns pool and session status for debugging.
-func handleStatus(w http.ResponseWriter, pool *Pool, sessions *SessionManager) {
-	workers := pool.Workers()
+// withField returns the JSON object body with key set to value. Re-encoding
+// sorts the object's keys.
+func withField(body []byte, key string, value any) ([]byte, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return nil, err
+	}
+	v, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	obj[key] = v
+	return json.Marshal(obj)
+}
+
+// bulkDeleteParallelism bounds concurrent worker deletes in DELETE /sessions
+// and the shutdown teardown.
+const bulkDeleteParallelism = 16
+
+// handleDeleteAllSessions handles DELETE /sessions: terminates every session
+// that existed when the request arrived. The list is snapshotted first, so
+// sessions created concurrently are left alone; each mapping is removed
+// before its worker-side delete, exactly as for a single DELETE.
+func handleDeleteAllSessions(w http.ResponseWriter, r *http.Request, sessions *SessionManager) {
+	log := requestLogger(r)
+	deleted, failed := deleteSessions(r.Context(), sessions, func(sessionID string, worker *Worker, err error) {
+		if err != nil {
+			log.Warn("bulk DELETE forward failed", "session_id", sessionID, "worker_id", worker.ID, "port", worker.Port, "error", err)
+		}
+	})
+
+	log.Info("bulk delete finished", "deleted", deleted, "failed", failed)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{
+		"deleted": deleted,
+		"failed":  failed,
+	})
+}
+
+// deleteSessions removes every session that exists now and forwards its
+// DELETE to the worker, bulkDeleteParallelism at a time, then frees the
+// worker. done is called for each one with the forward's outcome; a 404 from
+// the worker counts as deleted.
+func deleteSessions(ctx context.Context, sessions *SessionManager, done func(sessionID string, worker *Worker, err error)) (deleted, failed int64) {
+	var nDeleted, nFailed atomic.Int64
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, bulkDeleteParallelism)
+
+	for _, entry := range sessions.List() {
+		worker := sessions.Remove(entry.SessionID)
+		if worker == nil {
+			continue // deleted or expired since the snapshot
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(sessionID string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			statusCode, err := deleteSessionFromWorker(ctx, worker, sessionID)
+			worker.RemoveSession(sessionID)
+			if err == nil && statusCode >= 300 && statusCode != http.StatusNotFound {
+				err = fmt.Errorf("worker answered %d", statusCode)
+			}
+			if err != nil {
+				nFailed.Add(1)
+			} else {
+				nDeleted.Add(1)
+			}
+			done(sessionID, worker, err)
+		}(entry.SessionID)
+	}
+	wg.Wait()
+	return nDeleted.Load(), nFailed.Load()
+}
+
+// teardownSessions deletes every session on its worker before shutdown, so
+// browsers close cleanly and a client learns from the session_terminated
+// event instead of a timeout. It gives up on whatever is left after grace.
+func teardownSessions(ctx context.Context, sessions *SessionManager, grace time.Duration) {
+	n := sessions.Count()
+	if grace <= 0 || n == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, grace)
+	defer cancel()
+
+	log := logger("session")
+	log.Info("terminating sessions before shutdown", "sessions", n, "grace", grace.String())
+	deleted, failed := deleteSessions(ctx, sessions, func(sessionID string, worker *Worker, err error) {
+		fields := []any{"session_id", sessionID, "worker_id", worker.ID, "port", worker.Port, "reason", "shutdown"}
+		if err != nil {
+			log.Warn("session teardown failed", append(fields, "error", err)...)
+			fields = append(fields, "error", err.Error())
+		}
+		sessions.events.Record(EventSessionTerminated, fields...)
+	})
+	log.Info("session teardown finished", "deleted", deleted, "failed", failed)
+}
+
+// handleStatus returns pool and session status for debugging: JSON by
+// default, or with ?format=text a summary and an aligned worker table for
+// reading over curl.
+func handleStatus(w http.ResponseWriter, r *http.Request, pools *PoolSet, sessions *SessionManager) {
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+	case "text":
+		writeStatusText(w, pools, sessions)
+		return
+	default:
+		writeError(w, http.StatusBadRequest, "format must be json or text")
+		return
+	}
+
+	pool := pools.Default()
+	workers := pools.Workers()
 	workerStatus := make([]map[string]interface{}, len(workers))
 	for i, wr := range workers {
+		var age float64
+		if started := wr.StartedAt(); !started.IsZero() {
+			age = time.Since(started).Round(time.Second).Seconds()
+		}
+		failures, lastErr := wr.Failures()
+		var lastCheck *time.Time
+		probe := wr.LastProbe()
+		if !probe.At.IsZero() {
+			lastCheck = &probe.At
+		}
+		ids := wr.Sessions()
+		sid := ""
+		if len(ids) > 0 {
+			sid = ids[0]
+		}
 		workerStatus[i] = map[string]interface{}{
-			"id":         wr.ID,
-			"port":       wr.Port,
-			"state":      wr.State().String(),
-			"session_id": wr.SessionID(),
+			"id":                    wr.ID,
+			"pool":                  wr.Tag(),
+			"port":                  wr.Port,
+			"state":                 wr.State().String(),
+			"session_id":            sid,
+			"sessions":              ids,
+			"binary":                wr.Binary(),
+			"cordoned":              wr.Cordoned(),
+			"quarantined":           !wr.QuarantinedAt().IsZero(),
+			"rss_bytes":             wr.RSS(),
+			"cpu_percent":           wr.CPUPercent(),
+			"age_seconds":           age,
+			"uptime_seconds":        age,
+			"idle_seconds":          wr.IdleFor(time.Now()).Round(time.Second).Seconds(),
+			"requests":              wr.Requests(),
+			"restarts":              wr.Restarts(),
+			"consecutive_failures":  failures,
+			"last_error":            lastErr,
+			"last_health_check":     lastCheck,
+			"health_check_ms":       float64(probe.Latency.Microseconds()) / 1000,
+			"health_check_ok":       probe.Healthy,
+			"health_failure":        probe.Failure,
+			"health_failure_streak": probe.Streak,
+		}
+	}
+	failed := pool.FailedWorkers()
+	failedStatus := make([]map[string]interface{}, len(failed))
+	for i, f := range failed {
+		failedStatus[i] = map[string]interface{}{
+			"id":         f.ID,
+			"port":       f.Port,
+			"state":      WorkerStateFailed.String(),
+			"reason":     f.Reason,
+			"attempts":   f.Attempts,
+			"last_error": f.LastError,
+			"failed_at":  f.FailedAt,
 		}
 	}
 
+	poolStatus := make(map[string]interface{})
+	for _, p := range pools.All() {
+		cond := p.Condition()
+		poolStatus[p.Tag()] = map[string]interface{}{
+			"worker_count":      len(p.Workers()),
+			"available_workers": p.QueueDepth(),
+			"min_workers":       p.Min(),
+			"ready_workers":     cond.ReadyWorkers,
+			"configured_min":    cond.ConfiguredMin,
+			"condition":         cond.Condition,
+			"workers_by_state":  cond.States,
+			"max_workers":       p.Max(),
+			"queue_depth":       p.Waiting(),
+			"degraded":          p.Degraded().Degraded,
+			"quarantined":       p.Quarantined(),
+			"binary_rollout":    p.BinaryRollout(),
+		}
+	}
+
+	create := pool.CreateLatency()
+	wait := pool.AcquireWait()
+	scale := pool.ScaleSettings()
+	limits := pool.ScaleLimits()
+	baseMin, baseMax := pool.BaseLimits()
+	cond := pool.Condition()
+	var lastScaleUp *time.Time
+	if !scale.LastScaleUp.IsZero() {
+		lastScaleUp = &scale.LastScaleUp
+	}
+
 	status := map[string]interface{}{
-		"active_sessions":   sessions.Count(),
-		"worker_count":      len(workers),
-		"available_workers": pool.QueueDepth(),
-		"min_workers":       pool.Min(),
-		"max_workers":       pool.Max(),
-		"workers":           workerStatus,
+		"active_sessions":          sessions.Count(),
+		"max_sessions":             sessions.MaxSessions(),
+		"session_limit_rejections": sessions.LimitRejections(),
+		"worker_count":             len(workers),
+		"available_workers":        pool.QueueDepth(),
+		"sessions_per_worker":      pool.SessionsPerWorker(),
+		"min_workers":              limits.Min,
+		"ready_workers":            cond.ReadyWorkers,
+		"configured_min":           cond.ConfiguredMin,
+		"condition":                cond.Condition,
+		"workers_by_state":         cond.States,
+		"max_workers":              limits.Max,
+		"base_min_workers":         baseMin,
+		"base_max_workers":         baseMax,
+		"scale_window":             limits.Window,
+		"scale_windows_active":     limits.Active,
+		"create_latency_p50_ms":    create.Quantile(0.50),
+		"create_latency_p95_ms":    create.Quantile(0.95),
+		"create_latency_avg_ms":    create.AvgMs,
+		"acquire_wait_p50_ms":      wait.P50Ms,
+		"acquire_wait_p95_ms":      wait.P95Ms,
+		"acquire_wait_p99_ms":      wait.P99Ms,
+		"acquire_timeouts":         pool.AcquireTimeouts(),
+		"queue_depth":              pool.Waiting(),
+		"max_queue_depth":          pool.MaxQueueDepth(),
+		"queue_rejections":         pool.QueueRejections(),
+		"queue_depth_by_priority":  pool.WaitingByPriority(),
+		"readiness_failures":       pool.ReadinessFailures(),
+		"quarantined_workers":      pool.Quarantined(),
+		"degraded":                 pool.Degraded().Degraded,
+		"binary_rollout":           pool.BinaryRollout(),
+		"inflight_requests":        proxyLimit.InFlight(),
+		"max_inflight":             proxyLimit.Limit(),
+		"scaling": map[string]interface{}{
+			"policy":              scale.Policy,
+			"interval":            scale.Interval.String(),
+			"scale_down_after":    scale.ScaleDownAfter.String(),
+			"scale_up_cooldown":   scale.ScaleUpCooldown.String(),
+			"scale_down_fraction": scale.ScaleDownFraction,
+			"last_scale_up":       lastScaleUp,
+		},
+		"stats":          pool.Stats(),
+		"pools":          poolStatus,
+		"workers":        workerStatus,
+		"failed_workers": failedStatus,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(status)
 }
+
+// handleSetBinary changes the binary a pool starts workers from, see
+// Pool.SetBinary. The body is {"path": "...", "canary": 10}; canary is the
+// percentage of new and restarted workers that get path, 100 if omitted.
+// ?pool= selects a --worker-pool.
+func handleSetBinary(w http.ResponseWriter, r *http.Request, pools *PoolSet) {
+	pool, ok := pools.Get(r.URL.Query().Get("pool"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown pool %q", r.URL.Query().Get("pool")))
+		return
+	}
+	var req struct {
+		Path   string `json:"path"`
+		Canary *int   `json:"canary"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "body must be a JSON object with a path")
+		return
+	}
+	percent := 100
+	if req.Canary != nil {
+		percent = *req.Canary
+	}
+	if percent < 1 || percent > 100 {
+		writeError(w, http.StatusBadRequest, "canary must be between 1 and 100")
+		return
+	}
+	if err := checkBinary(req.Path); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	pool.SetBinary(req.Path, percent)
+	requestLogger(r).Info("worker binary updated", "pool", pool.Tag(), "binary", req.Path, "canary_percent", percent)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pool.BinaryRollout())
+}
+
+// handleRollingRestart starts (POST), reports (GET) or cancels (DELETE) a
+// rolling restart of a pool's workers, see Pool.StartRollingRestart. ?pool=
+// selects a --worker-pool; the default pool otherwise.
+func handleRollingRestart(w http.ResponseWriter, r *http.Request, pools *PoolSet) {
+	pool, ok := pools.Get(r.URL.Query().Get("pool"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown pool %q", r.URL.Query().Get("pool")))
+		return
+	}
+	status := http.StatusOK
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if _, err := pool.StartRollingRestart(); err != nil {
+			writeErrorFields(w, http.StatusConflict, err.Error(), map[string]any{"rolling_restart": pool.RollingRestart()})
+			return
+		}
+		requestLogger(r).Info("rolling restart requested", "pool", pool.Tag())
+		status = http.StatusAccepted
+	case http.MethodDelete:
+		if !pool.CancelRollingRestart() {
+			writeError(w, http.StatusConflict, "no rolling restart is running")
+			return
+		}
+		requestLogger(r).Info("rolling restart canceled", "pool", pool.Tag())
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(pool.RollingRestart())
+}
+
+// handleCordon serves POST /workers/{id}/cordon and /workers/{id}/uncordon.
+// Both are idempotent and answer with the worker's resulting state.
+func handleCordon(w http.ResponseWriter, r *http.Request, pools *PoolSet) {
+	idStr, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/workers/"), "/")
+	if action != "cordon" && action != "uncordon" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "worker id must be an integer")
+		return
+	}
+	pool, ok := pools.Get(r.URL.Query().Get("pool"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown pool %q", r.URL.Query().Get("pool")))
+		return
+	}
+	wr, ok := pool.WorkerByID(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("worker %d not found", id))
+		return
+	}
+	if action == "cordon" {
+		pool.Cordon(wr)
+	} else {
+		pool.Uncordon(wr)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":       wr.ID,
+		"pool":     wr.Tag(),
+		"state":    wr.State().String(),
+		"cordoned": wr.Cordoned(),
+		"sessions": wr.Sessions(),
+	})
+}
+
+// handleCapacity answers "what would N concurrent creates do to the pool" from
+// the current config and metrics, without acquiring or starting anything.
+// Warm-up is estimated as one p95 create round-trip per wave of boots, so it
+// is null until a create has been recorded. ?pool= plans for a --worker-pool.
+func handleCapacity(w http.ResponseWriter, r *http.Request, pools *PoolSet) {
+	n, err := strconv.Atoi(r.URL.Query().Get("concurrency"))
+	if err != nil || n < 1 {
+		writeError(w, http.StatusBadRequest, "concurrency must be a positive integer")
+		return
+	}
+	pool, ok := pools.Get(r.URL.Query().Get("pool"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown pool %q", r.URL.Query().Get("pool")))
+		return
+	}
+	plan := pool.Capacity(n)
+	create := pool.CreateLatency()
+
+	var warmup *float64
+	if create.Total > 0 {
+		ms := create.Quantile(0.95) * float64(plan.BootWaves)
+		warmup = &ms
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		CapacityPlan
+		CreateLatencyP95Ms float64  `json:"create_latency_p95_ms"`
+		CreateSamples      uint64   `json:"create_latency_samples"`
+		EstimatedWarmupMs  *float64 `json:"estimated_warmup_ms"`
+	}{plan, create.Quantile(0.95), create.Total, warmup})
+}
+
+// handleEventHistory handles GET /events/history?type=...&since=...
+// since accepts an RFC 3339 timestamp or a Go duration meaning "this long ago".
+func handleEventHistory(w http.ResponseWriter, r *http.Request, events *EventLog) {
+	q := r.URL.Query()
+
+	var since time.Time
+	if s := q.Get("since"); s != "" {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			since = t
+		} else if d, err := time.ParseDuration(s); err == nil {
+			since = time.Now().Add(-d)
+		} else {
+			writeError(w, http.StatusBadRequest, "since must be an RFC 3339 timestamp or a duration")
+			return
+		}
+	}
+
+	history := events.History(EventType(q.Get("type")), since)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"count":  len(history),
+		"events": history,
+	})
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"steel-orchestrator/client"
+)
+
+// newTestServer serves the session API over a test pool, routed as main
+// routes it, and returns a client for it. The client does not retry, so
+// every call is exactly one request.
+func newTestServer(t *testing.T, cfg PoolConfig) (*client.Client, *Pool, *SessionManager) {
+	t.Helper()
+	pool := newTestPool(t, cfg)
+	pools := newPoolSet(pool)
+	sessions, err := NewSessionManager(time.Minute, time.Minute, NewEventLog(defaultEventHistorySize), nil)
+	if err != nil {
+		t.Fatalf("NewSessionManager: %v", err)
+	}
+	crashHandler := func(sessionIDs []string, kind exitKind) {
+		for _, sessionID := range sessionIDs {
+			sessions.Remove(sessionID)
+		}
+	}
+	pool.CrashHandler = crashHandler
+	for _, w := range pool.Workers() {
+		w.OnCrash = crashHandler
+	}
+	srv := httptest.NewServer(newMux(defaultConfig(), pools, sessions))
+	t.Cleanup(srv.Close)
+
+	c, err := client.New(srv.URL)
+	if err != nil {
+		t.Fatalf("client.New: %v", err)
+	}
+	c.MaxRetries = 0
+	return c, pool, sessions
+}
+
+func TestSessionLifecycleThroughClient(t *testing.T) {
+	c, _, _ := newTestServer(t, PoolConfig{Min: 1, Max: 1})
+	ctx := context.Background()
+
+	s, err := c.CreateSession(ctx, json.RawMessage(`{"profile":"a"}`))
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if s.ID == "" || string(s.Data) != `{"profile":"a"}` {
+		t.Fatalf("created session = %+v, want an ID and the request's data", s)
+	}
+
+	got, err := c.GetSession(ctx, s.ID)
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if got.ID != s.ID {
+		t.Errorf("GetSession ID = %q, want %q", got.ID, s.ID)
+	}
+
+	list, err := c.ListSessions(ctx)
+	if err != nil {
+		t.Fatalf("ListSessions: %v", err)
+	}
+	if len(list) != 1 || list[0].SessionID != s.ID {
+		t.Errorf("ListSessions = %+v, want only %s", list, s.ID)
+	}
+
+	st, err := c.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if st.ActiveSessions != 1 || st.WorkerCount != 1 || st.AvailableWorkers != 0 {
+		t.Errorf("status = %d sessions, %d workers, %d available; want 1, 1, 0",
+			st.ActiveSessions, st.WorkerCount, st.AvailableWorkers)
+	}
+
+	if err := c.DeleteSession(ctx, s.ID); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if _, err := c.GetSession(ctx, s.ID); !client.IsNotFound(err) {
+		t.Errorf("GetSession after delete = %v, want a 404", err)
+	}
+	var apiErr *client.APIError
+	err = c.DeleteSession(ctx, s.ID)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message == "" {
+		t.Errorf("second DeleteSession = %v, want a 404 with an error message", err)
+	}
+}
+
+func TestCreateRelaysWorkerRejection(t *testing.T) {
+	c, p, sessions := newTestServer(t, PoolConfig{Min: 2, Max: 2, Launch: testLaunch("MOCK_REJECT_RATE=1")})
+	workers := p.Workers()
+
+	_, err := c.CreateSession(context.Background(), json.RawMessage(`{}`))
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity || apiErr.Message != "injected rejection" {
+		t.Fatalf("CreateSession = %v, want the worker's 422 as is", err)
+	}
+
+	// A 4xx is the caller's fault: no retry, and every worker is kept.
+	for _, w := range workers {
+		if w.State() != WorkerStateAvailable || w.Load() != 0 {
+			t.Errorf("worker %d is %s with load %d, want Available and empty", w.ID, w.State(), w.Load())
+		}
+	}
+	if got := p.QueueDepth(); got != 2 {
+		t.Errorf("available = %d, want 2", got)
+	}
+	if sessions.Count() != 0 {
+		t.Errorf("sessions = %d, want 0", sessions.Count())
+	}
+}
+
+func TestGetFailureLeavesWorkerAlone(t *testing.T) {
+	c, p, sessions := newTestServer(t, PoolConfig{Min: 1, Max: 1, SessionsPerWorker: 2, Launch: testLaunch("MOCK_MAX_SESSIONS=2")})
+	timeouts := workerTimeouts
+	workerTimeouts.Get = 200 * time.Millisecond
+	t.Cleanup(func() { workerTimeouts = timeouts })
+	ctx := context.Background()
+
+	a, err := c.CreateSession(ctx, nil)
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	b, err := c.CreateSession(ctx, nil)
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	w := p.Workers()[0]
+	pid := w.PID()
+
+	// Freeze the worker holding both sessions: the GET times out.
+	w.mu.Lock()
+	proc := w.cmd.Process
+	w.mu.Unlock()
+	if err := proc.Signal(syscall.SIGSTOP); err != nil {
+		t.Fatalf("SIGSTOP worker %d: %v", w.ID, err)
+	}
+	_, err = c.GetSession(ctx, a.ID)
+	proc.Signal(syscall.SIGCONT)
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("GetSession on a frozen worker = %v, want a 502", err)
+	}
+
+	// One failed read neither kills the worker nor drops its sessions.
+	if w.State() == WorkerStateDead || w.PID() != pid {
+		t.Errorf("worker is %s on pid %d, want it still running on pid %d", w.State(), w.PID(), pid)
+	}
+	for _, id := range []string{a.ID, b.ID} {
+		if got := sessions.Get(id); got != w {
+			t.Errorf("session %s maps to %v, want worker %d", id, got, w.ID)
+		}
+	}
+	if _, err := c.GetSession(ctx, a.ID); err != nil {
+		t.Errorf("GetSession once the worker answers again: %v", err)
+	}
+	if st := p.Stats(); st.Crashes != 0 {
+		t.Errorf("crashes = %d, want 0", st.Crashes)
+	}
+}
+
+func TestCreateRightAfterIdleWorkerDies(t *testing.T) {
+	c, p, sessions := newTestServer(t, PoolConfig{Min: 2, Max: 2})
+
+	// Crash the worker at the head of the queue, the one a create would get.
+	p.available.mu.Lock()
+	dead := p.available.idle[0]
+	p.available.mu.Unlock()
+	if err := dead.proc.Kill(); err != nil {
+		t.Fatalf("kill worker %d: %v", dead.ID, err)
+	}
+	// The exit takes it out of the queue; it is not left for Acquire to pop.
+	// Its restart is a second away, long after the create.
+	waitFor(t, "dead worker to leave the queue", func() bool {
+		return dead.State() == WorkerStateDead && p.available.idleLen() == 1
+	})
+	s, err := c.CreateSession(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if w := sessions.Get(s.ID); w == nil || w == dead {
+		t.Errorf("session %s not on the live worker", s.ID)
+	}
+	if ps := p.Stats(); ps.Acquires != 1 || ps.Crashes != 1 {
+		t.Errorf("acquires = %d, crashes = %d; want 1 and 1 (no create attempt spent on the dead worker)", ps.Acquires, ps.Crashes)
+	}
+}
+
+func TestColdStartFromZeroWorkers(t *testing.T) {
+	const bootDelay = 300 * time.Millisecond
+	c, p, _ := newTestServer(t, PoolConfig{Min: 0, Max: 1, Launch: testLaunch("MOCK_BOOT_DELAY=" + bootDelay.String())})
+	if got := len(p.Workers()); got != 0 {
+		t.Fatalf("workers at start = %d, want 0", got)
+	}
+
+	// An empty pool that can grow is ready: the first create boots a worker.
+	rec := httptest.NewRecorder()
+	handleReadyz(rec, newPoolSet(p))
+	if rec.Code != http.StatusOK {
+		t.Errorf("/readyz with no workers = %d %s, want 200", rec.Code, rec.Body)
+	}
+
+	start := time.Now()
+	s, err := c.CreateSession(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("cold-start CreateSession: %v", err)
+	}
+	if took, limit := time.Since(start), bootDelay+3*time.Second; took > limit {
+		t.Errorf("cold start took %s, want under %s", took, limit)
+	}
+	if got := len(p.Workers()); got != 1 {
+		t.Errorf("workers after the cold start = %d, want 1", got)
+	}
+	if st := p.Stats(); st.ScaleUps != 1 {
+		t.Errorf("scale_ups = %d, want 1", st.ScaleUps)
+	}
+
+	// Once idle again, the pool goes back to zero and stays ready.
+	if err := c.DeleteSession(context.Background(), s.ID); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	p.removeIdleWorker(0)
+	if got := len(p.Workers()); got != 0 {
+		t.Errorf("workers after scale-down = %d, want 0", got)
+	}
+	rec = httptest.NewRecorder()
+	handleReadyz(rec, newPoolSet(p))
+	if rec.Code != http.StatusOK {
+		t.Errorf("/readyz after scaling to zero = %d %s, want 200", rec.Code, rec.Body)
+	}
+}
+
+func TestReadyzAndMetricsCoverTaggedPools(t *testing.T) {
+	pools := newPoolSet(newTestPool(t, PoolConfig{Min: 1, Max: 1}))
+	gpu, err := NewPool(PoolConfig{Tag: "gpu", Min: 1, Max: 1, Launch: testLaunch("MOCK_BOOT_DELAY=500ms"),
+		HealthCheckInterval: time.Hour, ScaleInterval: time.Hour}, NewEventLog(defaultEventHistorySize))
+	if err != nil {
+		t.Fatalf("NewPool: %v", err)
+	}
+	t.Cleanup(func() { gpu.Shutdown(context.Background()) })
+	pools.add(gpu)
+
+	// The default pool is up, the gpu pool still booting.
+	rec := httptest.NewRecorder()
+	handleReadyz(rec, pools)
+	var body struct {
+		Ready bool `json:"ready"`
+		Pools []struct {
+			Pool  string `json:"pool"`
+			Ready bool   `json:"ready"`
+		} `json:"pools"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode /readyz: %v", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable || body.Ready {
+		t.Errorf("/readyz while gpu boots = %d %s, want 503 not ready", rec.Code, rec.Body)
+	}
+	if len(body.Pools) != 2 || !body.Pools[0].Ready || body.Pools[1].Pool != "gpu" || body.Pools[1].Ready {
+		t.Errorf("/readyz pools = %+v, want default ready and gpu not", body.Pools)
+	}
+
+	waitFor(t, "gpu pool ready", func() bool { return gpu.available.idleLen() == 1 })
+	rec = httptest.NewRecorder()
+	handleReadyz(rec, pools)
+	if rec.Code != http.StatusOK {
+		t.Errorf("/readyz with both pools up = %d %s, want 200", rec.Code, rec.Body)
+	}
+
+	sessions, err := NewSessionManager(time.Minute, time.Minute, NewEventLog(defaultEventHistorySize), nil)
+	if err != nil {
+		t.Fatalf("NewSessionManager: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	handleMetrics(rec, pools, sessions)
+	for _, want := range []string{
+		`orchestrator_workers{pool="default"} 1`,
+		`orchestrator_workers{pool="gpu"} 1`,
+		`orchestrator_create_latency_ms_count{pool="gpu"} 0`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("/metrics is missing %q", want)
+		}
+	}
+}
+
+func TestReloadResizesTaggedPool(t *testing.T) {
+	pools := newPoolSet(newTestPool(t, PoolConfig{Min: 1, Max: 1}))
+	gpu := newTestPool(t, PoolConfig{Tag: "gpu", Min: 0, Max: 1})
+	pools.add(gpu)
+
+	cur := &Config{WorkerPools: []string{"gpu:0:1"}}
+	if err := reloadWorkerPools(cur, &Config{WorkerPools: []string{"gpu:0:1:/other/binary"}}, pools); err == nil {
+		t.Error("reload with a new binary succeeded, want a restart required")
+	}
+	// Raising min above the old max moves max first.
+	if err := reloadWorkerPools(cur, &Config{WorkerPools: []string{"gpu:2:3"}}, pools); err != nil {
+		t.Fatalf("reloadWorkerPools: %v", err)
+	}
+	if gpu.Min() != 2 || gpu.Max() != 3 {
+		t.Errorf("gpu limits = %d..%d, want 2..3", gpu.Min(), gpu.Max())
+	}
+	waitFor(t, "gpu workers for the new min", func() bool { return gpu.available.idleLen() == 2 })
+
+	sessions, err := NewSessionManager(time.Minute, time.Minute, NewEventLog(defaultEventHistorySize), nil)
+	if err != nil {
+		t.Fatalf("NewSessionManager: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	writeStatusText(rec, pools, sessions)
+	text := strings.Join(strings.Fields(rec.Body.String()), " ")
+	for _, want := range []string{"pool default workers 1 (", "pool gpu workers 2 (2 ready, min 2, max 3,"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text status is missing %q:\n%s", want, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyBucketsMs are the upper bounds (in milliseconds) of the fixed
+// histogram buckets. Observations above the last bound land in an overflow bucket.
+var latencyBucketsMs = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000}
+
+// ewmaAlpha weights the most recent observation in the rolling average.
+const ewmaAlpha = 0.2
+
+// latencyHistogram is a fixed-bucket latency histogram with a rolling
+// average. Quantiles are estimated by interpolating within a bucket, which is
+// accurate enough for pool-sizing decisions and needs no external dependency.
+type latencyHistogram struct {
+	mu     sync.Mutex
+	counts []uint64 // len(latencyBucketsMs)+1; last entry is the overflow bucket
+	total  uint64
+	sumMs  float64
+	ewmaMs float64
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{counts: make([]uint64, len(latencyBucketsMs)+1)}
+}
+
+// Observe records a single duration.
+func (h *latencyHistogram) Observe(d time.Duration) {
+	ms := float64(d) / float64(time.Millisecond)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := 0
+	for i < len(latencyBucketsMs) && ms > latencyBucketsMs[i] {
+		i++
+	}
+	h.counts[i]++
+	h.total++
+	h.sumMs += ms
+	if h.total == 1 {
+		h.ewmaMs = ms
+	} else {
+		h.ewmaMs = ewmaAlpha*ms + (1-ewmaAlpha)*h.ewmaMs
+	}
+}
+
+// latencySnapshot is a point-in-time copy of a histogram.
+type latencySnapshot struct {
+	Counts []uint64
+	Total  uint64
+	SumMs  float64
+	AvgMs  float64 // rolling (exponentially weighted) average
+}
+
+// Snapshot copies the histogram state.
+func (h *latencyHistogram) Snapshot() latencySnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	counts := make([]uint64, len(h.counts))
+	copy(counts, h.counts)
+	return latencySnapshot{Counts: counts, Total: h.total, SumMs: h.sumMs, AvgMs: h.ewmaMs}
+}
+
+// Quantile estimates the q-th quantile (0 < q <= 1) in milliseconds.
+// Returns 0 when nothing has been observed.
+func (s latencySnapshot) Quantile(q float64) float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	rank := q * float64(s.Total)
+	var seen float64
+	for i, c := range s.Counts {
+		if c == 0 {
+			continue
+		}
+		if seen+float64(c) >= rank {
+			lower := 0.0
+			if i > 0 {
+				lower = latencyBucketsMs[i-1]
+			}
+			if i == len(latencyBucketsMs) {
+				// Overflow bucket has no upper bound; report its lower edge.
+				return lower
+			}
+			upper := latencyBucketsMs[i]
+			return lower + (upper-lower)*(rank-seen)/float64(c)
+		}
+		seen += float64(c)
+	}
+	return latencyBucketsMs[len(latencyBucketsMs)-1]
+}
+
+// acquireWaitWindow is how far back the Acquire wait-time window reaches.
+const acquireWaitWindow = 5 * time.Minute
+
+// maxWindowSamples bounds memory if the window sees extreme traffic; the
+// oldest samples are dropped first.
+const maxWindowSamples = 10000
+
+type timedSample struct {
+	at time.Time
+	d  time.Duration
+}
+
+// slidingWindow keeps duration samples from the recent past so percentiles
+// reflect current conditions rather than the whole process lifetime.
+type slidingWindow struct {
+	mu      sync.Mutex
+	window  time.Duration
+	samples []timedSample // oldest first
+}
+
+func newSlidingWindow(window time.Duration) *slidingWindow {
+	return &slidingWindow{window: window}
+}
+
+// Observe records a sample taken now.
+func (sw *slidingWindow) Observe(d time.Duration) {
+	now := time.Now()
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.pruneLocked(now)
+	if len(sw.samples) >= maxWindowSamples {
+		sw.samples = sw.samples[1:]
+	}
+	sw.samples = append(sw.samples, timedSample{at: now, d: d})
+}
+
+func (sw *slidingWindow) pruneLocked(now time.Time) {
+	cutoff := now.Add(-sw.window)
+	i := 0
+	for i < len(sw.samples) && sw.samples[i].at.Before(cutoff) {
+		i++
+	}
+	if i > 0 {
+		sw.samples = append(sw.samples[:0], sw.samples[i:]...)
+	}
+}
+
+// windowPercentiles summarizes the samples currently in a sliding window.
+type windowPercentiles struct {
+	Count int
+	P50Ms float64
+	P95Ms float64
+	P99Ms float64
+}
+
+// Percentiles returns nearest-rank percentiles over the current window.
+func (sw *slidingWindow) Percentiles() windowPercentiles {
+	sw.mu.Lock()
+	sw.pruneLocked(time.Now())
+	ds := make([]time.Duration, len(sw.samples))
+	for i, s := range sw.samples {
+		ds[i] = s.d
+	}
+	sw.mu.Unlock()
+
+	if len(ds) == 0 {
+		return windowPercentiles{}
+	}
+	sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+	at := func(q float64) float64 {
+		return float64(nearestRank(ds, q)) / float64(time.Millisecond)
+	}
+	return windowPercentiles{Count: len(ds), P50Ms: at(0.50), P95Ms: at(0.95), P99Ms: at(0.99)}
+}
+
+// nearestRank returns the q-th quantile (0 < q <= 1) of sorted, non-empty ds.
+func nearestRank(ds []time.Duration, q float64) time.Duration {
+	idx := int(q*float64(len(ds))+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(ds) {
+		idx = len(ds) - 1
+	}
+	return ds[idx]
+}
+
+// handleMetrics serves GET /metrics in the Prometheus text exposition format.
+// Pool metrics carry a pool label with the pool's tag ("default" for the
+// default pool); session and proxy metrics are orchestrator-wide.
+func handleMetrics(w http.ResponseWriter, pools *PoolSet, sessions *SessionManager) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	all := pools.All()
+	writePoolGauge(w, "orchestrator_workers", "Current number of worker processes.", all, func(p *Pool) float64 { return float64(len(p.Workers())) })
+	writePoolGauge(w, "orchestrator_workers_available", "Workers idle in the available queue.", all, func(p *Pool) float64 { return float64(p.QueueDepth()) })
+	writeGauge(w, "orchestrator_sessions_active", "Sessions currently mapped to a worker.", float64(sessions.Count()))
+	writeGauge(w, "orchestrator_sessions_max", "The max-sessions cap; 0 = unlimited.", float64(sessions.MaxSessions()))
+	writeCounter(w, "orchestrator_session_limit_rejections_total", "Create requests turned away because live sessions were at max-sessions.", float64(sessions.LimitRejections()))
+
+	writePoolGauge(w, "orchestrator_workers_cpu_percent", "CPU usage summed over all workers at the last health sweep (100 = one core).", all, func(p *Pool) float64 {
+		var total float64
+		for _, wr := range p.Workers() {
+			total += wr.CPUPercent()
+		}
+		return total
+	})
+	writePoolGauge(w, "orchestrator_worker_cpu_percent_max", "CPU usage of the busiest worker at the last health sweep (100 = one core).", all, func(p *Pool) float64 {
+		var busiest float64
+		for _, wr := range p.Workers() {
+			busiest = max(busiest, wr.CPUPercent())
+		}
+		return busiest
+	})
+
+	creates := make(map[*Pool]latencySnapshot, len(all))
+	for _, p := range all {
+		creates[p] = p.CreateLatency()
+	}
+	writePoolHistogram(w, "orchestrator_create_latency_ms", "Worker round-trip time of successful session creates.", all, creates)
+	writePoolGauge(w, "orchestrator_create_latency_p50_ms", "Estimated median create latency.", all, func(p *Pool) float64 { return creates[p].Quantile(0.50) })
+	writePoolGauge(w, "orchestrator_create_latency_p95_ms", "Estimated 95th percentile create latency.", all, func(p *Pool) float64 { return creates[p].Quantile(0.95) })
+	writePoolGauge(w, "orchestrator_create_latency_avg_ms", "Rolling average create latency.", all, func(p *Pool) float64 { return creates[p].AvgMs })
+
+	waits := make(map[*Pool]windowPercentiles, len(all))
+	for _, p := range all {
+		waits[p] = p.AcquireWait()
+	}
+	writePoolGauge(w, "orchestrator_acquire_wait_p50_ms", "Median Acquire wait over the last 5 minutes.", all, func(p *Pool) float64 { return waits[p].P50Ms })
+	writePoolGauge(w, "orchestrator_acquire_wait_p95_ms", "95th percentile Acquire wait over the last 5 minutes.", all, func(p *Pool) float64 { return waits[p].P95Ms })
+	writePoolGauge(w, "orchestrator_acquire_wait_p99_ms", "99th percentile Acquire wait over the last 5 minutes.", all, func(p *Pool) float64 { return waits[p].P99Ms })
+	writeGauge(w, "orchestrator_inflight_requests", "Proxied session requests currently in flight.", float64(proxyLimit.InFlight()))
+	writePoolGauge(w, "orchestrator_acquire_queue_depth", "Create requests currently waiting for a worker.", all, func(p *Pool) float64 { return float64(p.Waiting()) })
+	writePoolCounter(w, "orchestrator_acquire_queue_rejections_total", "Create requests turned away because the waiter queue was at max-queue-depth.", all, func(p *Pool) float64 { return float64(p.QueueRejections()) })
+	writePoolCounter(w, "orchestrator_acquire_timeouts_total", "Acquire calls that gave up before a worker became available.", all, func(p *Pool) float64 { return float64(p.AcquireTimeouts()) })
+	writePoolGauge(w, "orchestrator_pool_degraded", "1 while every worker is failing and creates fail fast, else 0.", all, func(p *Pool) float64 {
+		if p.Degraded().Degraded {
+			return 1
+		}
+		return 0
+	})
+	writePoolCounter(w, "orchestrator_worker_readiness_failures_total", "Worker processes killed for not becoming ready within the ready timeout.", all, func(p *Pool) float64 { return float64(p.ReadinessFailures()) })
+	writePoolGauge(w, "orchestrator_workers_quarantined", "Workers out of the available queue after failing health checks, awaiting re-admission or a kill.", all, func(p *Pool) float64 { return float64(p.Quarantined()) })
+	stats := make(map[*Pool]PoolStatsSnapshot, len(all))
+	for _, p := range all {
+		stats[p] = p.Stats()
+	}
+	writePoolCounter(w, "orchestrator_worker_quarantines_total", "Workers quarantined after a failed health probe.", all, func(p *Pool) float64 { return float64(stats[p].Quarantines) })
+	writePoolCounter(w, "orchestrator_worker_readmissions_total", "Quarantined workers that recovered and rejoined the available queue.", all, func(p *Pool) float64 { return float64(stats[p].Readmissions) })
+	writePoolCounter(w, "orchestrator_worker_deep_check_failures_total", "Deep checks (--deep-check-every) failed by workers that had just passed their health probe.", all, func(p *Pool) float64 { return float64(stats[p].DeepCheckFailures) })
+	writePoolCounter(w, "orchestrator_worker_smoke_test_failures_total", "Worker processes killed for passing their health probe but failing --ready-smoke-test.", all, func(p *Pool) float64 { return float64(stats[p].SmokeTestFailures) })
+}
+
+func writeCounter(w io.Writer, name, help string, v float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %g\n", name, help, name, name, v)
+}
+
+func writeGauge(w io.Writer, name, help string, v float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", name, help, name, name, v)
+}
+
+func writePoolCounter(w io.Writer, name, help string, pools []*Pool, v func(*Pool) float64) {
+	writePoolSamples(w, "counter", name, help, pools, v)
+}
+
+func writePoolGauge(w io.Writer, name, help string, pools []*Pool, v func(*Pool) float64) {
+	writePoolSamples(w, "gauge", name, help, pools, v)
+}
+
+// writePoolSamples writes one sample of the metric per pool, labelled with
+// the pool's tag.
+func writePoolSamples(w io.Writer, typ, name, help string, pools []*Pool, v func(*Pool) float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	for _, p := range pools {
+		fmt.Fprintf(w, "%s{pool=%q} %g\n", name, p.Tag(), v(p))
+	}
+}
+
+func writePoolHistogram(w io.Writer, name, help string, pools []*Pool, snapshots map[*Pool]latencySnapshot) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	for _, p := range pools {
+		s, label := snapshots[p], fmt.Sprintf("pool=%q", p.Tag())
+		var cumulative uint64
+		for i, bound := range latencyBucketsMs {
+			cumulative += s.Counts[i]
+			fmt.Fprintf(w, "%s_bucket{%s,le=\"%g\"} %d\n", name, label, bound, cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, label, s.Total)
+		fmt.Fprintf(w, "%s_sum{%s} %g\n%s_count{%s} %d\n", name, label, s.SumMs, name, label, s.Total)
+	}
+}
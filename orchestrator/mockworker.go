@@ -0,0 +1,221 @@
+package main
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mockWorkerCommand is the first argument that runs the orchestrator binary
+// as a stand-in steel-browser worker instead of an orchestrator:
+//
+//	steel-orchestrator -binary=./steel-orchestrator -worker-arg=mockworker
+const mockWorkerCommand = "mockworker"
+
+// mockWorkerConfig controls the mock worker's artificial misbehaviour. Every
+// field is read from a MOCK_* environment variable (passed via -worker-env).
+type mockWorkerConfig struct {
+	BootDelay  time.Duration // MOCK_BOOT_DELAY: wait before listening, like a browser boot
+	Latency    time.Duration // MOCK_LATENCY: added to every /sessions call
+	FailRate   float64       // MOCK_FAIL_RATE: fraction of /sessions calls answered with 500
+	CrashRate  float64       // MOCK_CRASH_RATE: fraction of /sessions calls that exit the process
+	HangRate   float64       // MOCK_HANG_RATE: fraction of /sessions calls that never respond
+	RejectRate float64       // MOCK_REJECT_RATE: fraction of creates answered with 422, as for a bad request
+	Sessions   int           // MOCK_MAX_SESSIONS: sessions held at once, default 1 (pair with --sessions-per-worker)
+}
+
+func mockWorkerConfigFromEnv() (mockWorkerConfig, error) {
+	c := mockWorkerConfig{Sessions: 1}
+	var err error
+	durations := []struct {
+		name string
+		dst  *time.Duration
+	}{{"MOCK_BOOT_DELAY", &c.BootDelay}, {"MOCK_LATENCY", &c.Latency}}
+	for _, d := range durations {
+		if v := os.Getenv(d.name); v != "" {
+			if *d.dst, err = time.ParseDuration(v); err != nil {
+				return c, fmt.Errorf("%s: %w", d.name, err)
+			}
+		}
+	}
+	rates := []struct {
+		name string
+		dst  *float64
+	}{{"MOCK_FAIL_RATE", &c.FailRate}, {"MOCK_CRASH_RATE", &c.CrashRate}, {"MOCK_HANG_RATE", &c.HangRate}, {"MOCK_REJECT_RATE", &c.RejectRate}}
+	for _, r := range rates {
+		if v := os.Getenv(r.name); v != "" {
+			if *r.dst, err = strconv.ParseFloat(v, 64); err != nil || *r.dst < 0 || *r.dst > 1 {
+				return c, fmt.Errorf("%s: want a fraction between 0 and 1, got %q", r.name, v)
+			}
+		}
+	}
+	if v := os.Getenv("MOCK_MAX_SESSIONS"); v != "" {
+		if c.Sessions, err = strconv.Atoi(v); err != nil || c.Sessions < 1 {
+			return c, fmt.Errorf("MOCK_MAX_SESSIONS: want a positive integer, got %q", v)
+		}
+	}
+	return c, nil
+}
+
+// mockSession mirrors the steel-browser session payload.
+type mockSession struct {
+	ID        string          `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// mockWorker implements the steel-browser API: one session at a time (or
+// MOCK_MAX_SESSIONS), and a create past that replaces the oldest session.
+type mockWorker struct {
+	cfg      mockWorkerConfig
+	mu       sync.Mutex
+	sessions []*mockSession // oldest first
+}
+
+// find returns the index of session id, or -1. m.mu must be held.
+func (m *mockWorker) find(id string) int {
+	return slices.IndexFunc(m.sessions, func(s *mockSession) bool { return s.ID == id })
+}
+
+// runMockWorker serves the steel-browser API on $PORT until the process is
+// killed. It never returns on success.
+func runMockWorker() error {
+	cfg, err := mockWorkerConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	port := os.Getenv("PORT")
+	if port == "" {
+		return fmt.Errorf("PORT is not set")
+	}
+	time.Sleep(cfg.BootDelay)
+
+	m := &mockWorker{cfg: cfg}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	})
+	mux.HandleFunc("/status", m.handleStatus)
+	mux.HandleFunc("/sessions", m.handleSessions)
+	mux.HandleFunc("/sessions/", m.handleSessions)
+
+	logger("mockworker").Info("listening", "port", port, "latency", cfg.Latency.String(),
+		"fail_rate", cfg.FailRate, "crash_rate", cfg.CrashRate, "hang_rate", cfg.HangRate, "reject_rate", cfg.RejectRate)
+	return http.ListenAndServe("127.0.0.1:"+port, mux)
+}
+
+func (m *mockWorker) handleStatus(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	var id *string
+	ids := make([]string, len(m.sessions))
+	for i, s := range m.sessions {
+		ids[i] = s.ID
+	}
+	if len(ids) > 0 {
+		id = &ids[0]
+	}
+	available := len(m.sessions) < m.cfg.Sessions
+	m.mu.Unlock()
+	writeMockJSON(w, http.StatusOK, map[string]any{"available": available, "session_id": id, "sessions": ids})
+}
+
+func (m *mockWorker) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if !m.misbehave(w) {
+		return
+	}
+
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/sessions"), "/")
+	switch {
+	case id == "" && r.Method == http.MethodPost:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "failed to read body"})
+			return
+		}
+		if len(data) == 0 {
+			data = []byte("{}")
+		}
+		if !json.Valid(data) {
+			writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+			return
+		}
+		if rand.Float64() < m.cfg.RejectRate {
+			writeMockJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": "injected rejection"})
+			return
+		}
+		s := &mockSession{ID: newMockSessionID(), CreatedAt: time.Now().UTC(), Data: data}
+		m.mu.Lock()
+		if len(m.sessions) >= m.cfg.Sessions {
+			m.sessions = m.sessions[len(m.sessions)-m.cfg.Sessions+1:]
+		}
+		m.sessions = append(m.sessions, s)
+		m.mu.Unlock()
+		writeMockJSON(w, http.StatusOK, s)
+	case id != "" && r.Method == http.MethodGet:
+		m.mu.Lock()
+		var s *mockSession
+		if i := m.find(id); i >= 0 {
+			s = m.sessions[i]
+		}
+		m.mu.Unlock()
+		if s == nil {
+			writeMockJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+			return
+		}
+		writeMockJSON(w, http.StatusOK, s)
+	case id != "" && r.Method == http.MethodDelete:
+		m.mu.Lock()
+		i := m.find(id)
+		found := i >= 0
+		if found {
+			m.sessions = slices.Delete(m.sessions, i, i+1)
+		}
+		m.mu.Unlock()
+		if !found {
+			writeMockJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// misbehave applies the configured latency and failure modes. It returns
+// false when the request has already been dealt with.
+func (m *mockWorker) misbehave(w http.ResponseWriter) bool {
+	time.Sleep(m.cfg.Latency)
+	switch roll := rand.Float64(); {
+	case roll < m.cfg.CrashRate:
+		logger("mockworker").Warn("crashing on purpose")
+		os.Exit(1)
+	case roll < m.cfg.CrashRate+m.cfg.HangRate:
+		select {} // never respond; the orchestrator's timeout has to fire
+	case roll < m.cfg.CrashRate+m.cfg.HangRate+m.cfg.FailRate:
+		writeMockJSON(w, http.StatusInternalServerError, map[string]string{"error": "injected failure"})
+		return false
+	}
+	return true
+}
+
+func writeMockJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func newMockSessionID() string {
+	var b [16]byte
+	_, _ = crand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -2,13 +2,122 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
-	"net"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"steel-orchestrator/clock"
+)
+
+// maxPoolCapacity is the hard ceiling for max-workers.
+const maxPoolCapacity = 1024
+
+// Jitter applied to the background loops so large pools, and several
+// orchestrators on one host, do not probe or scale in lockstep.
+const (
+	loopJitter    = 0.1 // each sweep waits interval ± 10%
+	healthStagger = 0.5 // a sweep's probes are spread over the first half of the interval
 )
 
+// PoolConfig holds the settings a Pool is created with.
+type PoolConfig struct {
+	Tag                 string // names a --worker-pool; "" is the default pool
+	Min                 int
+	Max                 int
+	Launch              LaunchConfig
+	HealthCheckInterval time.Duration
+	HealthGrace         time.Duration // freshly ready workers skip health checks this long
+	HealthFailures      int           // failed probes in a row before a kill; a refused connection kills at once
+	MaxWorkerAge        time.Duration // restart workers older than this, one at a time; 0 = never
+	MemoryLimit         int64         // restart workers whose RSS exceeds this many bytes; 0 = never
+	CrashLoopLimit      int           // replace a worker after this many failures in a row; 0 = never
+	RestartDelay        time.Duration // first restart delay, doubled per consecutive failure; 0 = restartBaseDelay
+	MaxRestartsPerMin   int           // replace a worker restarting faster than this; 0 = never
+
+	// QuarantineTimeout enables quarantine: a worker failing its health
+	// probe is taken off the available queue and re-probed every
+	// QuarantineInterval, re-admitted after QuarantineSuccesses healthy
+	// probes in a row, or killed once quarantined this long. 0 kills at
+	// HealthFailures as before.
+	QuarantineTimeout   time.Duration
+	QuarantineInterval  time.Duration
+	QuarantineSuccesses int
+
+	// DeepCheckEvery runs a deep check (see Worker.deepCheck) after the
+	// health probe on every DeepCheckEvery-th sweep, GETting DeepCheckPath
+	// or, if it is empty, each of the worker's sessions. 0 = never.
+	DeepCheckEvery int
+	DeepCheckPath  string
+
+	// PortMin and PortMax restrict worker ports to that range (--port-range);
+	// both 0 lets the OS pick. Ports, if set, is used instead so that several
+	// pools draw from one allocator and never hand out the same port.
+	PortMin, PortMax int
+	Ports            *portAllocator
+
+	// MemoryBudget caps the pool's total estimated memory: scale-up stops at
+	// MemoryBudget / WorkerMemoryEstimate workers even below Max. 0 = no budget.
+	MemoryBudget         int64
+	WorkerMemoryEstimate int64
+	Audit                *AuditLog   // optional worker lifecycle audit trail
+	Clock                clock.Clock // drives the scale and health loops and restart delays; nil means the wall clock
+
+	// StartQuorum is how many of the Min initial workers must spawn for
+	// NewPool to succeed. 0 means all of them.
+	StartQuorum int
+
+	// ScaleWindows override Min and Max during their daily time ranges,
+	// from ScaleWindowLead before each opens (--scale-window).
+	ScaleWindows    []ScaleWindow
+	ScaleWindowLead time.Duration
+
+	// WaitReady, if set, makes NewPool wait up to this long for Min workers
+	// to pass their readiness check, and fail if they do not.
+	WaitReady time.Duration
+
+	// WarmBuffer is how many idle workers the pool tries to keep ready ahead
+	// of demand while it is below Max. 0 disables proactive scale-up.
+	WarmBuffer int
+
+	// Policy drives scaleLoop; nil means the idle policy with a 20 s window.
+	Policy ScalePolicy
+
+	// ScaleInterval is the nominal gap between scaleLoop ticks (default 10 s).
+	// ScaleUpCooldown suppresses scale-down for that long after the most
+	// recent scale-up, so the workers a burst needed survive until the next one.
+	ScaleInterval   time.Duration
+	ScaleUpCooldown time.Duration
+
+	// ScaleDownFraction lets one scale-down tick remove up to this share of
+	// the idle surplus instead of a single worker. 0 = one per tick.
+	ScaleDownFraction float64
+
+	// MaxBoots caps how many scale-up workers may be booting at once, so a
+	// burst does not fork-bomb the host: a batch reserves all its slots up
+	// front and the rest of it starts as these become ready. 0 = unlimited.
+	MaxBoots int
+
+	// MaxQueueDepth caps how many Acquire callers may wait for a worker; one
+	// more gets ErrQueueFull at once. 0 = unbounded.
+	MaxQueueDepth int
+
+	// StopTimeout is how long Shutdown gives each worker to exit after
+	// SIGTERM before it is SIGKILLed. 0 = SIGKILL at once.
+	StopTimeout time.Duration
+
+	// SessionsPerWorker is how many sessions each worker holds at once
+	// (--sessions-per-worker). 0 or 1 = one session per worker.
+	SessionsPerWorker int
+}
+
 // Pool manages a set of workers with request queuing.
 // When all workers are busy, callers block until one becomes available.
 // The pool auto-scales between min and max workers based on demand.
@@ -16,44 +125,233 @@ type Pool struct {
 	mu      sync.RWMutex
 	workers []*Worker
 
-	// available is a buffered channel used as a semaphore.
-	// Workers are pushed onto it when free, and popped off when claimed.
-	available chan *Worker
+	// available holds idle workers and the callers waiting for them, and
+	// serves those callers in arrival order.
+	available *workerQueue
+
+	tag               string // PoolConfig.Tag
+	min               int    // in force: baseMin, or a scale window's; guarded by mu
+	max               int    // in force: baseMax, or a scale window's
+	baseMin, baseMax  int    // PoolConfig.Min and Max, following SetMin and SetMax
+	windows           []ScaleWindow
+	windowLead        time.Duration
+	limits            ScaleLimits   // the latest applied; guarded by mu
+	nextID            int           // monotonic counter, never reused
+	pendingAdds       int           // workers currently starting up but not yet in the slice
+	warmBuffer        int           // free session slots to keep ready ahead of demand
+	sessionsPerWorker int           // session slots per worker; at least 1
+	startSem          chan struct{} // one token per scale-up worker starting or booting (MaxBoots); nil = unlimited
+	waiting           atomic.Int64  // callers currently inside Acquire
+	acquired          atomic.Int64  // successful Acquires since the last scaleLoop tick
+	policy            ScalePolicy   // scaleLoop's add/remove decision
+	closing           bool          // set by Shutdown: no more workers are started (guarded by mu)
+
+	// binMu guards rollout. It is a leaf lock, taken under a worker's lock
+	// by Start, so nothing else is locked while it is held.
+	binMu   sync.Mutex
+	rollout binaryRollout // which binary new and restarted workers get
 
-	min         int
-	max         int
-	nextID      int    // monotonic counter, never reused
-	pendingAdds int    // workers currently starting up but not yet in the slice
-	binaryPath  string // path to the steel-browser binary
+	rollMu  sync.Mutex
+	rolling *rollingRestart // the most recent rolling restart, nil if none (guarded by rollMu)
+
+	scaleInterval   time.Duration
+	scaleUpCooldown time.Duration
+	scaleDownFrac   float64
+	lastScaleUp     time.Time    // when replenish or the policy last started workers (guarded by mu)
+	launch          LaunchConfig // how to start each steel-browser process
+	ports           *portAllocator
+	events          *EventLog
+	audit           *AuditLog
+	clk             clock.Clock
+
+	createLatency   *latencyHistogram // successful worker create round-trips
+	acquireWait     *slidingWindow    // time callers spent blocked in Acquire
+	recentWait      *slidingWindow    // the same, over scaleWaitWindow, for the scale policy
+	acquireTimeouts atomic.Int64
+	queueRejects    atomic.Int64 // Acquire calls refused with ErrQueueFull
+	degradedRejects atomic.Int64 // Acquire calls failed with ErrPoolDegraded
+	counters        poolCounters // lifetime totals for Stats
+	readyFailures   atomic.Int64 // worker processes killed for missing readyTimeout
+	smokeFailures   atomic.Int64 // worker processes killed for failing the smoke test
+	quarantines     atomic.Int64 // workers quarantined after a failed health probe
+	readmissions    atomic.Int64 // quarantined workers re-admitted after recovering
+	deepFailures    atomic.Int64 // deep checks failed by workers that passed their health probe
+
+	healthInterval atomic.Int64 // time.Duration; changeable at runtime
+	healthGrace    time.Duration
+	healthFailures int                               // HealthFailures, at least 1
+	quarantine     quarantineSettings                // QuarantineTimeout etc.; zero timeout = disabled
+	deep           deepCheckSettings                 // DeepCheckEvery and DeepCheckPath
+	sweeps         int                               // health sweeps so far; owned by healthCheckLoop
+	maxAge         time.Duration                     // MaxWorkerAge; 0 disables rotation
+	memoryLimit    int64                             // MemoryLimit in bytes; 0 disables enforcement
+	crashLoopLimit int                               // CrashLoopLimit; 0 restarts forever
+	restartDelay   time.Duration                     // RestartDelay, or restartBaseDelay
+	restartRate    int                               // MaxRestartsPerMin; 0 = unlimited
+	stopTimeout    time.Duration                     // StopTimeout; grace between SIGTERM and SIGKILL on shutdown
+	budgetWorkers  int                               // workers the memory budget allows; 0 = no budget
+	budgetCapped   bool                              // the last reservation was cut short by the budget (guarded by mu)
+	failed         []FailedWorker                    // most recent workers given up on, oldest first (guarded by mu)
+	degraded       degradedState                     // whether every worker is failing (guarded by mu)
+	condition      string                            // last condition seen by Condition (guarded by mu)
+	swept          atomic.Pointer[ConditionSnapshot] // condition as of the latest health sweep
+
+	// readyCh is closed once as many workers as NewPool spawned have passed
+	// waitForReady, so a replacement for a crash-looping initial worker
+	// counts in its place.
+	readyCh       chan struct{}
+	readyOnce     sync.Once
+	initialReady  map[int]bool // IDs of workers that have become ready (guarded by mu)
+	initialTarget int          // initial workers that spawned; -1 until NewPool knows (guarded by mu)
+
+	// OnHealthSweep, if set, is called after every health-check sweep.
+	// Used to drive the systemd watchdog from the health loop.
+	OnHealthSweep func()
 
 	// CrashHandler is called when a worker crashes with an active session.
 	// Set this after pool creation to wire up session manager cleanup.
 	// It is also applied automatically to any worker added during scale-up.
-	CrashHandler func(sessionID string)
+	CrashHandler func(sessionIDs []string, kind exitKind)
 }
 
-// NewPool creates a pool of min workers. Each worker is assigned a port by
-// the OS, so no port range configuration is needed.
-func NewPool(min, max int, binaryPath string) (*Pool, error) {
+// NewPool creates a pool of min workers. Each worker's port comes from the
+// pool's portAllocator: from PortMin-PortMax (--port-range) when set, else
+// from the OS. Either way a port stays reserved while its worker holds it,
+// so two workers never get the same one. Pools that share a range share
+// cfg.Ports.
+func NewPool(cfg PoolConfig, events *EventLog) (*Pool, error) {
+	limits := scaleLimitsAt(cfg.ScaleWindows, cfg.ScaleWindowLead, clock.Or(cfg.Clock).Now(), cfg.Min, cfg.Max)
+	min, max, launch := limits.Min, limits.Max, cfg.Launch
 	p := &Pool{
 		workers:    make([]*Worker, 0, max),
-		available:  make(chan *Worker, max),
+		available:  newWorkerQueue(cfg.MaxQueueDepth),
 		min:        min,
 		max:        max,
+		baseMin:    cfg.Min,
+		baseMax:    cfg.Max,
+		windows:    cfg.ScaleWindows,
+		windowLead: cfg.ScaleWindowLead,
+		limits:     limits,
 		nextID:     min,
-		binaryPath: binaryPath,
+		launch:     launch,
+		tag:        cfg.Tag,
+		ports:      cfg.Ports,
+		warmBuffer: cfg.WarmBuffer,
+		policy:     cfg.Policy,
+		events:     events,
+		audit:      cfg.Audit,
+		clk:        clock.Or(cfg.Clock),
+
+		createLatency: newLatencyHistogram(),
+		acquireWait:   newSlidingWindow(acquireWaitWindow),
+		recentWait:    newSlidingWindow(scaleWaitWindow),
+
+		readyCh:       make(chan struct{}),
+		initialReady:  make(map[int]bool),
+		initialTarget: -1,
+		condition:     conditionOK,
+	}
+	if p.ports == nil {
+		p.ports = newPortAllocator(cfg.PortMin, cfg.PortMax)
+	}
+	p.degraded.reset()
+	if cfg.MaxBoots > 0 {
+		p.startSem = make(chan struct{}, cfg.MaxBoots)
+	}
+	p.rollout.binary = launch.BinaryPath
+	p.healthInterval.Store(int64(cfg.HealthCheckInterval))
+	p.healthGrace = cfg.HealthGrace
+	p.healthFailures = cfg.HealthFailures
+	if p.healthFailures < 1 {
+		p.healthFailures = 1
+	}
+	p.quarantine = quarantineSettings{
+		timeout:   cfg.QuarantineTimeout,
+		interval:  cfg.QuarantineInterval,
+		successes: cfg.QuarantineSuccesses,
+	}
+	if p.quarantine.successes < 1 {
+		p.quarantine.successes = 1
+	}
+	p.deep = deepCheckSettings{every: cfg.DeepCheckEvery, path: cfg.DeepCheckPath}
+	p.maxAge = cfg.MaxWorkerAge
+	p.memoryLimit = cfg.MemoryLimit
+	p.crashLoopLimit = cfg.CrashLoopLimit
+	p.restartDelay = cfg.RestartDelay
+	if p.restartDelay <= 0 {
+		p.restartDelay = restartBaseDelay
+	}
+	p.restartRate = cfg.MaxRestartsPerMin
+	p.stopTimeout = cfg.StopTimeout
+	p.sessionsPerWorker = 1
+	if cfg.SessionsPerWorker > 1 {
+		p.sessionsPerWorker = cfg.SessionsPerWorker
+	}
+	if cfg.MemoryBudget > 0 && cfg.WorkerMemoryEstimate > 0 {
+		p.budgetWorkers = int(cfg.MemoryBudget / cfg.WorkerMemoryEstimate)
+	}
+	if p.policy == nil {
+		p.policy = &idlePolicy{after: 2 * defaultScaleInterval}
+	}
+	p.scaleInterval = cfg.ScaleInterval
+	if p.scaleInterval <= 0 {
+		p.scaleInterval = defaultScaleInterval
+	}
+	p.scaleUpCooldown = cfg.ScaleUpCooldown
+	p.scaleDownFrac = cfg.ScaleDownFraction
+
+	quorum := cfg.StartQuorum
+	if quorum <= 0 || quorum > min {
+		quorum = min
 	}
 
+	// Spawn the initial workers concurrently. Worker i always gets ID i, and
+	// the slice is sorted by ID afterwards, so both stay deterministic
+	// regardless of which spawn finishes first.
+	errs := make([]error, min)
+	var wg sync.WaitGroup
 	for i := 0; i < min; i++ {
-		port, err := findFreePort()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get free port for worker %d: %w", i, err)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			port, err := p.ports.acquire()
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to get free port for worker %d: %w", i, err)
+				return
+			}
+			w := NewWorker(i, port, launch, p)
+			// Join before starting, as in startReserved.
+			p.mu.Lock()
+			p.workers = append(p.workers, w)
+			p.mu.Unlock()
+			if err := w.Start(); err != nil {
+				errs[i] = fmt.Errorf("failed to start worker %d: %w", i, err)
+				p.forget(w)
+			}
+		}()
+	}
+	wg.Wait()
+	p.mu.Lock()
+	slices.SortFunc(p.workers, func(a, b *Worker) int { return a.ID - b.ID })
+	p.mu.Unlock()
+	if failed := errors.Join(errs...); failed != nil {
+		if len(p.workers) < quorum {
+			for _, w := range p.workers {
+				w.Stop("startup quorum not met")
+			}
+			return nil, fmt.Errorf("only %d of %d initial workers started (quorum %d): %w", len(p.workers), min, quorum, failed)
 		}
-		w := NewWorker(i, port, binaryPath, p)
-		if err := w.Start(); err != nil {
-			return nil, fmt.Errorf("failed to start worker %d: %w", i, err)
+		p.logger().Warn("some initial workers failed to start — continuing with quorum", "started", len(p.workers), "min_workers", min, "quorum", quorum, "error", failed)
+	}
+	p.setInitialTarget(len(p.workers))
+
+	if cfg.WaitReady > 0 {
+		if err := p.waitReady(min, cfg.WaitReady); err != nil {
+			for _, w := range p.Workers() {
+				w.Stop("startup readiness not met")
+			}
+			return nil, err
 		}
-		p.workers = append(p.workers, w)
 	}
 
 	// Start background health checker and auto-scaler
@@ -63,42 +361,327 @@ func NewPool(min, max int, binaryPath string) (*Pool, error) {
 	return p, nil
 }
 
+// waitReady blocks until n workers are ready or timeout passes, and then
+// fails naming each worker that is not ready, with its state and last error.
+// Workers that crash meanwhile are restarted as usual, so a slow first boot
+// still counts if it comes up in time.
+func (p *Pool) waitReady(n int, timeout time.Duration) error {
+	p.logger().Info("waiting for initial workers to become ready", "min_workers", n, "timeout", timeout.String())
+	deadline := p.clk.Now().Add(timeout)
+	for p.ReadyWorkers() < n {
+		if !p.clk.Now().Before(deadline) {
+			var notReady []string
+			for _, w := range p.Workers() {
+				if s := w.State(); s == WorkerStateAvailable || s == WorkerStateBusy {
+					continue
+				}
+				desc := fmt.Sprintf("worker %d (port %d) %s", w.ID, w.Port, w.State())
+				if _, lastErr := w.Failures(); lastErr != "" {
+					desc += ": " + lastErr
+				}
+				notReady = append(notReady, desc)
+			}
+			return fmt.Errorf("only %d of %d workers ready after %s; not ready: %s",
+				p.ReadyWorkers(), n, timeout, strings.Join(notReady, "; "))
+		}
+		p.clk.Sleep(readyPollInterval)
+	}
+	return nil
+}
+
+// ReadyWorkers counts the workers that have passed their readiness check and
+// are running, i.e. are Available or Busy.
+func (p *Pool) ReadyWorkers() int {
+	return p.readyOthers(nil)
+}
+
 // Release returns a worker to the available pool.
 // Called after a session is deleted, expired, or the worker is restarted.
+// A worker is queued at most once, never once it is draining, and only while
+// it is still one of the pool's workers, so neither a delete racing a
+// scale-down nor a late restart can put a removed worker back in the queue.
+// If callers are blocked in Acquire, the longest-waiting one gets it. A
+// worker marked surplus by SetMax is removed instead, and one marked to
+// retire is restarted, unless another worker is still coming back up, in
+// which case the next health sweep rotates it. Either waits for the worker's
+// last session to end and takes no new ones meanwhile.
 func (p *Pool) Release(w *Worker) {
-	// Non-blocking send — if channel is full, worker is already "available"
-	select {
-	case p.available <- w:
-		log.Printf("[pool] :%-5d returned to pool (available: %d)", w.Port, len(p.available))
-	default:
-		log.Printf("[pool] :%-5d release skipped — already in pool", w.Port)
+	if !p.isMember(w) {
+		poolLogger(w).Debug("release skipped — worker is no longer in the pool")
+		return
+	}
+	if w.Load() > 0 && w.windingDown() {
+		poolLogger(w).Debug("release skipped — winding down until its sessions end")
+		return
+	}
+	if w.takeSurplus() {
+		p.removeWorker(w, "above max-workers")
+		return
+	}
+	if w.isRetiring() && !w.isDraining() && settled(p.Workers()) {
+		p.rotate(w, w.retiringReason())
+		return
+	}
+	if !w.markQueued() {
+		poolLogger(w).Debug("release skipped — already in pool or draining")
+		return
+	}
+	p.available.put(w)
+	p.counters.releases.Add(1)
+	if debugEnabled() {
+		poolLogger(w).Debug("returned to pool", "available", p.available.idleLen())
+	}
+}
+
+// unqueueDead takes a worker whose process just exited out of the available
+// queue, so the queue depth is right at once and no Acquire pops it in the
+// meantime. Acquire would skip it anyway; this only saves the round trip.
+// It is queued again when its restart passes waitForReady.
+func (p *Pool) unqueueDead(w *Worker) {
+	if p.available.remove(w) {
+		w.clearQueued()
+		poolLogger(w).Debug("removed exited worker from the queue")
+	}
+}
+
+// WorkerByID returns the pool's worker with the given ID.
+func (p *Pool) WorkerByID(id int) (*Worker, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, w := range p.workers {
+		if w.ID == id {
+			return w, true
+		}
 	}
+	return nil, false
+}
+
+// Cordon stops w from taking new sessions without touching the ones it has:
+// it is taken off the available queue and Release will not queue it again,
+// even after a restart, until Uncordon. It reports whether w was not
+// already cordoned.
+func (p *Pool) Cordon(w *Worker) bool {
+	if !w.setCordoned(true) {
+		return false
+	}
+	if p.available.remove(w) {
+		w.clearQueued()
+	}
+	poolLogger(w).Info("worker cordoned", "sessions", len(w.Sessions()))
+	return true
+}
+
+// Uncordon lets w take new sessions again, queueing it at once if it has a
+// free slot. It reports whether w was cordoned.
+func (p *Pool) Uncordon(w *Worker) bool {
+	if !w.setCordoned(false) {
+		return false
+	}
+	poolLogger(w).Info("worker uncordoned")
+	if w.State() == WorkerStateAvailable {
+		p.Release(w)
+	}
+	return true
+}
+
+// isMember reports whether w is one of the pool's workers. Workers join
+// before their process starts and leave only when removed for good.
+func (p *Pool) isMember(w *Worker) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return slices.Contains(p.workers, w)
+}
+
+// forget removes w from the pool's workers, frees its port for a future
+// worker, and returns how many workers remain.
+func (p *Pool) forget(w *Worker) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if i := slices.Index(p.workers, w); i >= 0 {
+		p.workers = slices.Delete(p.workers, i, i+1)
+		p.ports.release(w.Port)
+	}
+	return len(p.workers)
+}
+
+// Ready returns a channel that is closed once as many workers as initially
+// spawned have become ready for the first time.
+func (p *Pool) Ready() <-chan struct{} {
+	return p.readyCh
+}
+
+// setInitialTarget fixes how many initial workers the readiness barrier waits
+// for. Workers may already have become ready while NewPool was still spawning.
+func (p *Pool) setInitialTarget(n int) {
+	p.mu.Lock()
+	p.initialTarget = n
+	done := len(p.initialReady) >= n
+	p.mu.Unlock()
+	if done {
+		p.readyOnce.Do(func() { close(p.readyCh) })
+	}
+}
+
+// markReady records that a worker passed waitForReady, closing the readiness
+// barrier once enough distinct workers have done so.
+func (p *Pool) markReady(w *Worker) {
+	p.mu.Lock()
+	p.initialReady[w.ID] = true
+	done := p.initialTarget >= 0 && len(p.initialReady) >= p.initialTarget
+	p.mu.Unlock()
+	if done {
+		p.readyOnce.Do(func() { close(p.readyCh) })
+	}
+	p.noteWorkerReady(w)
 }
 
 // Acquire blocks until a worker is available or the context is canceled.
-// If all workers are busy and the pool has room to grow, a new worker is
-// spawned asynchronously before blocking so it may arrive quickly.
-func (p *Pool) Acquire(ctx context.Context) (*Worker, error) {
-	// Trigger a scale-up if the pool is fully occupied and below the ceiling.
-	// Use pendingAdds alongside len(workers) so we don't fire redundant goroutines
-	// when multiple requests arrive simultaneously and workers are still starting.
-	if len(p.available) == 0 {
-		p.mu.RLock()
-		total := len(p.workers) + p.pendingAdds
-		p.mu.RUnlock()
-		if total < p.max {
-			log.Printf("[pool] all workers busy — scaling up (workers: %d → %d/%d)", total, total+1, p.max)
-			go p.addWorker()
+// Blocked callers are served highest priority first and strictly first come,
+// first served within a priority, so a steady stream of new arrivals cannot
+// starve one of the same priority that has been waiting longer.
+// Only workers still Available are handed out: one that died, was stopped or
+// failed a health check while queued is skipped, and the caller keeps its
+// place for the next one, within the same ctx deadline.
+// Callers are counted in waiting while they block; if the pool cannot cover
+// them, replenish starts the whole shortfall at once before blocking so a
+// burst does not grow the pool one boot cycle at a time.
+// With MaxQueueDepth set, a caller that would have to wait while that many
+// others already are gets ErrQueueFull at once instead of joining them.
+// While the pool is degraded (every worker failing) callers get
+// ErrPoolDegraded at once, and callers already waiting get it when the pool
+// becomes degraded.
+func (p *Pool) Acquire(ctx context.Context, prio Priority) (*Worker, error) {
+	if p.Degraded().Degraded {
+		p.degradedRejects.Add(1)
+		return nil, ErrPoolDegraded
+	}
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(p.degradedContext(), cancel)
+	defer stop()
+
+	p.waiting.Add(1)
+	defer p.waiting.Add(-1)
+	if p.FreeSlots() == 0 {
+		p.replenish()
+	}
+
+	start := p.clk.Now()
+	w, err := p.available.get(ctx, prio, func(w *Worker) bool {
+		w.clearQueued()
+		if w.isDraining() {
+			// Stopped while queued (e.g. during shutdown); never hand it out.
+			poolLogger(w).Debug("acquire skipped draining worker")
+			return false
+		}
+		if !w.claim() {
+			// Died or failed a health check while queued. Dropping it is
+			// safe: it is queued again when it next passes waitForReady.
+			poolLogger(w).Debug("acquire skipped worker that is no longer available", "state", w.State().String())
+			return false
 		}
+		return true
+	})
+	if errors.Is(err, errQueueFull) {
+		p.queueRejects.Add(1)
+		return nil, ErrQueueFull
 	}
+	p.observeWait(p.clk.Since(start))
+	if err != nil && parent.Err() == nil && p.Degraded().Degraded {
+		p.degradedRejects.Add(1)
+		return nil, ErrPoolDegraded
+	}
+	if err != nil {
+		p.acquireTimeouts.Add(1)
+		return nil, p.acquireError(err)
+	}
+	if w.State() == WorkerStateAvailable {
+		// It still has free session slots: queue it for the next caller.
+		p.Release(w)
+	}
+	if debugEnabled() {
+		poolLogger(w).Debug("acquired", "available", p.FreeSlots())
+	}
+	p.acquired.Add(1)
+	p.counters.acquires.Add(1)
+	go p.replenish()
+	return w, nil
+}
 
-	select {
-	case w := <-p.available:
-		log.Printf("[pool] :%-5d acquired (available: %d)", w.Port, len(p.available))
-		return w, nil
-	case <-ctx.Done():
-		return nil, fmt.Errorf("timed out waiting for available worker: %w", ctx.Err())
+// Errors from AcquireWorker.
+var (
+	errWorkerNotFound    = errors.New("no such worker")
+	errWorkerUnavailable = errors.New("worker is not available")
+)
+
+// AcquireWorker claims a session slot on the worker with the given ID,
+// bypassing the waiter queue: it fails at once with errWorkerUnavailable if
+// that worker is busy, booting, dead or draining. It is the X-Force-Worker
+// debugging aid; a cordoned worker can still be forced.
+func (p *Pool) AcquireWorker(id int) (*Worker, error) {
+	w, ok := p.WorkerByID(id)
+	if !ok {
+		return nil, errWorkerNotFound
+	}
+	if p.available.remove(w) {
+		w.clearQueued()
+	}
+	if w.isDraining() || !w.claim() {
+		return nil, errWorkerUnavailable
+	}
+	if w.State() == WorkerStateAvailable {
+		p.Release(w) // still has free session slots
+	}
+	poolLogger(w).Debug("acquired by X-Force-Worker")
+	p.acquired.Add(1)
+	p.counters.acquires.Add(1)
+	go p.replenish()
+	return w, nil
+}
+
+// ErrQueueFull is returned by Acquire when no worker is free and
+// MaxQueueDepth callers are already waiting for one.
+var ErrQueueFull = errQueueFull
+
+// AcquireError is returned by Acquire when its context ends before a worker
+// is free. It describes where the caller stood and how loaded the pool was,
+// so a client can judge when to retry.
+type AcquireError struct {
+	Err        error // the context's error
+	Position   int   // 1-based place in the waiter queue when the caller gave up; 0 if it was not queued
+	QueueDepth int   // callers blocked in Acquire at that moment, this one included
+	Workers    int   // registered workers, any state
+	Booting    int   // workers still starting up
+	MaxWorkers int
+}
+
+func (e *AcquireError) Error() string {
+	return fmt.Sprintf("timed out waiting for available worker (queue position %d of %d, %d workers): %v", e.Position, e.QueueDepth, e.Workers, e.Err)
+}
+
+func (e *AcquireError) Unwrap() error { return e.Err }
+
+// acquireError wraps the queue's error for a caller that gave up waiting.
+func (p *Pool) acquireError(err error) *AcquireError {
+	ae := &AcquireError{Err: err, QueueDepth: int(p.waiting.Load())}
+	var wt *waitTimeout
+	if errors.As(err, &wt) {
+		ae.Position = wt.ahead + 1
+		ae.Err = wt.err
 	}
+	p.mu.RLock()
+	ae.Workers = len(p.workers)
+	ae.Booting = p.bootingLocked()
+	ae.MaxWorkers = p.max
+	p.mu.RUnlock()
+	return ae
+}
+
+// observeWait records one Acquire wait for /status and the scale policy.
+func (p *Pool) observeWait(d time.Duration) {
+	p.acquireWait.Observe(d)
+	p.recentWait.Observe(d)
 }
 
 // FindBySession returns the worker that holds the given session ID.
@@ -107,7 +690,7 @@ func (p *Pool) FindBySession(sessionID string) (*Worker, bool) {
 	defer p.mu.RUnlock()
 
 	for _, w := range p.workers {
-		if w.SessionID() == sessionID {
+		if w.HasSession(sessionID) {
 			return w, true
 		}
 	}
@@ -123,159 +706,1204 @@ func (p *Pool) Workers() []*Worker {
 	return out
 }
 
-// QueueDepth returns how many workers are currently available.
+// QueueDepth returns how many sessions can be created right now without
+// waiting: the free slots of the available workers.
 func (p *Pool) QueueDepth() int {
-	return len(p.available)
+	return p.FreeSlots()
+}
+
+// FreeSlots returns the free session slots of the workers in the available
+// queue. With one session per worker it is the number of idle workers.
+func (p *Pool) FreeSlots() int {
+	return p.available.freeSlots()
+}
+
+// SessionsPerWorker returns how many sessions each worker holds at once.
+func (p *Pool) SessionsPerWorker() int { return p.sessionsPerWorker }
+
+// ObserveCreateLatency records the worker round-trip time of a successful create.
+func (p *Pool) ObserveCreateLatency(d time.Duration) {
+	p.createLatency.Observe(d)
+}
+
+// CreateLatency returns a snapshot of the create latency histogram.
+func (p *Pool) CreateLatency() latencySnapshot {
+	return p.createLatency.Snapshot()
+}
+
+// AcquireWait returns wait-time percentiles for recent Acquire calls.
+func (p *Pool) AcquireWait() windowPercentiles {
+	return p.acquireWait.Percentiles()
+}
+
+// QueueRejections returns how many Acquire calls were refused because the
+// waiter queue was full.
+func (p *Pool) QueueRejections() int64 {
+	return p.queueRejects.Load()
+}
+
+// MaxQueueDepth returns the waiter queue's limit; 0 means unbounded.
+func (p *Pool) MaxQueueDepth() int { return p.available.maxWaiters }
+
+// AcquireTimeouts returns how many Acquire calls gave up waiting.
+func (p *Pool) AcquireTimeouts() int64 {
+	return p.acquireTimeouts.Load()
+}
+
+// poolCounters are the pool's lifetime totals, kept with atomics at the
+// points where each event happens and reset only when the process restarts.
+type poolCounters struct {
+	acquires   atomic.Int64 // workers handed out by Acquire
+	releases   atomic.Int64 // workers queued (or handed to a waiter) on becoming ready or freed
+	scaleUps   atomic.Int64 // workers added after startup
+	scaleDowns atomic.Int64 // workers removed as idle or above max-workers
+	crashes    atomic.Int64 // unplanned worker exits, recycles included
+	restarts   atomic.Int64 // worker processes restarted after an exit
+	failed     atomic.Int64 // workers given up on as crash-looping and replaced
+}
+
+// PoolStatsSnapshot is a point-in-time copy of the pool's lifetime counters,
+// as returned by Stats.
+type PoolStatsSnapshot struct {
+	Acquires          int64 `json:"acquires"`
+	Releases          int64 `json:"releases"`
+	ScaleUps          int64 `json:"scale_ups"`
+	ScaleDowns        int64 `json:"scale_downs"`
+	Crashes           int64 `json:"crashes"`
+	Restarts          int64 `json:"restarts"`
+	FailedWorkers     int64 `json:"failed_workers"`
+	AcquireTimeouts   int64 `json:"acquire_timeouts"`
+	QueueRejections   int64 `json:"queue_rejections"`
+	DegradedRejects   int64 `json:"degraded_rejections"` // creates failed fast while the pool was degraded
+	ReadinessFailures int64 `json:"readiness_failures"`
+	SmokeTestFailures int64 `json:"smoke_test_failures"` // healthy workers killed for failing --ready-smoke-test
+	Quarantines       int64 `json:"quarantines"`         // workers quarantined after a failed health probe
+	Readmissions      int64 `json:"readmissions"`        // quarantined workers that recovered and rejoined the queue
+	DeepCheckFailures int64 `json:"deep_check_failures"` // deep checks failed after a passing health probe
+	PendingAcquires   int64 `json:"pending_acquires"`    // callers blocked in Acquire right now
+}
+
+// Stats returns the pool's lifetime counters since the process started.
+func (p *Pool) Stats() PoolStatsSnapshot {
+	return PoolStatsSnapshot{
+		Acquires:          p.counters.acquires.Load(),
+		Releases:          p.counters.releases.Load(),
+		ScaleUps:          p.counters.scaleUps.Load(),
+		ScaleDowns:        p.counters.scaleDowns.Load(),
+		Crashes:           p.counters.crashes.Load(),
+		Restarts:          p.counters.restarts.Load(),
+		FailedWorkers:     p.counters.failed.Load(),
+		AcquireTimeouts:   p.acquireTimeouts.Load(),
+		QueueRejections:   p.queueRejects.Load(),
+		DegradedRejects:   p.degradedRejects.Load(),
+		ReadinessFailures: p.readyFailures.Load(),
+		SmokeTestFailures: p.smokeFailures.Load(),
+		Quarantines:       p.quarantines.Load(),
+		Readmissions:      p.readmissions.Load(),
+		DeepCheckFailures: p.deepFailures.Load(),
+		PendingAcquires:   p.waiting.Load(),
+	}
+}
+
+// WaitingByPriority returns how many callers are blocked in Acquire in each
+// priority lane.
+func (p *Pool) WaitingByPriority() map[string]int {
+	n := p.available.waiting()
+	out := make(map[string]int, len(n))
+	for prio, count := range n {
+		out[Priority(prio).String()] = count
+	}
+	return out
+}
+
+// Waiting returns how many callers are queued in Acquire for a worker.
+func (p *Pool) Waiting() int {
+	n := 0
+	for _, c := range p.available.waiting() {
+		n += c
+	}
+	return n
+}
+
+// ReadinessFailures returns how many worker processes were killed for not
+// becoming ready within the ready timeout.
+func (p *Pool) ReadinessFailures() int64 {
+	return p.readyFailures.Load()
+}
+
+// Tag returns the pool's --worker-pool tag, or "default".
+func (p *Pool) Tag() string {
+	if p.tag == "" {
+		return defaultPoolTag
+	}
+	return p.tag
 }
 
 // Min returns the minimum number of workers the pool will maintain.
-func (p *Pool) Min() int { return p.min }
+func (p *Pool) Min() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.min
+}
 
 // Max returns the maximum number of workers the pool may scale up to.
-func (p *Pool) Max() int { return p.max }
+func (p *Pool) Max() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.max
+}
 
-// addWorker creates, starts, and registers a new worker during scale-up.
-// The OS assigns a free port; no port tracking needed.
-// pendingAdds is incremented before the lock is released so that concurrent
-// calls to addWorker see the correct in-flight count and cannot overshoot max.
-func (p *Pool) addWorker() {
+// SetMax changes the scale-up ceiling at runtime. Lowering it below the
+// current worker count never drops a live session: idle workers above the
+// new max are removed at once, newest first, and the rest of the excess is
+// marked surplus and removed when next released (see Release). Raising it
+// again unmarks workers that fit.
+// With scale windows configured, n is the base max: it applies outside
+// the windows, and inside them only once they close.
+func (p *Pool) SetMax(n int) error {
 	p.mu.Lock()
-	if len(p.workers)+p.pendingAdds >= p.max {
+	if baseMin := p.baseMin; n < 1 || n < baseMin || n > maxPoolCapacity {
 		p.mu.Unlock()
+		return fmt.Errorf("max-workers must be between max(1, min-workers=%d) and %d", baseMin, maxPoolCapacity)
+	}
+	p.baseMax = n
+	p.mu.Unlock()
+	if len(p.windows) > 0 {
+		p.applyScaleLimits(p.scaleLimits())
+		return nil
+	}
+	p.resize(n)
+	return nil
+}
+
+// SetMin changes the number of workers the pool keeps at runtime. Raising
+// it starts the missing workers at once; lowering it lets the scale policy
+// remove idle workers down to the new min.
+// With scale windows configured, n is the base min, as for SetMax.
+func (p *Pool) SetMin(n int) error {
+	p.mu.Lock()
+	if baseMax := p.baseMax; n < 0 || n > baseMax {
+		p.mu.Unlock()
+		return fmt.Errorf("min-workers must be between 0 and max-workers=%d", baseMax)
+	}
+	p.baseMin = n
+	p.mu.Unlock()
+
+	l := p.scaleLimits()
+	p.applyScaleLimits(l)
+	p.mu.RLock()
+	short := l.Min - len(p.workers) - p.pendingAdds
+	p.mu.RUnlock()
+	if short > 0 {
+		p.scaleUp(short, "min-workers raised")
+	}
+	return nil
+}
+
+// resize makes n the max in force, removing or marking surplus the workers
+// above it as SetMax describes.
+func (p *Pool) resize(n int) {
+	p.mu.Lock()
+	p.max = n
+	workers := slices.Clone(p.workers)
+	p.mu.Unlock()
+
+	excess := len(workers) - n
+	if excess <= 0 {
+		for _, w := range workers {
+			w.setSurplus(false)
+		}
 		return
 	}
-	id := p.nextID
-	p.nextID++
-	p.pendingAdds++ // reserve the slot before releasing the lock
+	slices.SortFunc(workers, func(a, b *Worker) int { return b.ID - a.ID })
+	removed := 0
+	for _, w := range workers {
+		if removed == excess {
+			break
+		}
+		if !p.available.remove(w) {
+			continue
+		}
+		w.clearQueued()
+		if w.Load() > 0 {
+			p.Release(w) // still holds sessions; marked surplus below
+			continue
+		}
+		p.removeWorker(w, "above max-workers")
+		removed++
+	}
+	marked := 0
+	for _, w := range workers {
+		keep := w.isDraining() || marked >= excess-removed
+		if !keep {
+			marked++
+		}
+		w.setSurplus(!keep)
+	}
+	p.logger().Info("max-workers lowered", "max_workers", n, "workers", len(workers),
+		"removed_idle", removed, "removing_after_release", marked)
+}
+
+// scaleLimits resolves the min and max that apply now from the base limits
+// and the scale windows.
+func (p *Pool) scaleLimits() ScaleLimits {
+	p.mu.RLock()
+	baseMin, baseMax := p.baseMin, p.baseMax
+	p.mu.RUnlock()
+	return scaleLimitsAt(p.windows, p.windowLead, p.clk.Now(), baseMin, baseMax)
+}
+
+// ScaleLimits returns the min and max in force and the scale windows that
+// set them, for /status.
+func (p *Pool) ScaleLimits() ScaleLimits {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	l := p.limits
+	l.Min, l.Max = p.min, p.max
+	l.Active = append([]string{}, l.Active...)
+	return l
+}
+
+// BaseLimits returns the configured min and max that apply outside every
+// scale window.
+func (p *Pool) BaseLimits() (min, max int) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.baseMin, p.baseMax
+}
+
+// applyScaleLimits puts l in force. When the deciding window changes it logs
+// the new limits and records a scale_window event; a lower max removes or
+// marks surplus workers as SetMax does. A higher max only lifts the ceiling.
+func (p *Pool) applyScaleLimits(l ScaleLimits) {
+	p.mu.Lock()
+	prev := p.limits
+	lowered := l.Max < p.max
+	p.min, p.limits = l.Min, l
+	if !lowered {
+		p.max = l.Max
+	}
+	p.mu.Unlock()
+	if lowered {
+		p.resize(l.Max)
+	}
+	if l.Window != prev.Window {
+		log := p.logger().With("from", prev.Window, "to", l.Window, "min_workers", l.Min, "max_workers", l.Max)
+		if l.Window == "" {
+			log.Info("scale window closed — back to base limits")
+		} else {
+			log.Info("scale window in force", "active", l.Active)
+		}
+		p.events.Record(EventScaleWindow, "pool", p.Tag(), "from", prev.Window, "to", l.Window,
+			"min_workers", l.Min, "max_workers", l.Max)
+	}
+}
+
+// applySchedule brings the scale windows up to date and starts whatever
+// workers the min in force is short of, so a window's extra capacity is
+// booting before its load arrives rather than waiting for the first
+// queued create. Called on each scaleLoop tick.
+func (p *Pool) applySchedule() {
+	l := p.scaleLimits()
+	p.applyScaleLimits(l)
+	p.mu.RLock()
+	short := l.Min - len(p.workers) - p.pendingAdds
+	p.mu.RUnlock()
+	if short > 0 && l.Window != "" {
+		p.scaleUp(short, "scale window "+l.Window)
+	}
+}
+
+// SetHealthCheckInterval changes the health-check period; it takes effect
+// after the current tick.
+func (p *Pool) SetHealthCheckInterval(d time.Duration) {
+	p.healthInterval.Store(int64(d))
+}
+
+// replenish scales up so that the worker supply — idle workers plus those
+// that will become idle on their own (pendingAdds and workers still
+// Starting) — covers every caller blocked in Acquire plus the warm buffer
+// (see warmBufferLocked). The whole shortfall is reserved in one batch,
+// bounded by max, and startReserved staggers the boots. Slots are reserved
+// in pendingAdds under the lock, so concurrent calls cannot overshoot.
+// Called on entry to and after every Acquire, and on each scaleLoop tick.
+func (p *Pool) replenish() {
+	p.mu.Lock()
+	// Read under the lock so that of several callers arriving together, the
+	// first to get here sizes the batch for all of them.
+	waiting := int(p.waiting.Load())
+	warm := p.warmBufferLocked(waiting)
+	want := waiting + warm
+	if want <= 0 {
+		p.mu.Unlock()
+		return
+	}
+	cold := len(p.workers) == 0
+	booting := p.bootingLocked()
+	supply := p.available.freeSlots() + booting*p.sessionsPerWorker
+	ids := p.reserveLocked(ceilDiv(want-supply, p.sessionsPerWorker))
+	total := len(p.workers) + p.pendingAdds
+	max := p.max
+	p.mu.Unlock()
+
+	if len(ids) == 0 {
+		return
+	}
+	reason := "warm buffer"
+	switch {
+	case cold && booting == 0:
+		reason = "cold start"
+	case waiting > supply:
+		reason = "all workers busy"
+	}
+	p.logger().Info("scaling up", "reason", reason, "batch", len(ids), "waiting", waiting, "warm_buffer", warm,
+		"supply", supply, "target", total, "max_workers", max)
+	for _, id := range ids {
+		go p.startReserved(id, reason)
+	}
+}
+
+// warmBufferLocked is the warm buffer to keep for waiting callers in
+// Acquire. A pool that may scale to zero (min 0) keeps none while nobody is
+// waiting and no worker holds a session, so once traffic stops it can empty
+// completely and the next create cold-starts a worker. Caller holds p.mu.
+func (p *Pool) warmBufferLocked(waiting int) int {
+	if p.min > 0 || waiting > 0 {
+		return p.warmBuffer
+	}
+	for _, w := range p.workers {
+		if w.Load() > 0 {
+			return p.warmBuffer
+		}
+	}
+	return 0
+}
+
+// scaleUp starts up to n workers on behalf of the scale policy, within max
+// and the concurrent boot limit.
+func (p *Pool) scaleUp(n int, reason string) {
+	p.mu.Lock()
+	ids := p.reserveLocked(n)
+	total := len(p.workers) + p.pendingAdds
+	max := p.max
 	p.mu.Unlock()
 
-	port, err := findFreePort()
+	if len(ids) == 0 {
+		return
+	}
+	p.logger().Info("scaling up", "reason", reason, "batch", len(ids), "target", total, "max_workers", max)
+	for _, id := range ids {
+		go p.startReserved(id, reason)
+	}
+}
+
+// bootingLocked counts workers that will become available on their own:
+// pending adds plus workers still Starting. Caller holds p.mu.
+func (p *Pool) bootingLocked() int {
+	booting := p.pendingAdds
+	for _, w := range p.workers {
+		if w.State() == WorkerStateStarting {
+			booting++
+		}
+	}
+	return booting
+}
+
+// reserveLocked reserves IDs and pendingAdds slots for up to n new workers,
+// capped by max (or the memory budget, if lower).
+// Caller holds p.mu for writing.
+func (p *Pool) reserveLocked(n int) []int {
+	if p.closing {
+		return nil
+	}
+	want := n
+	n = min(n, p.ceilingLocked()-len(p.workers)-p.pendingAdds)
+	if capped := n < want && p.budgetWorkers > 0 && p.budgetWorkers < p.max; want > 0 && capped != p.budgetCapped {
+		p.budgetCapped = capped
+		if capped {
+			p.logger().Warn("scale-up limited by the pool memory budget — requests will queue",
+				"budget_workers", p.budgetWorkers, "workers", len(p.workers)+p.pendingAdds, "wanted", want, "max_workers", p.max)
+		}
+	}
+	ids := make([]int, 0, max(n, 0))
+	for range n {
+		ids = append(ids, p.nextID)
+		p.nextID++
+		p.pendingAdds++ // reserve the slot before releasing the lock
+	}
+	if len(ids) > 0 {
+		p.lastScaleUp = p.clk.Now()
+	}
+	return ids
+}
+
+// ceilingLocked returns how many workers the pool may hold: max, lowered to
+// what the memory budget allows. Caller holds p.mu.
+func (p *Pool) ceilingLocked() int {
+	if p.budgetWorkers > 0 {
+		return min(p.max, p.budgetWorkers)
+	}
+	return p.max
+}
+
+// startReserved starts worker id in a slot already counted in pendingAdds.
+// With --max-concurrent-boots it first waits for a start slot, keeping the
+// reservation meanwhile, holds the slot until the worker has booted, and
+// logs how long the boot and the wait for the slot took.
+func (p *Pool) startReserved(id int, reason string) {
+	var waited time.Duration
+	if p.startSem != nil {
+		queued := p.clk.Now()
+		p.startSem <- struct{}{}
+		defer func() { <-p.startSem }()
+		if waited = p.clk.Since(queued); waited >= readyPollInterval {
+			p.logger().Debug("scale-up: got a start slot", "worker_id", id, "waited", waited.Round(time.Millisecond).String())
+		}
+	}
+
+	port, err := p.ports.acquire()
 	if err != nil {
-		log.Printf("[pool] scale-up failed: could not get free port — %v", err)
+		p.logger().Error("scale-up failed: could not get free port", "error", err)
 		p.mu.Lock()
 		p.pendingAdds--
 		p.mu.Unlock()
 		return
 	}
 
-	w := NewWorker(id, port, p.binaryPath, p)
+	w := NewWorker(id, port, p.launch, p)
 	if p.CrashHandler != nil {
 		w.OnCrash = p.CrashHandler
 	}
 
-	if err := w.Start(); err != nil {
-		log.Printf("[pool] scale-up failed: port=%d — %v", port, err)
-		p.mu.Lock()
-		p.pendingAdds--
+	// Join the pool before the process starts: Release only accepts
+	// members, and waitForReady releases the worker as soon as it is up.
+	p.mu.Lock()
+	p.pendingAdds--
+	if p.closing {
 		p.mu.Unlock()
+		p.ports.release(port)
 		return
 	}
-
-	p.mu.Lock()
 	p.workers = append(p.workers, w)
-	p.pendingAdds--
 	count := len(p.workers)
+	max := p.max
 	p.mu.Unlock()
 
-	log.Printf("[pool] scale-up: :%-5d started (workers: %d/%d)", port, count, p.max)
-}
+	started := p.clk.Now()
+	if err := w.Start(); err != nil {
+		p.logger().Error("scale-up failed", "worker_id", id, "port", port, "error", err)
+		p.forget(w)
+		return
+	}
 
-// findFreePort asks the OS for an available TCP port by binding to :0.
-func findFreePort() (int, error) {
-	ln, err := net.Listen("tcp", "127.0.0.1:0")
-	if err != nil {
-		return 0, err
+	poolLogger(w).Info("scale-up: worker started", "workers", count, "max_workers", max)
+	p.counters.scaleUps.Add(1)
+	p.events.Record(EventScaleUp, "worker_id", w.ID, "port", w.Port, "workers", count, "reason", reason)
+	p.audit.Record(AuditScaleUp, w, reason, "workers", count)
+
+	if p.startSem != nil {
+		// Keep the start slot until the boot is over either way: ready,
+		// killed as not ready, or exited.
+		for w.State() == WorkerStateStarting {
+			p.clk.Sleep(readyPollInterval)
+		}
+		poolLogger(w).Info("scale-up: boot finished", "state", w.State().String(),
+			"boot_time", p.clk.Since(started).Round(time.Millisecond).String(),
+			"slot_wait", waited.Round(time.Millisecond).String())
 	}
-	defer ln.Close()
-	return ln.Addr().(*net.TCPAddr).Port, nil
 }
 
-// scaleLoop ticks every 10 s and removes idle workers above the minimum.
-// Uses a consecutive-idle-tick counter to avoid thrashing — a worker is only
-// removed after 2 ticks (20 s) of sustained idleness.
+// defaultScaleInterval is the scaleLoop tick when none is configured.
+const defaultScaleInterval = 10 * time.Second
+
+// scaleLoop ticks every scaleInterval (± loopJitter), tops up the warm
+// buffer, and applies the configured ScalePolicy to a fresh PoolStats
+// snapshot. Scale-down only ever removes idle workers beyond the warm buffer,
+// so it and replenish never undo each other, and is suppressed for
+// scaleUpCooldown after the last scale-up.
 func (p *Pool) scaleLoop() {
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
+	for {
+		<-p.clk.After(jittered(p.scaleInterval, loopJitter))
+		if len(p.windows) > 0 {
+			p.applySchedule()
+		}
+		p.replenish()
 
-	idleTicks := 0
-	for range ticker.C {
-		p.mu.RLock()
-		count := len(p.workers)
-		p.mu.RUnlock()
+		st := p.scaleStats()
+		switch delta := p.policy.Decide(st); {
+		case delta > 0:
+			p.scaleUp(delta, "acquire wait above target")
+		case delta < 0:
+			if since := p.sinceLastScaleUp(); since < p.scaleUpCooldown {
+				p.logger().Debug("scale-down suppressed — within scale-up cooldown", "since_scale_up", since.Round(time.Second).String(), "cooldown", p.scaleUpCooldown.String())
+				continue
+			}
+			n := max(-delta, p.scaleDownBatch(st))
+			if n > 1 {
+				p.logger().Info("scaling down", "batch", n, "available", st.Available, "workers", st.Workers, "min_workers", st.Min)
+			}
+			minIdle := p.ScaleSettings().ScaleDownAfter
+			for range n {
+				p.removeIdleWorker(minIdle)
+			}
+		}
+	}
+}
 
-		available := len(p.available)
+// scaleDownBatch is how many workers a scale-down tick may remove:
+// scaleDownFrac of the idle surplus, the idle workers beyond both the warm
+// buffer and min. A heavily overprovisioned pool so shrinks geometrically
+// rather than by one worker per tick. 0 leaves it to the policy.
+func (p *Pool) scaleDownBatch(st PoolStats) int {
+	surplus := min(st.Available-st.WarmBuffer, st.Workers-st.Min)
+	return int(float64(surplus) * p.scaleDownFrac)
+}
 
-		if available > 0 && count > p.min {
-			idleTicks++
-		} else {
-			idleTicks = 0
-		}
+// sinceLastScaleUp returns how long ago workers were last added, or a very
+// large duration if never.
+func (p *Pool) sinceLastScaleUp() time.Duration {
+	p.mu.RLock()
+	last := p.lastScaleUp
+	p.mu.RUnlock()
+	if last.IsZero() {
+		return time.Duration(math.MaxInt64)
+	}
+	return p.clk.Since(last)
+}
+
+// ScaleSettings describes the scaler for /status.
+type ScaleSettings struct {
+	Policy            string
+	Interval          time.Duration
+	ScaleDownAfter    time.Duration // idle policy only: how long a worker must sit idle
+	ScaleUpCooldown   time.Duration
+	ScaleDownFraction float64
+	LastScaleUp       time.Time // zero if the pool never scaled up
+}
+
+// ScaleSettings returns the scaler configuration and last scale-up time.
+func (p *Pool) ScaleSettings() ScaleSettings {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	s := ScaleSettings{
+		Policy:            p.policy.Name(),
+		Interval:          p.scaleInterval,
+		ScaleUpCooldown:   p.scaleUpCooldown,
+		ScaleDownFraction: p.scaleDownFrac,
+		LastScaleUp:       p.lastScaleUp,
+	}
+	if ip, ok := p.policy.(*idlePolicy); ok {
+		s.ScaleDownAfter = ip.after
+	}
+	return s
+}
 
-		if idleTicks >= 2 {
-			p.removeIdleWorker()
-			idleTicks = 0
+// CapacityPlan projects what the pool would do with a number of concurrent
+// creates, for GET /admin/capacity.
+type CapacityPlan struct {
+	Concurrency       int  `json:"concurrency"`
+	CurrentWorkers    int  `json:"current_workers"`
+	AvailableWorkers  int  `json:"available_workers"`
+	FreeSlots         int  `json:"free_slots"` // session slots free on available workers
+	BootingWorkers    int  `json:"booting_workers"`
+	SessionsPerWorker int  `json:"sessions_per_worker"`
+	MaxWorkers        int  `json:"max_workers"`
+	EffectiveMax      int  `json:"effective_max_workers"` // max-workers, lowered by the memory budget
+	ProjectedWorkers  int  `json:"projected_workers"`
+	NewWorkers        int  `json:"new_workers"`
+	BootWaves         int  `json:"boot_waves"` // rounds of at most --max-concurrent-boots concurrent boots
+	HitsMax           bool `json:"hits_max"`
+	QueuedCreates     int  `json:"queued_creates"` // creates left waiting for a session to end
+	WouldQueue        bool `json:"would_queue"`
+}
+
+// Capacity projects n concurrent creates onto the pool as it is now, without
+// changing it. Free slots on available and booting workers absorb creates
+// first, then new workers up to the effective max; whatever is left has to
+// wait for a session to end.
+func (p *Pool) Capacity(n int) CapacityPlan {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c := CapacityPlan{
+		Concurrency:       n,
+		CurrentWorkers:    len(p.workers),
+		AvailableWorkers:  p.available.idleLen(),
+		FreeSlots:         p.available.freeSlots(),
+		BootingWorkers:    p.bootingLocked(),
+		SessionsPerWorker: p.sessionsPerWorker,
+		MaxWorkers:        p.max,
+		EffectiveMax:      p.ceilingLocked(),
+	}
+	ready := c.FreeSlots + c.BootingWorkers*p.sessionsPerWorker
+	room := max(c.EffectiveMax-c.CurrentWorkers-p.pendingAdds, 0)
+	c.NewWorkers = min(max(ceilDiv(n-ready, p.sessionsPerWorker), 0), room)
+	c.ProjectedWorkers = c.CurrentWorkers + p.pendingAdds + c.NewWorkers
+	if c.NewWorkers > 0 {
+		c.BootWaves = 1
+		if n := p.bootConcurrency(); n > 0 {
+			c.BootWaves = ceilDiv(c.NewWorkers, n)
 		}
 	}
+	c.QueuedCreates = max(n-ready-c.NewWorkers*p.sessionsPerWorker, 0)
+	c.WouldQueue = c.QueuedCreates > 0
+	c.HitsMax = c.ProjectedWorkers >= c.EffectiveMax && n > ready
+	return c
 }
 
-// removeIdleWorker grabs one idle worker from the available channel and shuts it down.
-// The worker is drained before being killed so monitor() does not restart it.
-func (p *Pool) removeIdleWorker() {
-	select {
-	case w := <-p.available:
-		p.mu.Lock()
-		for i, existing := range p.workers {
-			if existing == w {
-				p.workers = append(p.workers[:i], p.workers[i+1:]...)
-				break
-			}
+// bootConcurrency is how many scale-up workers can boot at once, 0 if that
+// is unlimited.
+func (p *Pool) bootConcurrency() int {
+	return cap(p.startSem)
+}
+
+// ceilDiv returns a/b rounded up, for b > 0; a <= 0 gives at most 0.
+func ceilDiv(a, b int) int {
+	if a <= 0 {
+		return 0
+	}
+	return (a + b - 1) / b
+}
+
+// scaleStats snapshots the pool for the scale policy and resets the
+// per-tick Acquire counter.
+func (p *Pool) scaleStats() PoolStats {
+	st := PoolStats{
+		Waiting:  int(p.waiting.Load()),
+		Acquired: int(p.acquired.Swap(0)),
+		Now:      p.clk.Now(),
+		WaitP95:  time.Duration(p.recentWait.Percentiles().P95Ms * float64(time.Millisecond)),
+	}
+	st.Available = p.available.idleCount(func(w *Worker) bool {
+		if w.Load() > 0 {
+			return false
 		}
-		count := len(p.workers)
-		p.mu.Unlock()
+		st.IdleFor = append(st.IdleFor, w.IdleFor(st.Now))
+		return true
+	})
+	p.mu.RLock()
+	st.Workers = len(p.workers)
+	st.Min = p.min
+	st.Max = p.ceilingLocked()
+	st.Booting = p.bootingLocked()
+	st.WarmBuffer = p.warmBufferLocked(st.Waiting)
+	for _, w := range p.workers {
+		if w.Load() > 0 {
+			st.Busy++
+		}
+	}
+	p.mu.RUnlock()
+	return st
+}
 
-		w.Drain()
-		w.Kill()
+// removeIdleWorker takes the most recently started worker that has held no
+// session for at least minIdle from the queue and shuts it down, so the
+// long-lived warm workers are the ones kept. The worker is stopped
+// intentionally so monitor() does not restart it. A worker that gained a
+// session meanwhile (a claim raced with the scan) is put back and skipped,
+// so scale-down never kills a live session.
+func (p *Pool) removeIdleWorker(minIdle time.Duration) {
+	now := p.clk.Now()
+	w, ok := p.available.tryGetFunc(func(w *Worker) bool { return w.Load() == 0 && w.IdleFor(now) >= minIdle },
+		func(a, b *Worker) int { return b.StartedAt().Compare(a.StartedAt()) })
+	if !ok {
+		return // no idle worker right now
+	}
+	w.clearQueued()
+	if ids := w.Sessions(); len(ids) > 0 || w.Load() > 0 {
+		poolLogger(w).Warn("scale-down skipped — idle worker still holds sessions", "session_ids", ids)
+		p.Release(w)
+		return
+	}
+	if n := p.waiting.Load(); n > 0 {
+		// A burst arrived since the tick; the caller needs this worker.
+		poolLogger(w).Info("scale-down skipped — callers waiting in Acquire", "waiting", n)
+		p.Release(w)
+		return
+	}
+	p.removeWorker(w, "sustained idleness")
+}
 
-		log.Printf("[pool] scale-down: :%-5d removed (workers: %d/%d)", w.Port, count, p.max)
-	default:
-		// No idle worker available right now — skip
+// removeWorker takes w out of the pool for good and stops it. The caller
+// has made sure it holds no session.
+func (p *Pool) removeWorker(w *Worker, reason string) {
+	// Mark it non-restartable and non-releasable before anything else can
+	// hand it back to the queue.
+	w.Drain()
+
+	count := p.forget(w)
+	max := p.Max()
+
+	p.audit.Record(AuditScaleDown, w, reason, "workers", count)
+	w.Stop("scale-down")
+	p.counters.scaleDowns.Add(1)
+
+	poolLogger(w).Info("scale-down: worker removed", "reason", reason, "workers", count, "max_workers", max)
+	p.events.Record(EventScaleDown, "worker_id", w.ID, "port", w.Port, "workers", count, "reason", reason)
+	if count == 0 {
+		p.logger().Info("pool scaled to zero — the next create cold-starts a worker")
+	}
+}
+
+// maxFailedWorkers bounds the failed-worker history kept for /status.
+const maxFailedWorkers = 10
+
+// FailedWorker records a worker that was removed after crash-looping or
+// restarting too often.
+type FailedWorker struct {
+	ID        int
+	Port      int
+	Reason    string // failedCrashLoop or failedRestartRate
+	Attempts  int
+	LastError string
+	FailedAt  time.Time
+}
+
+// Why replaceFailed gave up on a worker.
+const (
+	failedCrashLoop   = "crash loop"
+	failedRestartRate = "restart rate exceeded"
+)
+
+// replaceFailed gives up on a worker that failed attempts times in a row, or
+// restarted too often: it leaves the pool for good, is remembered in
+// FailedWorkers, and a fresh worker (new ID, new port, clean profile) is
+// started in its place.
+func (p *Pool) replaceFailed(w *Worker, attempts int, reason string) {
+	w.Drain()
+	w.SetState(WorkerStateFailed)
+	_, lastErr := w.Failures()
+	count := p.forget(w)
+
+	p.counters.failed.Add(1)
+	p.mu.Lock()
+	p.failed = append(p.failed, FailedWorker{ID: w.ID, Port: w.Port, Reason: reason, Attempts: attempts, LastError: lastErr, FailedAt: p.clk.Now()})
+	if len(p.failed) > maxFailedWorkers {
+		p.failed = slices.Delete(p.failed, 0, len(p.failed)-maxFailedWorkers)
 	}
+	p.mu.Unlock()
+
+	poolLogger(w).Error(reason+" — worker removed, starting a replacement", "attempts", attempts, "last_error", lastErr, "workers", count)
+	p.events.Record(EventWorkerFailed, "worker_id", w.ID, "port", w.Port, "reason", reason, "attempts", attempts, "error", lastErr, "workers", count)
+	p.audit.Record(AuditWorkerFail, w, reason, "attempts", attempts, "error", lastErr, "workers", count)
+	p.scaleUp(1, "replace failed worker")
+}
+
+// FailedWorkers returns the most recent workers removed for crash-looping,
+// oldest first.
+func (p *Pool) FailedWorkers() []FailedWorker {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return slices.Clone(p.failed)
 }
 
 // healthCheckLoop periodically checks worker health and restarts unhealthy ones.
+// Sweeps are jittered by loopJitter, and the probes within a sweep are spaced
+// evenly over healthStagger of the interval rather than fired back to back.
+// Probes run concurrently, one per worker so a few hung ones cannot delay
+// the rest, and the sweep must finish within the interval: a probe still
+// pending then is cut short and counts as failed. Since probes start within
+// the first half of the interval, each gets at least half of it (or its
+// own 2 s timeout). Failing workers are killed, or quarantined (see
+// quarantineWorker), only once the whole sweep has reported.
 func (p *Pool) healthCheckLoop() {
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
+	for {
+		interval := time.Duration(p.healthInterval.Load())
+		<-p.clk.After(jittered(interval, loopJitter))
 
-	for range ticker.C {
 		p.mu.RLock()
 		workers := make([]*Worker, len(p.workers))
 		copy(workers, p.workers)
 		p.mu.RUnlock()
 
-		for _, w := range workers {
-			state := w.State()
-			if state == WorkerStateDead || state == WorkerStateStarting {
+		p.logger().Debug("health check sweep", "workers", len(workers))
+		var gap time.Duration
+		if len(workers) > 1 {
+			gap = time.Duration(float64(interval) * healthStagger / float64(len(workers)))
+		}
+		sampleCPU(workers)
+		workers = p.enforceMemory(workers)
+		p.sweeps++
+		deep := p.deep.every > 0 && p.sweeps%p.deep.every == 0
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		failures := p.probeWorkers(ctx, workers, gap, deep)
+		cancel()
+		for _, f := range failures {
+			log := poolLogger(f.w).With("state", f.state.String(), "failure", f.probe.Failure, "streak", f.probe.Streak)
+			switch fatal := f.probe.fatal(); {
+			case !fatal && p.quarantine.timeout > 0 && (f.w.Load() == 0 || f.probe.Streak >= p.healthFailures):
+				p.quarantineWorker(f.w, f.probe)
+			case !fatal && f.probe.Streak < p.healthFailures:
+				log.Warn("failed health check", "health_failures", p.healthFailures)
+			default:
+				log.Warn("failed health check — killing")
+				f.w.Kill("failed health check") // monitor goroutine will handle restart
+			}
+		}
+		if p.maxAge > 0 && len(failures) == 0 {
+			p.rotateAged(workers)
+		}
+		p.swept.Store(&ConditionSnapshot{PoolCondition: p.Condition(), At: p.clk.Now()})
+
+		if p.OnHealthSweep != nil {
+			p.OnHealthSweep()
+		}
+	}
+}
+
+// sampleCPU refreshes the CPU usage of every running worker. It is purely
+// observational: the figures are reported in /status and /metrics.
+func sampleCPU(workers []*Worker) {
+	if !procSampling {
+		return
+	}
+	for _, w := range workers {
+		if s := w.State(); s == WorkerStateDead || s == WorkerStateStarting {
+			continue
+		}
+		if err := w.sampleCPU(); err != nil {
+			poolLogger(w).Debug("could not sample CPU", "error", err)
+		}
+	}
+}
+
+// enforceMemory samples every running worker's RSS, kills those above
+// memoryLimit and returns the rest for probing. A worker killed holding a
+// session is reported to OnCrash as exitMemoryLimit, so its session answers
+// 410 rather than vanishing.
+func (p *Pool) enforceMemory(workers []*Worker) []*Worker {
+	if !procSampling {
+		return workers
+	}
+	kept := make([]*Worker, 0, len(workers))
+	for _, w := range workers {
+		if p.overMemory(w) {
+			w.killForMemory() // monitor goroutine will handle restart
+			continue
+		}
+		kept = append(kept, w)
+	}
+	return kept
+}
+
+// overMemory samples w's RSS and reports whether it exceeds memoryLimit.
+// Dead and starting workers are not sampled.
+func (p *Pool) overMemory(w *Worker) bool {
+	if s := w.State(); s == WorkerStateDead || s == WorkerStateStarting {
+		return false
+	}
+	rss, err := w.sampleRSS()
+	if err != nil {
+		poolLogger(w).Debug("could not sample RSS", "error", err)
+		return false
+	}
+	if p.memoryLimit == 0 || rss <= p.memoryLimit {
+		return false
+	}
+	poolLogger(w).Warn("over memory limit — killing", "rss_bytes", rss, "limit_bytes", p.memoryLimit, "session_ids", w.Sessions())
+	return true
+}
+
+// rotateAged restarts the oldest idle worker that has outlived maxAge and
+// marks older workers holding sessions to retire once those end. At most one
+// worker is rotated per sweep, and none while any worker is booting, dead or
+// unhealthy, so the pool never restarts more than one worker at a time.
+func (p *Pool) rotateAged(workers []*Worker) {
+	if !settled(workers) {
+		return
+	}
+	now := p.clk.Now()
+	workers = slices.Clone(workers)
+	slices.SortFunc(workers, func(a, b *Worker) int { return a.StartedAt().Compare(b.StartedAt()) })
+	for _, w := range workers {
+		age := now.Sub(w.StartedAt())
+		if age < p.maxAge {
+			return // the rest are younger
+		}
+		switch s := w.State(); {
+		case s == WorkerStateBusy || s == WorkerStateAvailable && w.Load() > 0:
+			if w.markRetiring(retireMaxAge) {
+				poolLogger(w).Info("past max age — retiring after its sessions", "age", age.Round(time.Second))
+				if s == WorkerStateAvailable && p.available.remove(w) {
+					w.clearQueued() // takes no new sessions
+				}
+			}
+		case s == WorkerStateAvailable:
+			if !p.available.remove(w) {
+				continue // taken by Acquire since the snapshot
+			}
+			w.clearQueued()
+			if w.Load() > 0 {
+				p.Release(w) // claimed since the snapshot; caught next sweep
 				continue
 			}
+			p.rotate(w, retireMaxAge)
+			return
+		}
+	}
+}
 
-			if !w.HealthCheck() {
-				log.Printf("[pool] :%-5d failed health check (state=%s) — killing", w.Port, state)
-				w.Kill() // monitor goroutine will handle restart
+// Reasons a worker is retired and restarted once idle.
+const (
+	retireMaxAge         = "max worker age"
+	retireRollingRestart = "rolling restart"
+)
+
+// rotate restarts an idle worker that has outlived maxAge or is due in a
+// rolling restart. The kill counts as a recycle, so monitor restarts it on
+// the same port.
+func (p *Pool) rotate(w *Worker, reason string) {
+	age := p.clk.Since(w.StartedAt()).Round(time.Second)
+	if reason == retireMaxAge {
+		poolLogger(w).Info("rotating worker past max age", "age", age, "max_worker_age", p.maxAge)
+	} else {
+		poolLogger(w).Info("restarting retired worker", "reason", reason, "age", age)
+	}
+	w.Kill(reason)
+}
+
+// settled reports whether every worker is available or busy, i.e. none is
+// booting, restarting or unhealthy.
+func settled(workers []*Worker) bool {
+	for _, w := range workers {
+		if s := w.State(); s != WorkerStateAvailable && s != WorkerStateBusy {
+			return false
+		}
+	}
+	return true
+}
+
+// healthFailure is a worker that failed its probe, with the state it was in
+// when probed.
+type healthFailure struct {
+	w     *Worker
+	state WorkerState
+	probe probeResult
+}
+
+// probeWorkers health-checks workers concurrently, starting one probe every
+// gap, and cuts short any probe still running when ctx is done. Dead,
+// starting and quarantined workers are skipped, as are workers that became
+// ready less than healthGrace ago and may still be settling. With deep set,
+// each worker that passes is also deep-checked. It returns the failures once
+// every probe has finished.
+func (p *Pool) probeWorkers(ctx context.Context, workers []*Worker, gap time.Duration, deep bool) []healthFailure {
+	var (
+		mu       sync.Mutex
+		failures []healthFailure
+		wg       sync.WaitGroup
+	)
+	for i, w := range workers {
+		if i > 0 {
+			p.clk.Sleep(gap)
+		}
+		if ctx.Err() != nil {
+			p.logger().Warn("health sweep out of time — remaining workers not probed", "unprobed", len(workers)-i)
+			break
+		}
+		state := w.State()
+		if state == WorkerStateDead || state == WorkerStateStarting || !w.QuarantinedAt().IsZero() {
+			continue // quarantined workers are probed by watchQuarantine
+		}
+		if p.healthGrace > 0 && p.clk.Since(w.ReadyAt()) < p.healthGrace {
+			poolLogger(w).Debug("health check skipped — within grace period", "ready_for", p.clk.Since(w.ReadyAt()).String())
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := w.healthCheck(ctx)
+			if res.Healthy && deep {
+				res = p.deepCheck(ctx, w)
 			}
+			if !res.Healthy {
+				mu.Lock()
+				failures = append(failures, healthFailure{w, state, res})
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return failures
+}
+
+// deepCheck runs w's deep check and counts its failures.
+func (p *Pool) deepCheck(ctx context.Context, w *Worker) probeResult {
+	res := w.deepCheck(ctx, p.deep.path)
+	if !res.Healthy {
+		p.deepFailures.Add(1)
+	}
+	return res
+}
+
+// jittered returns d scaled by a uniform random factor in [1-frac, 1+frac].
+// math/rand/v2's global source is seeded once per process.
+func jittered(d time.Duration, frac float64) time.Duration {
+	return time.Duration(float64(d) * (1 - frac + 2*frac*rand.Float64()))
+}
+
+// Adopt takes over a worker process left running by a previous orchestrator
+// (see --state-file) that still holds sessionIDs. The process must be alive
+// and pass the health probe on port; it joins the pool busy, or available if
+// it has session slots to spare, and is watched by polling since it is not
+// our child. When its sessions end it is released like any other worker.
+func (p *Pool) Adopt(sessionIDs []string, port, pid int) (*Worker, error) {
+	proc, err := os.FindProcess(pid)
+	if err != nil || !processAlive(proc) {
+		return nil, fmt.Errorf("process %d is gone", pid)
+	}
+
+	p.mu.Lock()
+	id := p.nextID
+	p.nextID++
+	p.mu.Unlock()
+
+	w := NewWorker(id, port, p.launch, p)
+	if !w.HealthCheck() {
+		return nil, fmt.Errorf("worker on port %d failed its health probe", port)
+	}
+	w.proc = proc
+	w.exited = make(chan struct{})
+	w.startedAt = p.clk.Now()
+	w.state = WorkerStateBusy
+	w.sessions = slices.Clone(sessionIDs)
+	if len(w.sessions) < w.capacity {
+		w.state = WorkerStateAvailable
+	}
+	if len(w.sessions) == 0 {
+		w.idleSince = w.startedAt
+	}
+	if p.CrashHandler != nil {
+		w.OnCrash = p.CrashHandler
+	}
+
+	p.ports.claim(port)
+	p.mu.Lock()
+	p.workers = append(p.workers, w)
+	count := len(p.workers)
+	p.mu.Unlock()
+
+	go w.watchAdopted()
+
+	poolLogger(w).Info("adopted worker from previous run", "pid", pid, "session_ids", sessionIDs, "workers", count)
+	p.events.Record(EventWorkerAdopted, "worker_id", w.ID, "port", w.Port, "pid", pid, "session_ids", sessionIDs)
+	p.audit.Record(AuditWorkerAdopt, w, "restored from state file", "pid", pid, "session_ids", sessionIDs)
+	if w.State() == WorkerStateAvailable {
+		p.Release(w)
+	}
+	return w, nil
+}
+
+// ShutdownKeepingSessions stops idle workers like Shutdown but detaches the
+// ones holding a session, leaving them running for the next orchestrator to
+// adopt.
+func (p *Pool) ShutdownKeepingSessions(ctx context.Context) error {
+	var stopping []*Worker
+	for _, w := range p.beginShutdown() {
+		if len(w.Sessions()) > 0 {
+			w.Detach()
+			continue
 		}
+		stopping = append(stopping, w)
 	}
+	err := p.stopWorkers(ctx, stopping)
+	p.logger().Info("workers shut down; busy workers left running for adoption", "stopped", len(stopping))
+	return err
+}
+
+// Shutdown stops all workers concurrently and waits for their processes to
+// exit. Each is stopped intentionally so monitor() goroutines do not attempt
+// a restart. The error lists the workers that did not exit cleanly: those
+// that needed SIGKILL, and those still running when ctx ended.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	workers := p.beginShutdown()
+	err := p.stopWorkers(ctx, workers)
+	p.logger().Info("all workers stopped", "workers", len(workers))
+	return err
 }
 
-// Shutdown kills all workers. Workers are drained first so monitor()
-// goroutines do not attempt a restart after the process exits.
-func (p *Pool) Shutdown() {
+// beginShutdown stops the pool from starting any more workers and returns a
+// snapshot of the current ones. The caller stops them without holding p.mu,
+// so a Kill that hangs cannot also wedge /status or Acquire.
+func (p *Pool) beginShutdown() []*Worker {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	p.closing = true
+	return slices.Clone(p.workers)
+}
 
-	for _, w := range p.workers {
-		w.Drain()
-		w.Kill()
+// stopWorkers stops every worker in parallel, see stopWorker.
+func (p *Pool) stopWorkers(ctx context.Context, workers []*Worker) error {
+	errs := make([]error, len(workers))
+	var wg sync.WaitGroup
+	for i, w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = p.stopWorker(ctx, w)
+		}()
+	}
+	wg.Wait()
+
+	var unclean []string
+	for _, err := range errs {
+		if err != nil {
+			unclean = append(unclean, err.Error())
+		}
+	}
+	if len(unclean) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d workers did not exit cleanly: %s", len(unclean), len(workers), strings.Join(unclean, "; "))
+}
+
+// stopWorker sends w SIGTERM and waits up to stopTimeout for its process to
+// exit, then SIGKILLs it and waits for the rest of ctx. With no stop timeout
+// it is killed at once, as on scale-down.
+func (p *Pool) stopWorker(ctx context.Context, w *Worker) error {
+	done := w.Done()
+	if p.stopTimeout <= 0 {
+		w.Stop("shutdown")
+	} else {
+		w.Terminate("shutdown")
+		select {
+		case <-done:
+			return nil
+		case <-p.clk.After(p.stopTimeout):
+		case <-ctx.Done():
+		}
+		poolLogger(w).Warn("did not exit after SIGTERM — killing", "pid", w.PID(), "stop_timeout", p.stopTimeout.String())
+		w.Stop("shutdown: no exit after SIGTERM")
+	}
+
+	select {
+	case <-done:
+		if p.stopTimeout > 0 {
+			return fmt.Errorf("worker %d (pid %d) needed SIGKILL", w.ID, w.PID())
+		}
+		return nil
+	case <-ctx.Done():
+	}
+	select {
+	case <-done:
+		return nil
+	default:
+		return fmt.Errorf("worker %d (pid %d) still running at the shutdown deadline", w.ID, w.PID())
+	}
+}
+
+// logger returns the pool logger, tagged with the pool's tag unless it is
+// the default pool.
+func (p *Pool) logger() *slog.Logger {
+	if p.tag == "" {
+		return logger("pool")
+	}
+	return logger("pool").With("pool", p.tag)
+}
+
+// poolLogger returns the pool logger tagged with the worker's identity.
+func poolLogger(w *Worker) *slog.Logger {
+	if w.pool == nil {
+		return logger("pool").With("worker_id", w.ID, "port", w.Port)
 	}
-	log.Println("[pool] all workers shut down")
+	return w.pool.logger().With("worker_id", w.ID, "port", w.Port)
 }
@@ -0,0 +1,650 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"steel-orchestrator/clock/clocktest"
+)
+
+// testWorkerEnv makes the test binary serve the mock worker API instead of
+// running the tests, so pools under test launch os.Args[0] as their worker.
+const testWorkerEnv = "ORCHESTRATOR_TEST_WORKER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testWorkerEnv) != "" {
+		if err := runMockWorker(); err != nil {
+			fmt.Fprintf(os.Stderr, "mock worker: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// testLaunch launches the test binary as a mock worker; env adds MOCK_*
+// settings.
+func testLaunch(env ...string) LaunchConfig {
+	return LaunchConfig{
+		BinaryPath:   os.Args[0],
+		Env:          append([]string{testWorkerEnv + "=1"}, env...),
+		ReadyTimeout: 5 * time.Second,
+	}
+}
+
+// newTestPool starts a pool of mock workers, waits until its initial
+// workers are queued and shuts it down when the test ends. Health sweeps
+// and scale ticks are an hour apart unless cfg sets them, so only the test
+// drives the pool.
+func newTestPool(t *testing.T, cfg PoolConfig) *Pool {
+	t.Helper()
+	if cfg.Launch.BinaryPath == "" {
+		cfg.Launch = testLaunch()
+	}
+	if cfg.HealthCheckInterval == 0 {
+		cfg.HealthCheckInterval = time.Hour
+	}
+	if cfg.ScaleInterval == 0 {
+		cfg.ScaleInterval = time.Hour
+	}
+	p, err := NewPool(cfg, NewEventLog(defaultEventHistorySize))
+	if err != nil {
+		t.Fatalf("NewPool: %v", err)
+	}
+	t.Cleanup(func() { p.Shutdown(context.Background()) })
+	waitFor(t, "initial workers", func() bool { return p.available.idleLen() == cfg.Min })
+	return p
+}
+
+// waitFor polls cond until it holds, failing the test after 10 s.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSetMaxRaisedKeepsIdleWorkers(t *testing.T) {
+	p := newTestPool(t, PoolConfig{Min: 2, Max: 2})
+	waitFor(t, "2 ready workers", func() bool { return p.ReadyWorkers() == 2 })
+
+	if err := p.SetMax(4); err != nil {
+		t.Fatalf("SetMax(4): %v", err)
+	}
+	if got := len(p.Workers()); got != 2 {
+		t.Fatalf("workers after raising max = %d, want 2", got)
+	}
+	for _, w := range p.Workers() {
+		if w.isDraining() || w.isSurplus() {
+			t.Errorf("worker %d removed or marked surplus after raising max", w.ID)
+		}
+	}
+	if st := p.Stats(); st.ScaleDowns != 0 {
+		t.Errorf("scale_downs = %d, want 0", st.ScaleDowns)
+	}
+	if got := p.Max(); got != 4 {
+		t.Errorf("Max() = %d, want 4", got)
+	}
+}
+
+func TestSetMaxLoweredRemovesIdleWorkers(t *testing.T) {
+	p := newTestPool(t, PoolConfig{Min: 1, Max: 3})
+	p.scaleUp(2, "test")
+	waitFor(t, "3 available workers", func() bool { return p.QueueDepth() == 3 })
+
+	var busy []*Worker
+	for range 2 {
+		w, err := p.Acquire(context.Background(), PriorityNormal)
+		if err != nil {
+			t.Fatalf("Acquire: %v", err)
+		}
+		w.AddSession(fmt.Sprintf("s%d", w.ID))
+		busy = append(busy, w)
+	}
+	if err := p.SetMax(1); err != nil {
+		t.Fatalf("SetMax(1): %v", err)
+	}
+
+	// The idle worker goes at once; of the busy ones, the newest is marked
+	// and goes when its session ends.
+	if got := len(p.Workers()); got != 2 {
+		t.Fatalf("workers after lowering max = %d, want the 2 busy ones", got)
+	}
+	older, newer := busy[0], busy[1]
+	if older.ID > newer.ID {
+		older, newer = newer, older
+	}
+	if !newer.isSurplus() || older.isSurplus() {
+		t.Fatalf("surplus: worker %d = %v, worker %d = %v; want only the newer one",
+			older.ID, older.isSurplus(), newer.ID, newer.isSurplus())
+	}
+	newer.RemoveSession(fmt.Sprintf("s%d", newer.ID))
+	if got := p.Workers(); len(got) != 1 || got[0] != older {
+		t.Errorf("%d workers after the surplus one is released, want only worker %d", len(got), older.ID)
+	}
+}
+
+func TestScaleWindowTransition(t *testing.T) {
+	peak, err := parseScaleWindow("peak=09:00-10:00 min=2 max=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	windows := []ScaleWindow{peak}
+	at := func(hour int) ScaleLimits {
+		return scaleLimitsAt(windows, 0, time.Date(2026, 1, 5, hour, 30, 0, 0, time.Local), 1, 2)
+	}
+	p := newTestPool(t, PoolConfig{Min: 1, Max: 2})
+	p.scaleUp(1, "test")
+	waitFor(t, "2 ready workers", func() bool { return p.ReadyWorkers() == 2 })
+
+	// Opening the window raises the max: no worker may go.
+	p.applyScaleLimits(at(9))
+	if got := p.ScaleLimits(); got.Window != "peak" || got.Min != 2 || got.Max != 4 {
+		t.Fatalf("limits in window = %+v, want peak min 2 max 4", got)
+	}
+	if got := len(p.Workers()); got != 2 {
+		t.Fatalf("workers after the window opened = %d, want 2", got)
+	}
+
+	p.scaleUp(1, "test")
+	waitFor(t, "3 ready workers", func() bool { return p.ReadyWorkers() == 3 })
+
+	// Closing it lowers the max back to 2: the newest idle worker goes.
+	p.applyScaleLimits(at(10))
+	if got := p.ScaleLimits(); got.Window != "" || got.Min != 1 || got.Max != 2 {
+		t.Fatalf("limits after the window = %+v, want base min 1 max 2", got)
+	}
+	workers := p.Workers()
+	if len(workers) != 2 {
+		t.Fatalf("workers after the window closed = %d, want 2", len(workers))
+	}
+	for _, w := range workers {
+		if w.ID == 2 {
+			t.Errorf("newest worker 2 kept, want it removed")
+		}
+	}
+	if st := p.Stats(); st.ScaleDowns != 1 {
+		t.Errorf("scale_downs = %d, want 1", st.ScaleDowns)
+	}
+}
+
+func TestScaleUpStaggersBoots(t *testing.T) {
+	p := newTestPool(t, PoolConfig{
+		Min:      1,
+		Max:      6,
+		MaxBoots: 1,
+		Launch:   testLaunch("MOCK_BOOT_DELAY=100ms"),
+	})
+	logs := captureLogs(t)
+	p.scaleUp(3, "test")
+
+	// The whole batch is reserved at once, but only one boots at a time.
+	p.mu.RLock()
+	reserved := len(p.workers) + p.pendingAdds
+	p.mu.RUnlock()
+	if reserved != 4 {
+		t.Fatalf("workers plus reserved slots = %d, want 4", reserved)
+	}
+	waitFor(t, "4 ready workers", func() bool {
+		starting := 0
+		for _, w := range p.Workers() {
+			if w.State() == WorkerStateStarting {
+				starting++
+			}
+		}
+		if starting > 1 {
+			t.Fatalf("%d workers starting at once, want at most 1", starting)
+		}
+		return p.ReadyWorkers() == 4
+	})
+	if got := p.Capacity(6).BootWaves; got != 2 {
+		t.Errorf("boot waves for 2 new workers = %d, want 2", got)
+	}
+
+	// Each worker logs its boot as it gives the slot back; the last one
+	// waited for two boots ahead of it.
+	var boots []map[string]any
+	waitFor(t, "3 boot logs", func() bool {
+		boots = boots[:0]
+		for _, rec := range logs.records() {
+			if rec["msg"] == "scale-up: boot finished" {
+				boots = append(boots, rec)
+			}
+		}
+		return len(boots) == 3
+	})
+	var longest time.Duration
+	for _, rec := range boots {
+		d, err := time.ParseDuration(rec["slot_wait"].(string))
+		if err != nil || rec["state"] != WorkerStateAvailable.String() {
+			t.Errorf("boot log = %v, want an Available worker and its slot wait", rec)
+		}
+		longest = max(longest, d)
+	}
+	if longest < 200*time.Millisecond {
+		t.Errorf("longest slot wait = %s, want at least 200ms", longest)
+	}
+}
+
+func TestIdleScaleDownOnFakeClock(t *testing.T) {
+	clk := clocktest.NewFake(time.Now())
+	p := newTestPool(t, PoolConfig{
+		Min:           1,
+		Max:           3,
+		Clock:         clk,
+		ScaleInterval: 10 * time.Second,
+		Policy:        &idlePolicy{after: 30 * time.Second},
+	})
+	p.scaleUp(2, "test")
+	waitFor(t, "3 ready workers", func() bool { return p.ReadyWorkers() == 3 })
+
+	// Each Advance fires one scale tick; BlockUntil waits for the scale and
+	// health loops to be waiting again, i.e. for the tick to be over.
+	clk.BlockUntil(2)
+	for range 2 {
+		clk.Advance(11 * time.Second)
+		clk.BlockUntil(2)
+	}
+	if got := len(p.Workers()); got != 3 {
+		t.Fatalf("workers after 22s idle = %d, want 3 (scale-down after 30s)", got)
+	}
+
+	clk.Advance(11 * time.Second)
+	clk.BlockUntil(2)
+	if got := len(p.Workers()); got != 1 {
+		t.Fatalf("workers after 33s idle = %d, want min-workers 1", got)
+	}
+	if st := p.Stats(); st.ScaleDowns != 2 {
+		t.Errorf("scale_downs = %d, want 2", st.ScaleDowns)
+	}
+}
+
+func TestScaleDownSkipsWorkerWithSession(t *testing.T) {
+	p := newTestPool(t, PoolConfig{Min: 1, Max: 1, SessionsPerWorker: 2, Launch: testLaunch("MOCK_MAX_SESSIONS=2")})
+
+	// A worker holding a session with a slot to spare stays in the queue.
+	w, err := p.Acquire(context.Background(), PriorityNormal)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	w.AddSession("s1")
+
+	p.removeIdleWorker(0)
+	if got := len(p.Workers()); got != 1 {
+		t.Fatalf("workers after scale-down = %d, want 1", got)
+	}
+	if w.isDraining() || w.State() != WorkerStateAvailable || !w.HasSession("s1") {
+		t.Errorf("worker is %s holding %v, draining %v; want Available holding s1", w.State(), w.Sessions(), w.isDraining())
+	}
+	if !w.HealthCheck() {
+		t.Errorf("worker holding a session was killed")
+	}
+	// Once its session ends both slots are free again, and it is queued once.
+	w.RemoveSession("s1")
+	if got, idle := p.QueueDepth(), p.available.idleLen(); got != 2 || idle != 1 {
+		t.Errorf("after the session ends: %d free slots on %d queued workers, want 2 on 1", got, idle)
+	}
+}
+
+func TestHealthProbesRunConcurrently(t *testing.T) {
+	p := newTestPool(t, PoolConfig{Min: 4, Max: 4})
+
+	// Freeze two workers: their probes hang until the 2 s probe timeout.
+	workers := p.Workers()
+	hung := workers[:2]
+	for _, w := range hung {
+		w.mu.Lock()
+		proc := w.cmd.Process
+		w.mu.Unlock()
+		if err := proc.Signal(syscall.SIGSTOP); err != nil {
+			t.Fatalf("SIGSTOP worker %d: %v", w.ID, err)
+		}
+		t.Cleanup(func() { proc.Signal(syscall.SIGCONT) })
+	}
+
+	start := time.Now()
+	failures := p.probeWorkers(context.Background(), workers, 0, false)
+	if took := time.Since(start); took >= 4*time.Second {
+		t.Errorf("sweep took %s, want under 4s: the hung probes ran one after another", took)
+	}
+	if len(failures) != len(hung) {
+		t.Fatalf("failures = %d, want %d", len(failures), len(hung))
+	}
+	for _, f := range failures {
+		if !slices.Contains(hung, f.w) {
+			t.Errorf("healthy worker %d reported as failed", f.w.ID)
+		}
+	}
+}
+
+func TestAcquireBurstScalesUpInOneBatch(t *testing.T) {
+	const callers, max = 8, 5
+	p := newTestPool(t, PoolConfig{Min: 1, Max: max})
+	logs := captureLogs(t)
+	p.available.remove(p.Workers()[0]) // the only worker is taken; every caller has to wait
+
+	// Hold the queue lock: callers count themselves in waiting, then stop
+	// at its idle check, so all of them are inside Acquire before any of
+	// them can size a batch.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	p.available.mu.Lock()
+	unlock := sync.OnceFunc(p.available.mu.Unlock)
+	defer unlock()
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Acquire(ctx, PriorityNormal)
+		}()
+	}
+	waitFor(t, "callers to queue", func() bool { return p.waiting.Load() == callers })
+	unlock()
+
+	waitFor(t, "scale-up to max-workers", func() bool { return p.QueueDepth() == 0 && len(p.Workers()) == max })
+	cancel()
+	wg.Wait()
+
+	var batches []int
+	for _, rec := range logs.records() {
+		if rec["msg"] == "scaling up" {
+			batches = append(batches, int(rec["batch"].(float64)))
+		}
+	}
+	if want := min(callers, max-1); len(batches) != 1 || batches[0] != want {
+		t.Errorf("scale-up batches = %v, want one batch of %d", batches, want)
+	}
+}
+
+func TestNoScaleDownWhileCallersWait(t *testing.T) {
+	policy := &idlePolicy{after: time.Second}
+	st := PoolStats{Workers: 3, Available: 2, IdleFor: []time.Duration{time.Minute, time.Minute}, Waiting: 1, Min: 1, Max: 3}
+	if n := policy.Decide(st); n != 0 {
+		t.Errorf("idle policy with a caller waiting = %d, want 0", n)
+	}
+	st.Waiting = 0
+	if n := policy.Decide(st); n != -2 {
+		t.Errorf("idle policy with nobody waiting = %d, want -2", n)
+	}
+
+	p := newTestPool(t, PoolConfig{Min: 1, Max: 2})
+	p.scaleUp(1, "test")
+	waitFor(t, "2 ready workers", func() bool { return p.ReadyWorkers() == 2 })
+
+	// A caller that arrives between the tick and the removal gets the
+	// worker instead: it goes back to the queue.
+	p.waiting.Add(1)
+	p.removeIdleWorker(0)
+	p.waiting.Add(-1)
+	if got := len(p.Workers()); got != 2 {
+		t.Fatalf("workers = %d, want 2", got)
+	}
+	if idle := p.available.idleLen(); idle != 2 {
+		t.Errorf("idle workers = %d, want both back in the queue", idle)
+	}
+	if st := p.Stats(); st.ScaleDowns != 0 {
+		t.Errorf("scale_downs = %d, want 0", st.ScaleDowns)
+	}
+}
+
+func TestAcquireServesCallersInArrivalOrder(t *testing.T) {
+	const callers = 10
+	p := newTestPool(t, PoolConfig{Min: 1, Max: 1})
+	w, err := p.Acquire(context.Background(), PriorityNormal)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	served := make(chan int)
+	for i := range callers {
+		go func() {
+			got, err := p.Acquire(context.Background(), PriorityNormal)
+			if err != nil {
+				t.Errorf("caller %d: %v", i, err)
+				return
+			}
+			if got != w {
+				t.Errorf("caller %d got worker %d, want %d", i, got.ID, w.ID)
+			}
+			served <- i
+		}()
+		waitFor(t, "caller to queue", func() bool { return p.waiting.Load() == int64(i+1) })
+	}
+
+	// Hand the one worker round: each release goes to the longest waiter.
+	for want := range callers {
+		w.Unclaim()
+		select {
+		case got := <-served:
+			if got != want {
+				t.Fatalf("release %d went to caller %d, want %d", want, got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("release %d reached no caller", want)
+		}
+	}
+}
+
+func TestAcquireSkipsQueuedWorkerNoLongerAvailable(t *testing.T) {
+	p := newTestPool(t, PoolConfig{Min: 2, Max: 2})
+
+	// Turn the worker at the head of the queue unhealthy, as a failed
+	// health check does, without taking it out.
+	p.available.mu.Lock()
+	bad, healthy := p.available.idle[0], p.available.idle[1]
+	p.available.mu.Unlock()
+	bad.mu.Lock()
+	bad.state = WorkerStateUnhealthy
+	bad.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	w, err := p.Acquire(ctx, PriorityNormal)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if w != healthy {
+		t.Fatalf("Acquire returned worker %d (%s), want the healthy worker %d", w.ID, w.State(), healthy.ID)
+	}
+	if idle := p.available.idleLen(); idle != 0 {
+		t.Errorf("idle workers = %d, want unhealthy worker %d dropped from the queue", idle, bad.ID)
+	}
+}
+
+func TestRemovedWorkerStaysOut(t *testing.T) {
+	p := newTestPool(t, PoolConfig{Min: 1, Max: 2})
+	p.scaleUp(1, "test")
+	waitFor(t, "2 available workers", func() bool { return p.QueueDepth() == 2 })
+
+	before := p.Workers()
+	p.removeIdleWorker(0)
+	if got := len(p.Workers()); got != 1 {
+		t.Fatalf("workers after scale-down = %d, want 1", got)
+	}
+	var removed *Worker
+	for _, w := range before {
+		if !slices.Contains(p.Workers(), w) {
+			removed = w
+		}
+	}
+	waitFor(t, "removed worker to exit", func() bool { return removed.State() == WorkerStateDead })
+
+	// A late release, e.g. from a DELETE racing the scale-down, and a late
+	// restart must both leave it out.
+	p.Release(removed)
+	if err := removed.Start(); err == nil {
+		t.Fatal("Start on a removed worker succeeded")
+	}
+	if idle, workers := p.available.idleLen(), len(p.Workers()); idle != workers {
+		t.Errorf("idle workers = %d, want %d, one per worker", idle, workers)
+	}
+	for _, w := range p.Workers() {
+		if w.Port == removed.Port {
+			t.Errorf("removed worker's port %d is back in the pool as worker %d", removed.Port, w.ID)
+		}
+	}
+	w, err := p.Acquire(context.Background(), PriorityNormal)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if w == removed {
+		t.Errorf("Acquire handed out removed worker %d", w.ID)
+	}
+}
+
+func TestReleaseUnderContentionLeaksNoWorker(t *testing.T) {
+	const (
+		workers = 4
+		callers = 16
+		rounds  = 50
+	)
+	p := newTestPool(t, PoolConfig{Min: workers, Max: workers})
+
+	var (
+		mu   sync.Mutex
+		held = map[*Worker]int{} // worker -> caller holding it
+		wg   sync.WaitGroup
+	)
+	for c := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range rounds {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				w, err := p.Acquire(ctx, PriorityNormal)
+				cancel()
+				if err != nil {
+					t.Errorf("caller %d: Acquire: %v", c, err)
+					return
+				}
+				mu.Lock()
+				if other, ok := held[w]; ok {
+					t.Errorf("worker %d handed to caller %d while caller %d holds it", w.ID, c, other)
+				}
+				held[w] = c
+				mu.Unlock()
+
+				id := fmt.Sprintf("s%d-%d", c, r)
+				w.AddSession(id)
+				time.Sleep(time.Millisecond) // the session's lifetime
+				mu.Lock()
+				delete(held, w)
+				mu.Unlock()
+				w.RemoveSession(id)
+				// Stale frees, by when another caller likely holds the
+				// worker: a second delete and a late release must not
+				// queue it again.
+				time.Sleep(time.Millisecond / 2)
+				w.RemoveSession(id)
+				p.Release(w)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if idle := p.available.idleLen(); idle != workers {
+		t.Errorf("idle workers = %d, want %d", idle, workers)
+	}
+	for _, w := range p.Workers() {
+		if w.State() != WorkerStateAvailable || w.Load() != 0 {
+			t.Errorf("worker %d is %s with load %d, want Available and empty", w.ID, w.State(), w.Load())
+		}
+	}
+}
+
+func TestStaleReadinessWaitLeavesRestartedWorker(t *testing.T) {
+	launch := testLaunch("MOCK_BOOT_DELAY=900ms")
+	launch.ReadyTimeout = 1500 * time.Millisecond
+	clk := clocktest.NewFake(time.Now())
+	p := newTestPool(t, PoolConfig{Min: 1, Max: 1, Launch: launch, Clock: clk})
+	w := p.Workers()[0]
+
+	// Restart delays sleep on the fake clock: once monitor is asleep next
+	// to the scale and health loops, skip the delay.
+	restart := func() {
+		clk.BlockUntil(3)
+		clk.Advance(time.Minute)
+	}
+
+	// Kill the worker, then kill its replacement 650 ms into its boot. The
+	// first replacement's waitForReady times out 1.5 s after it started,
+	// while the second is still booting: a stale wait must not kill it.
+	pid := w.PID()
+	w.Kill("test")
+	restart()
+	waitFor(t, "worker to restart", func() bool { return w.State() == WorkerStateStarting && w.PID() != pid })
+	pid = w.PID()
+	time.Sleep(650 * time.Millisecond)
+	w.Kill("test")
+	restart()
+	waitFor(t, "worker to restart again", func() bool { return w.State() == WorkerStateStarting && w.PID() != pid })
+	pid = w.PID()
+
+	waitFor(t, "worker to be ready", func() bool { return w.State() == WorkerStateAvailable })
+	time.Sleep(launch.ReadyTimeout)
+	if w.State() != WorkerStateAvailable || w.PID() != pid {
+		t.Fatalf("worker is %s with pid %d, want Available on pid %d", w.State(), w.PID(), pid)
+	}
+	if failures, restarts := p.ReadinessFailures(), w.Restarts(); failures != 0 || restarts != 2 {
+		t.Errorf("readiness failures = %d, restarts = %d; want 0 and 2", failures, restarts)
+	}
+	if idle := p.available.idleLen(); idle != 1 {
+		t.Errorf("idle workers = %d, want the worker queued once", idle)
+	}
+	got, err := p.Acquire(context.Background(), PriorityNormal)
+	if err != nil || got != w {
+		t.Fatalf("Acquire = %v, %v; want worker %d", got, err, w.ID)
+	}
+	if idle := p.available.idleLen(); idle != 0 {
+		t.Errorf("idle workers after Acquire = %d, want 0", idle)
+	}
+}
+
+// logCapture collects the JSON records logged while a test runs.
+type logCapture struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// captureLogs sends the default logger to a logCapture until the test ends.
+func captureLogs(t *testing.T) *logCapture {
+	t.Helper()
+	c := &logCapture{}
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(c, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return c
+}
+
+func (c *logCapture) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.Write(b)
+}
+
+func (c *logCapture) records() []map[string]any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var recs []map[string]any
+	for _, line := range bytes.Split(c.buf.Bytes(), []byte("\n")) {
+		var rec map[string]any
+		if json.Unmarshal(line, &rec) == nil {
+			recs = append(recs, rec)
+		}
+	}
+	return recs
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultPoolTag names the pool built from --min-workers, --max-workers and
+// --binary. Creates that do not ask for a pool go there.
+const defaultPoolTag = "default"
+
+// WorkerPoolSpec is one --worker-pool entry: an extra set of workers with its
+// own tag, size limits and, optionally, binary.
+type WorkerPoolSpec struct {
+	Tag    string
+	Min    int
+	Max    int
+	Binary string   // "" = --binary
+	Env    []string // KEY=VALUE from --worker-pool-env, added to --worker-env
+}
+
+// parseWorkerPool parses "tag:min:max[:binary]".
+func parseWorkerPool(s string) (WorkerPoolSpec, error) {
+	parts := strings.SplitN(s, ":", 4)
+	if len(parts) < 3 {
+		return WorkerPoolSpec{}, fmt.Errorf("worker-pool must be tag:min:max[:binary], got %q", s)
+	}
+	spec := WorkerPoolSpec{Tag: parts[0]}
+	if len(parts) == 4 {
+		spec.Binary = parts[3]
+	}
+	var err1, err2 error
+	spec.Min, err1 = strconv.Atoi(parts[1])
+	spec.Max, err2 = strconv.Atoi(parts[2])
+	switch {
+	case spec.Tag == "" || spec.Tag == defaultPoolTag:
+		return WorkerPoolSpec{}, fmt.Errorf("worker-pool %q: tag must be set and not %q", s, defaultPoolTag)
+	case err1 != nil || err2 != nil || spec.Min < 0 || spec.Max < 1 || spec.Min > spec.Max || spec.Max > maxPoolCapacity:
+		return WorkerPoolSpec{}, fmt.Errorf("worker-pool %q: need 0 <= min <= max, 1 <= max <= %d", s, maxPoolCapacity)
+	}
+	return spec, nil
+}
+
+// poolConfig derives the tagged pool's config from the default pool's: same
+// health, scaling and limits, but its own size, binary and scale policy, and
+// no memory budget or scale windows. Its workers also get the spec's extra env and
+// WORKER_POOL=<tag>.
+func (spec WorkerPoolSpec) poolConfig(base PoolConfig, cfg *Config) PoolConfig {
+	pc := base
+	pc.Tag, pc.Min, pc.Max = spec.Tag, spec.Min, spec.Max
+	pc.StartQuorum = 0
+	pc.MemoryBudget = 0
+	pc.ScaleWindows = nil
+	if spec.Binary != "" {
+		pc.Launch.BinaryPath = spec.Binary
+	}
+	pc.Launch.Env = append(slices.Concat(base.Launch.Env, spec.Env), "WORKER_POOL="+spec.Tag)
+	// Scale policies keep per-pool state, so each pool gets its own.
+	pc.Policy, _ = newScalePolicy(cfg.ScalePolicy, time.Duration(cfg.ScaleDownAfter), time.Duration(cfg.ScaleTargetWait), cfg.ScaleDownUtilization)
+	return pc
+}
+
+// PoolSet is the default pool plus any tagged pools (--worker-pool). Each
+// pool scales, health-checks and queues independently; the set only routes
+// creates to the right one and fans out lookups and shutdown.
+type PoolSet struct {
+	def    *Pool
+	tagged map[string]*Pool
+}
+
+func newPoolSet(def *Pool) *PoolSet {
+	return &PoolSet{def: def, tagged: make(map[string]*Pool)}
+}
+
+// add registers a tagged pool. Tags are validated by parseWorkerPool.
+func (s *PoolSet) add(p *Pool) {
+	s.tagged[p.Tag()] = p
+}
+
+// Default returns the default pool.
+func (s *PoolSet) Default() *Pool { return s.def }
+
+// Get returns the pool for tag; "" and "default" mean the default pool.
+func (s *PoolSet) Get(tag string) (*Pool, bool) {
+	if tag == "" || tag == defaultPoolTag {
+		return s.def, true
+	}
+	p, ok := s.tagged[tag]
+	return p, ok
+}
+
+// All returns every pool, the default first and the rest ordered by tag.
+func (s *PoolSet) All() []*Pool {
+	tags := make([]string, 0, len(s.tagged))
+	for tag := range s.tagged {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+	all := []*Pool{s.def}
+	for _, tag := range tags {
+		all = append(all, s.tagged[tag])
+	}
+	return all
+}
+
+// Workers returns the workers of every pool, in All order.
+func (s *PoolSet) Workers() []*Worker {
+	var ws []*Worker
+	for _, p := range s.All() {
+		ws = append(ws, p.Workers()...)
+	}
+	return ws
+}
+
+// FindBySession returns the worker, in any pool, that holds sessionID.
+func (s *PoolSet) FindBySession(sessionID string) (*Worker, bool) {
+	for _, p := range s.All() {
+		if w, ok := p.FindBySession(sessionID); ok {
+			return w, true
+		}
+	}
+	return nil, false
+}
+
+// Shutdown shuts every pool down in parallel, see Pool.Shutdown and
+// Pool.ShutdownKeepingSessions.
+func (s *PoolSet) Shutdown(ctx context.Context, keepSessions bool) error {
+	pools := s.All()
+	errs := make([]error, len(pools))
+	var wg sync.WaitGroup
+	for i, p := range pools {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			if keepSessions {
+				err = p.ShutdownKeepingSessions(ctx)
+			} else {
+				err = p.Shutdown(ctx)
+			}
+			if err != nil && len(pools) > 1 {
+				err = fmt.Errorf("pool %s: %w", p.Tag(), err)
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+
+	var msgs []string
+	for _, err := range errs {
+		if err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// portAttempts bounds how many candidate ports one acquire tries when the OS
+// picks them, in case it hands back a port another worker already holds.
+const portAttempts = 10
+
+// portAllocator hands out worker ports and tracks which are in use, so two
+// workers are never assigned the same port at once. With a range (--port-range)
+// ports come only from [lo, hi], in rotation; otherwise the OS picks an
+// ephemeral one. Either way a candidate must be bindable when handed out.
+type portAllocator struct {
+	mu     sync.Mutex
+	lo, hi int // 0, 0 = let the OS choose
+	next   int // where the next scan of the range starts
+	inUse  map[int]bool
+}
+
+func newPortAllocator(lo, hi int) *portAllocator {
+	return &portAllocator{lo: lo, hi: hi, next: lo, inUse: make(map[int]bool)}
+}
+
+// acquire reserves a free port for a new worker.
+func (a *portAllocator) acquire() (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.lo == 0 {
+		for range portAttempts {
+			port, err := findFreePort()
+			if err != nil {
+				return 0, err
+			}
+			if !a.inUse[port] {
+				a.inUse[port] = true
+				return port, nil
+			}
+		}
+		return 0, fmt.Errorf("no unused port after %d attempts", portAttempts)
+	}
+
+	size := a.hi - a.lo + 1
+	for i := range size {
+		port := a.lo + (a.next-a.lo+i)%size
+		if a.inUse[port] || !portBindable(port) {
+			continue
+		}
+		a.inUse[port] = true
+		a.next = port + 1
+		return port, nil
+	}
+	return 0, fmt.Errorf("no free port in range %d-%d (%d in use by workers)", a.lo, a.hi, len(a.inUse))
+}
+
+// claim marks a port already held by a worker, e.g. one adopted from a
+// previous orchestrator, as in use.
+func (a *portAllocator) claim(port int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.inUse[port] = true
+}
+
+// release returns a port once its worker has left the pool for good.
+func (a *portAllocator) release(port int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.inUse, port)
+}
+
+// findFreePort asks the OS for an available TCP port by binding to :0.
+func findFreePort() (int, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port, nil
+}
+
+// portBindable reports whether nothing is listening on port right now.
+func portBindable(port int) bool {
+	ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return false
+	}
+	ln.Close()
+	return true
+}
+
+// parsePortRange parses --port-range ("lo-hi"); "" means no range.
+func parsePortRange(s string) (lo, hi int, err error) {
+	if s == "" {
+		return 0, 0, nil
+	}
+	a, b, ok := strings.Cut(s, "-")
+	if ok {
+		lo, err = strconv.Atoi(strings.TrimSpace(a))
+	}
+	if ok && err == nil {
+		hi, err = strconv.Atoi(strings.TrimSpace(b))
+	}
+	if !ok || err != nil || lo < 1 || hi > 65535 || lo > hi {
+		return 0, 0, fmt.Errorf("port-range must be lo-hi with 1 <= lo <= hi <= 65535, got %q", s)
+	}
+	return lo, hi, nil
+}
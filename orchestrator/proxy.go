@@ -5,23 +5,100 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"sync/atomic"
 	"time"
 )
 
-const workerRequestTimeout = 5 * time.Second
+// WorkerTimeouts bounds each kind of proxied worker call. Creates get their
+// own budget because a browser session legitimately takes longer to set up
+// than a read.
+type WorkerTimeouts struct {
+	Create time.Duration
+	Get    time.Duration
+	Delete time.Duration
+}
+
+// workerTimeouts is set once at startup from config.
+var workerTimeouts = WorkerTimeouts{
+	Create: 10 * time.Second,
+	Get:    5 * time.Second,
+	Delete: 5 * time.Second,
+}
+
+// httpClient has no overall timeout; each call is bounded by its context.
+// main replaces its transport with newProxyTransport.
+var httpClient = &http.Client{}
+
+// newProxyTransport is the default transport with the idle connection pool
+// sized for the orchestrator. The default keeps only 2 idle connections per
+// host, so with many concurrent calls to one worker (--sessions-per-worker)
+// most of them dial afresh and leave a TIME_WAIT socket behind.
+func newProxyTransport(maxIdle, maxIdlePerHost int, idleTimeout time.Duration) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConns = maxIdle
+	t.MaxIdleConnsPerHost = maxIdlePerHost
+	t.IdleConnTimeout = idleTimeout
+	return t
+}
+
+// inflightLimiter caps concurrent proxied requests across all sessions and
+// workers, independent of how many workers are available. A limit of 0
+// admits everything.
+type inflightLimiter struct {
+	limit int64
+	cur   atomic.Int64
+}
+
+func newInflightLimiter(limit int) *inflightLimiter {
+	return &inflightLimiter{limit: int64(limit)}
+}
 
-var httpClient = &http.Client{
-	Timeout: workerRequestTimeout,
+// TryAcquire takes a slot without blocking; it reports false when the limit
+// has been reached. Every successful TryAcquire must be paired with Release.
+func (l *inflightLimiter) TryAcquire() bool {
+	if n := l.cur.Add(1); l.limit > 0 && n > l.limit {
+		l.cur.Add(-1)
+		return false
+	}
+	return true
+}
+
+// Release frees a slot taken by TryAcquire.
+func (l *inflightLimiter) Release() { l.cur.Add(-1) }
+
+// InFlight returns the number of proxied requests currently running.
+func (l *inflightLimiter) InFlight() int64 { return l.cur.Load() }
+
+// Limit returns the configured ceiling (0 = unlimited).
+func (l *inflightLimiter) Limit() int64 { return l.limit }
+
+// proxyLimit is set once at startup from config.
+var proxyLimit = newInflightLimiter(0)
+
+// admitProxied takes a proxy slot for a client request, answering 429 when
+// the orchestrator is at its in-flight limit. Callers must Release on true.
+func admitProxied(w http.ResponseWriter, r *http.Request) bool {
+	if proxyLimit.TryAcquire() {
+		return true
+	}
+	requestLogger(r).Warn("rejecting request — too many in-flight proxied requests", "limit", proxyLimit.Limit())
+	w.Header().Set("Retry-After", "1")
+	writeError(w, http.StatusTooManyRequests, "too many in-flight requests")
+	return false
 }
 
 // forwardCreateSession sends POST /sessions to the worker and returns the response body.
-func forwardCreateSession(worker *Worker, body []byte) ([]byte, int, error) {
+func forwardCreateSession(parent context.Context, worker *Worker, body []byte) ([]byte, int, error) {
 	url := fmt.Sprintf("%s/sessions", worker.BaseURL())
 
-	ctx, cancel := context.WithTimeout(context.Background(), workerRequestTimeout)
+	// Detach from the caller's cancellation so a client hanging up does not
+	// abort a worker call mid-flight; the request ID still flows through.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(parent), workerTimeouts.Create)
 	defer cancel()
+	ctx, sp := startSpan(ctx, "worker POST /sessions", spanKindClient, "worker_id", worker.ID, "port", worker.Port)
+	defer sp.End()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
@@ -29,12 +106,17 @@ func forwardCreateSession(worker *Worker, body []byte) ([]byte, int, error) {
 	}
 	req.Header.Set("Content-Type", "application/json")
 
+	injectTraceparent(ctx, req)
+
+	worker.requests.Add(1)
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		log.Printf("[proxy] POST /sessions to worker %d failed: %v", worker.ID, err)
+		sp.SetError(err)
+		proxyLogger(ctx, worker).Warn("POST /sessions to worker failed", "error", err)
 		return nil, 0, fmt.Errorf("forward to worker %d: %w", worker.ID, err)
 	}
 	defer resp.Body.Close()
+	sp.SetAttr("http.response.status_code", resp.StatusCode)
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -45,23 +127,32 @@ func forwardCreateSession(worker *Worker, body []byte) ([]byte, int, error) {
 }
 
 // forwardGetSession sends GET /sessions/:id to the worker.
-func forwardGetSession(worker *Worker, sessionID string) ([]byte, int, error) {
+func forwardGetSession(parent context.Context, worker *Worker, sessionID string) ([]byte, int, error) {
 	url := fmt.Sprintf("%s/sessions/%s", worker.BaseURL(), sessionID)
 
-	ctx, cancel := context.WithTimeout(context.Background(), workerRequestTimeout)
+	// Detach from the caller's cancellation so a client hanging up does not
+	// abort a worker call mid-flight; the request ID still flows through.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(parent), workerTimeouts.Get)
 	defer cancel()
+	ctx, sp := startSpan(ctx, "worker GET /sessions/{id}", spanKindClient, "worker_id", worker.ID, "port", worker.Port, "session_id", sessionID)
+	defer sp.End()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, 0, fmt.Errorf("create request: %w", err)
 	}
 
+	injectTraceparent(ctx, req)
+
+	worker.requests.Add(1)
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		log.Printf("[proxy] GET /sessions/%s to worker %d failed: %v", sessionID, worker.ID, err)
+		sp.SetError(err)
+		proxyLogger(ctx, worker).Warn("GET /sessions to worker failed", "session_id", sessionID, "error", err)
 		return nil, 0, fmt.Errorf("forward to worker %d: %w", worker.ID, err)
 	}
 	defer resp.Body.Close()
+	sp.SetAttr("http.response.status_code", resp.StatusCode)
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -72,26 +163,45 @@ func forwardGetSession(worker *Worker, sessionID string) ([]byte, int, error) {
 }
 
 // deleteSessionFromWorker sends DELETE /sessions/:id to the worker.
-func deleteSessionFromWorker(worker *Worker, sessionID string) (int, error) {
+func deleteSessionFromWorker(parent context.Context, worker *Worker, sessionID string) (int, error) {
 	url := fmt.Sprintf("%s/sessions/%s", worker.BaseURL(), sessionID)
 
-	ctx, cancel := context.WithTimeout(context.Background(), workerRequestTimeout)
+	// Detach from the caller's cancellation so a client hanging up does not
+	// abort a worker call mid-flight; the request ID still flows through.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(parent), workerTimeouts.Delete)
 	defer cancel()
+	ctx, sp := startSpan(ctx, "worker DELETE /sessions/{id}", spanKindClient, "worker_id", worker.ID, "port", worker.Port, "session_id", sessionID)
+	defer sp.End()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
 	if err != nil {
 		return 0, fmt.Errorf("create request: %w", err)
 	}
 
+	injectTraceparent(ctx, req)
+
+	worker.requests.Add(1)
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		log.Printf("[proxy] DELETE /sessions/%s to worker %d failed: %v", sessionID, worker.ID, err)
+		sp.SetError(err)
+		proxyLogger(ctx, worker).Warn("DELETE /sessions to worker failed", "session_id", sessionID, "error", err)
 		return 0, fmt.Errorf("forward to worker %d: %w", worker.ID, err)
 	}
 	defer resp.Body.Close()
+	sp.SetAttr("http.response.status_code", resp.StatusCode)
 
 	// Drain body to allow connection reuse
 	_, _ = io.Copy(io.Discard, resp.Body)
 
 	return resp.StatusCode, nil
 }
+
+// proxyLogger tags proxy log lines with the worker and, when called on behalf
+// of a client request, its request ID.
+func proxyLogger(ctx context.Context, worker *Worker) *slog.Logger {
+	l := logger("proxy").With("worker_id", worker.ID, "port", worker.Port)
+	if id := requestID(ctx); id != "" {
+		l = l.With("request_id", id)
+	}
+	return l
+}
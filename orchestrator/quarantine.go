@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// quarantineSettings are PoolConfig's Quarantine* fields.
+type quarantineSettings struct {
+	timeout   time.Duration // 0 = disabled: failing workers are killed
+	interval  time.Duration
+	successes int
+}
+
+// quarantineWorker takes w, which has just failed probe, off the available
+// queue and starts watchQuarantine on it. An idle worker is quarantined on
+// its first failure, since setting it aside costs nothing. One holding
+// sessions is only quarantined once it reaches HealthFailures in a row,
+// where it used to be killed along with them: a worker that is merely slow
+// gets QuarantineTimeout more to recover before they are given up.
+func (p *Pool) quarantineWorker(w *Worker, probe probeResult) {
+	now := p.clk.Now()
+	if !w.startQuarantine(now) {
+		return
+	}
+	if p.available.remove(w) {
+		w.clearQueued()
+	}
+	p.quarantines.Add(1)
+	sessions := w.Sessions()
+	poolLogger(w).Warn("failed health check — quarantined", "failure", probe.Failure, "streak", probe.Streak,
+		"session_ids", sessions, "quarantine_timeout", p.quarantine.timeout.String())
+	p.events.Record(EventWorkerQuarantined, "worker_id", w.ID, "port", w.Port, "failure", probe.Failure,
+		"streak", probe.Streak, "session_ids", sessions)
+	go p.watchQuarantine(w, now)
+}
+
+// watchQuarantine probes a worker quarantined since since every
+// QuarantineInterval, with the deep check too when one is configured.
+// QuarantineSuccesses healthy probes in a row re-admit it. A refused
+// connection or a missing session, or any failure once QuarantineTimeout
+// has passed, lifts the quarantine and kills it, and monitor restarts it as
+// after any failed health check. It stops if the worker exits (Start lifts
+// the quarantine) or leaves the pool.
+func (p *Pool) watchQuarantine(w *Worker, since time.Time) {
+	passes := 0
+	for {
+		<-p.clk.After(p.quarantine.interval)
+		if !w.QuarantinedAt().Equal(since) || !p.isMember(w) {
+			return
+		}
+		if s := w.State(); s == WorkerStateDead || s == WorkerStateStarting {
+			return
+		}
+		res := w.healthCheck(context.Background())
+		if res.Healthy && p.deep.every > 0 {
+			res = p.deepCheck(context.Background(), w)
+		}
+		if res.Healthy {
+			if passes++; passes >= p.quarantine.successes {
+				p.readmit(w, since)
+				return
+			}
+			continue
+		}
+		passes = 0
+		log := poolLogger(w).With("failure", res.Failure, "quarantined_for", p.clk.Since(since).Round(time.Millisecond).String())
+		switch {
+		case res.fatal():
+			log.Warn("quarantined worker failed its probe for good — killing")
+			w.endQuarantine(since)
+			w.Kill("failed health check in quarantine")
+			return
+		case p.clk.Since(since) >= p.quarantine.timeout:
+			log.Warn("quarantine timed out — killing", "session_ids", w.Sessions())
+			w.endQuarantine(since)
+			w.Kill("quarantine timed out")
+			return
+		}
+	}
+}
+
+// readmit lifts w's quarantine and queues it again if it has a free slot.
+func (p *Pool) readmit(w *Worker, since time.Time) {
+	if !w.endQuarantine(since) {
+		return
+	}
+	p.readmissions.Add(1)
+	quarantined := p.clk.Since(since).Round(time.Millisecond)
+	poolLogger(w).Info("quarantined worker recovered — re-admitted", "quarantined_for", quarantined.String(),
+		"probes", p.quarantine.successes)
+	p.events.Record(EventWorkerReadmitted, "worker_id", w.ID, "port", w.Port, "quarantined_for", quarantined.String())
+	if w.State() == WorkerStateAvailable {
+		p.Release(w)
+	}
+}
+
+// Quarantined returns how many of the pool's workers are quarantined.
+func (p *Pool) Quarantined() int {
+	n := 0
+	for _, w := range p.Workers() {
+		if !w.QuarantinedAt().IsZero() {
+			n++
+		}
+	}
+	return n
+}
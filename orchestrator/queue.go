@@ -0,0 +1,250 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Priority is a create request's lane in the waiter queue (X-Priority).
+type Priority int
+
+const (
+	PriorityHigh Priority = iota
+	PriorityNormal
+	PriorityLow
+	numPriorities
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityHigh:
+		return "high"
+	case PriorityLow:
+		return "low"
+	default:
+		return "normal"
+	}
+}
+
+// parsePriority parses an X-Priority value; "" is normal.
+func parsePriority(s string) (Priority, error) {
+	switch s {
+	case "high":
+		return PriorityHigh, nil
+	case "", "normal":
+		return PriorityNormal, nil
+	case "low":
+		return PriorityLow, nil
+	}
+	return 0, fmt.Errorf("priority must be high, normal or low, got %q", s)
+}
+
+// workerQueue hands idle workers to Acquire callers, highest priority first
+// and in strict arrival order within a priority. A worker with several
+// session slots sits in the queue once, for as long as it has one free.
+//
+// Idle workers wait in a FIFO list and blocked callers in one FIFO lane per
+// priority; a released worker goes straight to the longest-waiting caller of
+// the highest non-empty lane, and only joins the idle list when nobody is
+// waiting. So idle workers and waiters never coexist, and a late arrival can
+// never overtake an already-blocked caller of the same or higher priority.
+type workerQueue struct {
+	mu         sync.Mutex
+	idle       []*Worker                 // oldest first
+	waiters    [numPriorities]*list.List // of *queueWaiter, oldest first
+	maxWaiters int                       // get refuses to queue beyond this many; 0 = unbounded
+}
+
+// errQueueFull is get's error when the caller would have to wait and
+// maxWaiters callers already are.
+var errQueueFull = errors.New("queue full")
+
+// queueWaiter is one blocked get call. ch has room for exactly one worker so
+// put never blocks while holding the lock.
+type queueWaiter struct {
+	ch     chan *Worker
+	handed bool // a worker has been sent on ch (guarded by workerQueue.mu)
+}
+
+func newWorkerQueue(maxWaiters int) *workerQueue {
+	q := &workerQueue{maxWaiters: maxWaiters}
+	for i := range q.waiters {
+		q.waiters[i] = list.New()
+	}
+	return q
+}
+
+// put hands w to the oldest waiter of the highest priority, or appends it to
+// the idle list.
+func (q *workerQueue) put(w *Worker) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, lane := range q.waiters {
+		if front := lane.Front(); front != nil {
+			wt := lane.Remove(front).(*queueWaiter)
+			wt.handed = true
+			wt.ch <- w
+			return
+		}
+	}
+	q.idle = append(q.idle, w)
+}
+
+// get returns the oldest idle worker, or queues the caller in prio's lane
+// behind every earlier one until a worker is put or ctx is done. Workers for
+// which accept returns false are dropped and the caller keeps its place at
+// the front of its lane. A new caller that would have to wait when maxWaiters
+// are already queued gets errQueueFull instead.
+func (q *workerQueue) get(ctx context.Context, prio Priority, accept func(*Worker) bool) (*Worker, error) {
+	lane := q.waiters[prio]
+	front := false
+	for {
+		q.mu.Lock()
+		if len(q.idle) > 0 {
+			w := q.idle[0]
+			q.idle = q.idle[1:]
+			q.mu.Unlock()
+			if accept(w) {
+				return w, nil
+			}
+			continue
+		}
+		if !front && q.maxWaiters > 0 && q.waitingLocked() >= q.maxWaiters {
+			q.mu.Unlock()
+			return nil, errQueueFull
+		}
+		wt := &queueWaiter{ch: make(chan *Worker, 1)}
+		var elem *list.Element
+		if front {
+			elem = lane.PushFront(wt)
+		} else {
+			elem = lane.PushBack(wt)
+		}
+		q.mu.Unlock()
+
+		select {
+		case w := <-wt.ch:
+			if accept(w) {
+				return w, nil
+			}
+			front = true // it was our turn; keep it
+		case <-ctx.Done():
+			q.mu.Lock()
+			if !wt.handed {
+				ahead := 0
+				for _, l := range q.waiters[:prio] {
+					ahead += l.Len()
+				}
+				for e := lane.Front(); e != elem; e = e.Next() {
+					ahead++
+				}
+				lane.Remove(elem)
+				q.mu.Unlock()
+				return nil, &waitTimeout{ahead: ahead, err: ctx.Err()}
+			}
+			q.mu.Unlock()
+			// A worker was handed over as we gave up: pass it on.
+			q.put(<-wt.ch)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// waitTimeout is get's error when ctx ends while the caller is queued. ahead
+// is how many callers were queued in front of it at that moment, higher
+// priorities included.
+type waitTimeout struct {
+	ahead int
+	err   error
+}
+
+func (e *waitTimeout) Error() string { return e.err.Error() }
+func (e *waitTimeout) Unwrap() error { return e.err }
+
+// tryGetFunc removes and returns, without blocking, the idle worker that
+// sorts lowest by cmp among those for which match returns true; ties go to
+// the longest-idle one.
+func (q *workerQueue) tryGetFunc(match func(*Worker) bool, cmp func(a, b *Worker) int) (*Worker, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	best := -1
+	for i, w := range q.idle {
+		if match(w) && (best < 0 || cmp(w, q.idle[best]) < 0) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil, false
+	}
+	w := q.idle[best]
+	q.idle = slices.Delete(q.idle, best, best+1)
+	return w, true
+}
+
+// remove takes w out of the idle list, reporting whether it was there. It
+// fails if a caller has already taken w.
+func (q *workerQueue) remove(w *Worker) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i := slices.Index(q.idle, w)
+	if i < 0 {
+		return false
+	}
+	q.idle = slices.Delete(q.idle, i, i+1)
+	return true
+}
+
+// waiting returns how many callers are blocked in each priority lane.
+func (q *workerQueue) waiting() [numPriorities]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var n [numPriorities]int
+	for i, l := range q.waiters {
+		n[i] = l.Len()
+	}
+	return n
+}
+
+// waitingLocked counts queued callers across all lanes. Caller holds q.mu.
+func (q *workerQueue) waitingLocked() int {
+	n := 0
+	for _, l := range q.waiters {
+		n += l.Len()
+	}
+	return n
+}
+
+// idleLen returns how many workers are idle in the queue.
+func (q *workerQueue) idleLen() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.idle)
+}
+
+// idleCount returns how many queued workers match.
+func (q *workerQueue) idleCount(match func(*Worker) bool) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, w := range q.idle {
+		if match(w) {
+			n++
+		}
+	}
+	return n
+}
+
+// freeSlots sums the free session slots of the queued workers.
+func (q *workerQueue) freeSlots() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, w := range q.idle {
+		n += w.freeSlots()
+	}
+	return n
+}
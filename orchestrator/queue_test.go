@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// queueGrant is a worker handed to the waiter that arrived in position n.
+type queueGrant struct {
+	n int
+	w *Worker
+}
+
+// enqueue starts a get in prio's lane and waits until it is queued, so
+// waiters arrive in the order enqueue is called.
+func enqueue(t *testing.T, q *workerQueue, prio Priority, n int, grants chan<- queueGrant) {
+	t.Helper()
+	before := q.waiting()[prio]
+	go func() {
+		w, err := q.get(context.Background(), prio, func(*Worker) bool { return true })
+		if err != nil {
+			t.Errorf("waiter %d: %v", n, err)
+		}
+		grants <- queueGrant{n, w}
+	}()
+	waitFor(t, "waiter to queue", func() bool { return q.waiting()[prio] == before+1 })
+}
+
+func TestWorkerQueueGrantsInArrivalOrder(t *testing.T) {
+	const n = 50
+	q := newWorkerQueue(0)
+	grants := make(chan queueGrant, n)
+
+	// Interleave arrivals and releases: two waiters join for every worker
+	// put, so the queue is never empty until the tail is drained.
+	next := 0
+	for i := range n {
+		enqueue(t, q, PriorityNormal, i, grants)
+		if i%2 == 1 {
+			q.put(&Worker{ID: next})
+			next++
+		}
+	}
+	for next < n {
+		q.put(&Worker{ID: next})
+		next++
+	}
+
+	for range n {
+		g := <-grants
+		if g.w.ID != g.n {
+			t.Errorf("waiter %d got worker %d, want %d", g.n, g.w.ID, g.n)
+		}
+	}
+	if idle := q.idleLen(); idle != 0 {
+		t.Errorf("idle workers = %d, want 0", idle)
+	}
+}
+
+func TestWorkerQueueTimedOutWaiterLeavesItsPlace(t *testing.T) {
+	q := newWorkerQueue(0)
+	grants := make(chan queueGrant, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := q.get(ctx, PriorityNormal, func(*Worker) bool { return true })
+		done <- err
+	}()
+	waitFor(t, "waiter to queue", func() bool { return q.waiting()[PriorityNormal] == 1 })
+	enqueue(t, q, PriorityNormal, 1, grants)
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled get = %v, want context.Canceled", err)
+	}
+	q.put(&Worker{ID: 7})
+	select {
+	case g := <-grants:
+		if g.n != 1 || g.w.ID != 7 {
+			t.Errorf("got waiter %d worker %d, want waiter 1 worker 7", g.n, g.w.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker not handed to the remaining waiter")
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// ResourceLimits caps what a single worker process may consume.
+// Zero values mean "unlimited".
+type ResourceLimits struct {
+	// MemoryBytes is applied as memory.max when CgroupParent is set,
+	// otherwise as an address-space rlimit (RLIMIT_AS) on the process.
+	MemoryBytes int64
+	// CPUs is a fractional core count written to cpu.max. Requires CgroupParent.
+	CPUs float64
+	// CgroupParent is a cgroup v2 directory under which each worker gets its
+	// own child cgroup (worker-<id>). The orchestrator must be allowed to write to it.
+	CgroupParent string
+}
+
+// Enabled reports whether any limit is configured.
+func (l ResourceLimits) Enabled() bool {
+	return l.MemoryBytes > 0 || l.CPUs > 0
+}
+
+// Validate checks that the combination of limits can be enforced.
+func (l ResourceLimits) Validate() error {
+	if l.MemoryBytes < 0 || l.CPUs < 0 {
+		return errors.New("resource limits must not be negative")
+	}
+	if l.CPUs > 0 && l.CgroupParent == "" {
+		return errors.New("a CPU limit requires a cgroup parent (-worker-cgroup)")
+	}
+	return validateLimitsPlatform(l)
+}
+
+// exitSignal extracts the terminating signal from a cmd.Wait error.
+// ok is false when the process exited normally (with any exit code).
+func exitSignal(err error) (sig syscall.Signal, ok bool) {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 0, false
+	}
+	status, isWait := exitErr.Sys().(syscall.WaitStatus)
+	if !isWait || !status.Signaled() {
+		return 0, false
+	}
+	return status.Signal(), true
+}
@@ -0,0 +1,180 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// cpuPeriodMicros is the cgroup v2 cpu.max period used for CPU quotas.
+const cpuPeriodMicros = 100000
+
+func validateLimitsPlatform(l ResourceLimits) error {
+	if l.CgroupParent == "" {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(l.CgroupParent, "cgroup.controllers")); err != nil {
+		return fmt.Errorf("%s is not a cgroup v2 directory: %w", l.CgroupParent, err)
+	}
+	return nil
+}
+
+// prepareLimits is called before cmd.Start. With a cgroup parent configured it
+// creates the worker's cgroup, writes its limits, and arranges for the child to
+// be born inside it (CLONE_INTO_CGROUP), so there is no window where it runs
+// unconstrained. The returned cleanup closes the cgroup fd after Start.
+func (w *Worker) prepareLimits(cmd *exec.Cmd) (cleanup func(), err error) {
+	l := w.Limits
+	if l.CgroupParent == "" || !l.Enabled() {
+		return func() {}, nil
+	}
+
+	dir := w.cgroupDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create cgroup %s: %w", dir, err)
+	}
+	if l.MemoryBytes > 0 {
+		if err := os.WriteFile(filepath.Join(dir, "memory.max"), []byte(strconv.FormatInt(l.MemoryBytes, 10)), 0o644); err != nil {
+			return nil, fmt.Errorf("set memory.max: %w", err)
+		}
+	}
+	if l.CPUs > 0 {
+		quota := int64(l.CPUs * cpuPeriodMicros)
+		if err := os.WriteFile(filepath.Join(dir, "cpu.max"), []byte(fmt.Sprintf("%d %d", quota, cpuPeriodMicros)), 0o644); err != nil {
+			return nil, fmt.Errorf("set cpu.max: %w", err)
+		}
+	}
+
+	fd, err := syscall.Open(dir, syscall.O_RDONLY|syscall.O_DIRECTORY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open cgroup %s: %w", dir, err)
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = fd
+
+	w.oomBaseline = w.cgroupOOMKills()
+	return func() { syscall.Close(fd) }, nil
+}
+
+// applyLimits is called after cmd.Start. Without a cgroup, the memory limit is
+// applied to the running child as RLIMIT_AS via prlimit(2).
+func (w *Worker) applyLimits(pid int) error {
+	l := w.Limits
+	if l.CgroupParent != "" || l.MemoryBytes <= 0 {
+		return nil
+	}
+	lim := syscall.Rlimit{Cur: uint64(l.MemoryBytes), Max: uint64(l.MemoryBytes)}
+	_, _, errno := syscall.RawSyscall6(syscall.SYS_PRLIMIT64, uintptr(pid), uintptr(syscall.RLIMIT_AS), uintptr(unsafe.Pointer(&lim)), 0, 0, 0)
+	if errno != 0 {
+		return fmt.Errorf("prlimit RLIMIT_AS: %w", errno)
+	}
+	return nil
+}
+
+// oomKilled reports whether the kernel OOM killer terminated the worker.
+// With a cgroup this is authoritative (memory.events oom_kill increased);
+// otherwise an unrequested SIGKILL is treated as the OOM killer's signature.
+func (w *Worker) oomKilled(waitErr error, killRequested bool) bool {
+	if w.Limits.CgroupParent != "" && w.Limits.Enabled() {
+		return w.cgroupOOMKills() > w.oomBaseline
+	}
+	sig, ok := exitSignal(waitErr)
+	return ok && sig == syscall.SIGKILL && !killRequested
+}
+
+func (w *Worker) cgroupDir() string {
+	return filepath.Join(w.Limits.CgroupParent, fmt.Sprintf("worker-%d", w.ID))
+}
+
+// cgroupOOMKills reads the oom_kill counter from the worker's memory.events.
+func (w *Worker) cgroupOOMKills() int64 {
+	f, err := os.Open(filepath.Join(w.cgroupDir(), "memory.events"))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if v, ok := strings.CutPrefix(sc.Text(), "oom_kill "); ok {
+			n, _ := strconv.ParseInt(v, 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+// procSampling reports whether processRSS and processCPUTime work on this
+// platform.
+const procSampling = true
+
+// processRSS returns the resident set size of pid in bytes, from the VmRSS
+// line of /proc/<pid>/status.
+func processRSS(pid int) (int64, error) {
+	if pid <= 0 {
+		return 0, errors.New("no process")
+	}
+	f, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if v, ok := strings.CutPrefix(sc.Text(), "VmRSS:"); ok {
+			kb, err := strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v), "kB")), 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("parse VmRSS %q: %w", v, err)
+			}
+			return kb * 1024, nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no VmRSS in /proc/%d/status", pid)
+}
+
+// userHZ is the unit of the utime and stime fields in /proc/<pid>/stat. It
+// is 100 on every architecture Linux supports for user space.
+const userHZ = 100
+
+// processCPUTime returns the CPU time pid has used so far, user plus system,
+// from fields 14 and 15 of /proc/<pid>/stat.
+func processCPUTime(pid int) (time.Duration, error) {
+	if pid <= 0 {
+		return 0, errors.New("no process")
+	}
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, err
+	}
+	// The command name (field 2) may contain spaces; the rest starts after
+	// its closing parenthesis, with field 3 (state).
+	i := strings.LastIndexByte(string(data), ')')
+	if i < 0 {
+		return 0, fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+	fields := strings.Fields(string(data[i+1:]))
+	if len(fields) < 13 {
+		return 0, fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+	utime, err1 := strconv.ParseInt(fields[11], 10, 64)
+	stime, err2 := strconv.ParseInt(fields[12], 10, 64)
+	if err := errors.Join(err1, err2); err != nil {
+		return 0, fmt.Errorf("parse /proc/%d/stat: %w", pid, err)
+	}
+	return time.Duration(utime+stime) * time.Second / userHZ, nil
+}
@@ -0,0 +1,36 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"time"
+)
+
+func validateLimitsPlatform(l ResourceLimits) error {
+	if l.Enabled() || l.CgroupParent != "" {
+		return errors.New("worker resource limits are only supported on Linux")
+	}
+	return nil
+}
+
+func (w *Worker) prepareLimits(cmd *exec.Cmd) (cleanup func(), err error) {
+	return func() {}, nil
+}
+
+func (w *Worker) applyLimits(pid int) error { return nil }
+
+func (w *Worker) oomKilled(waitErr error, killRequested bool) bool { return false }
+
+// procSampling reports whether processRSS and processCPUTime work on this
+// platform.
+const procSampling = false
+
+func processRSS(pid int) (int64, error) {
+	return 0, errors.New("RSS sampling is only supported on Linux")
+}
+
+func processCPUTime(pid int) (time.Duration, error) {
+	return 0, errors.New("CPU sampling is only supported on Linux")
+}
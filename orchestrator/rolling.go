@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// rollingPollInterval is how often a rolling restart re-checks the worker it
+// is waiting on.
+const rollingPollInterval = 200 * time.Millisecond
+
+// errRollingRestartRunning is returned by StartRollingRestart while another
+// rolling restart of the same pool is still in progress.
+var errRollingRestartRunning = errors.New("a rolling restart is already running")
+
+// RollingRestartStatus is the progress of a pool's most recent rolling
+// restart, as served by GET /pool/rolling-restart.
+type RollingRestartStatus struct {
+	State      string     `json:"state"` // "idle", "running", "done" or "canceled"
+	Pool       string     `json:"pool"`
+	Total      int        `json:"total"`
+	Done       []int      `json:"done"`      // worker IDs restarted (or gone) so far
+	Remaining  []int      `json:"remaining"` // worker IDs still to restart, current included
+	Current    *int       `json:"current"`   // worker being restarted or waited on; null between workers
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// rollingRestart is one run of Pool.StartRollingRestart. Its progress fields
+// are guarded by Pool.rollMu.
+type rollingRestart struct {
+	cancel     context.CancelFunc
+	status     RollingRestartStatus
+	startedAt  time.Time
+	finishedAt time.Time
+}
+
+// StartRollingRestart restarts every current worker of the pool one at a
+// time, in the background, so a new binary on disk is picked up without
+// dropping sessions. An idle worker is taken out of the queue and restarted;
+// a busy one is marked retiring and restarted once its sessions end or
+// expire. Each restart waits for the worker to pass its readiness check
+// before the next begins, and a worker is only taken down while at least
+// min-1 others are ready.
+func (p *Pool) StartRollingRestart() (RollingRestartStatus, error) {
+	p.rollMu.Lock()
+	defer p.rollMu.Unlock()
+	if p.rolling != nil && p.rolling.status.State == "running" {
+		return p.rollingStatusLocked(), errRollingRestartRunning
+	}
+
+	workers := p.Workers()
+	ids := make([]int, len(workers))
+	for i, w := range workers {
+		ids[i] = w.ID
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.rolling = &rollingRestart{
+		cancel:    cancel,
+		startedAt: p.clk.Now(),
+		status:    RollingRestartStatus{State: "running", Pool: p.Tag(), Total: len(ids), Done: []int{}, Remaining: ids},
+	}
+	p.logger().Info("rolling restart started", "workers", len(ids))
+	go p.runRollingRestart(ctx, p.rolling, workers)
+	return p.rollingStatusLocked(), nil
+}
+
+// CancelRollingRestart stops a running rolling restart after the worker in
+// progress, if any, and reports whether one was running. A worker already
+// marked retiring is still restarted when its sessions end.
+func (p *Pool) CancelRollingRestart() bool {
+	p.rollMu.Lock()
+	defer p.rollMu.Unlock()
+	if p.rolling == nil || p.rolling.status.State != "running" {
+		return false
+	}
+	p.rolling.cancel()
+	return true
+}
+
+// RollingRestart returns the progress of the pool's most recent rolling
+// restart; State is "idle" if there has been none.
+func (p *Pool) RollingRestart() RollingRestartStatus {
+	p.rollMu.Lock()
+	defer p.rollMu.Unlock()
+	return p.rollingStatusLocked()
+}
+
+func (p *Pool) rollingStatusLocked() RollingRestartStatus {
+	if p.rolling == nil {
+		return RollingRestartStatus{State: "idle", Pool: p.Tag(), Done: []int{}, Remaining: []int{}}
+	}
+	st := p.rolling.status
+	st.Done = slices.Clone(st.Done)
+	st.Remaining = slices.Clone(st.Remaining)
+	if st.Current != nil {
+		id := *st.Current
+		st.Current = &id
+	}
+	started := p.rolling.startedAt
+	st.StartedAt = &started
+	if !p.rolling.finishedAt.IsZero() {
+		finished := p.rolling.finishedAt
+		st.FinishedAt = &finished
+	}
+	return st
+}
+
+// runRollingRestart restarts workers in order until all are done, the run is
+// canceled, or the pool shuts down.
+func (p *Pool) runRollingRestart(ctx context.Context, run *rollingRestart, workers []*Worker) {
+	state := "done"
+	for _, w := range workers {
+		p.rollMu.Lock()
+		id := w.ID
+		run.status.Current = &id
+		p.rollMu.Unlock()
+
+		if !p.restartForRollout(ctx, w) {
+			state = "canceled"
+			break
+		}
+
+		p.rollMu.Lock()
+		run.status.Current = nil
+		run.status.Done = append(run.status.Done, w.ID)
+		run.status.Remaining = slices.DeleteFunc(run.status.Remaining, func(id int) bool { return id == w.ID })
+		p.rollMu.Unlock()
+	}
+
+	p.rollMu.Lock()
+	run.status.State = state
+	run.status.Current = nil
+	run.finishedAt = p.clk.Now()
+	done := len(run.status.Done)
+	p.rollMu.Unlock()
+	run.cancel()
+	p.logger().Info("rolling restart finished", "state", state, "restarted", done, "workers", len(workers))
+}
+
+// restartForRollout restarts w and waits until it is ready again. A worker
+// that leaves the pool meanwhile (scaled down or replaced) counts as done.
+// It returns false if ctx is canceled or the pool is shutting down first.
+func (p *Pool) restartForRollout(ctx context.Context, w *Worker) bool {
+	before := w.StartedAt()
+	for {
+		if !p.isMember(w) {
+			return true
+		}
+		p.mu.RLock()
+		closing := p.closing
+		p.mu.RUnlock()
+		if closing {
+			return false
+		}
+
+		restarted := !w.StartedAt().Equal(before)
+		switch s := w.State(); {
+		case restarted && (s == WorkerStateAvailable || s == WorkerStateBusy):
+			poolLogger(w).Info("rolling restart: worker ready")
+			return true
+		case restarted:
+			// Booting; wait for its readiness check.
+		case s == WorkerStateAvailable && w.Load() == 0 && p.readyOthers(w) >= p.Min()-1:
+			if p.available.remove(w) {
+				w.clearQueued()
+			} else if !w.Cordoned() {
+				break // taken by Acquire, or not queued yet; look again
+			}
+			if w.Load() > 0 {
+				p.Release(w) // claimed since the check
+				break
+			}
+			p.rotate(w, retireRollingRestart)
+		case s == WorkerStateBusy || s == WorkerStateAvailable && w.Load() > 0:
+			// Release restarts it when its last session ends, or queues it
+			// if the pool is not settled; the idle case then takes it.
+			if w.markRetiring(retireRollingRestart) {
+				poolLogger(w).Info("rolling restart: retiring after its sessions")
+				if s == WorkerStateAvailable && p.available.remove(w) {
+					w.clearQueued() // takes no new sessions
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-p.clk.After(rollingPollInterval):
+		}
+	}
+}
+
+// readyOthers counts the pool's workers other than w that are Available or
+// Busy, i.e. have passed their readiness check and are running. A nil w
+// counts them all.
+func (p *Pool) readyOthers(w *Worker) int {
+	n := 0
+	for _, o := range p.Workers() {
+		if s := o.State(); o != w && (s == WorkerStateAvailable || s == WorkerStateBusy) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Scale policy names accepted by --scale-policy.
+const (
+	ScalePolicyIdle    = "idle"
+	ScalePolicyLatency = "latency"
+)
+
+// scaleWaitWindow is how far back the wait percentile fed to scale policies
+// reaches. Much shorter than acquireWaitWindow so the scaler reacts to the
+// current load, not the last burst.
+const scaleWaitWindow = 30 * time.Second
+
+// PoolStats is the snapshot a ScalePolicy decides from, taken once per
+// scaleLoop tick.
+type PoolStats struct {
+	Workers    int             // registered workers, any state
+	Busy       int             // workers holding a session
+	Available  int             // idle workers in the available queue
+	IdleFor    []time.Duration // how long each of those has held no session
+	Booting    int             // pending adds plus workers still Starting
+	Waiting    int             // callers blocked in Acquire
+	Acquired   int             // successful Acquires since the previous tick
+	Min, Max   int
+	WarmBuffer int       // 0 while a pool with min 0 has no demand
+	Now        time.Time // the pool clock at the snapshot
+
+	// WaitP95 is the p95 Acquire wait over the last scaleWaitWindow; 0 when
+	// there were no Acquires.
+	WaitP95 time.Duration
+}
+
+// ScalePolicy decides, once per scaleLoop tick, how many workers to add
+// (positive) or remove (negative). The pool clamps the result to min, max and
+// the concurrent boot limit, and removes only idle workers. Reactive scale-up
+// for callers already waiting in Acquire happens regardless of policy.
+type ScalePolicy interface {
+	Name() string
+	Decide(s PoolStats) int
+}
+
+// newScalePolicy returns the policy selected by --scale-policy. downAfter
+// is how long a worker must sit idle before the idle policy removes it.
+func newScalePolicy(name string, downAfter, targetWait time.Duration, lowUtilization float64) (ScalePolicy, error) {
+	switch name {
+	case "", ScalePolicyIdle:
+		return &idlePolicy{after: downAfter}, nil
+	case ScalePolicyLatency:
+		return &latencyPolicy{target: targetWait, lowUtilization: lowUtilization}, nil
+	default:
+		return nil, fmt.Errorf("unknown scale policy %q (want %s or %s)", name, ScalePolicyIdle, ScalePolicyLatency)
+	}
+}
+
+// idlePolicy is the default: it never scales up on its own, and removes the
+// workers that have each held no session for at least after. Idleness is
+// per worker, so a busy neighbour does not keep an idle worker alive, and a
+// worker that has just served a session is not removed because the rest of
+// the pool was quiet. Nothing is removed while callers wait in Acquire, and
+// never more than the idle workers beyond the warm buffer and min.
+type idlePolicy struct {
+	after time.Duration
+}
+
+func (ip *idlePolicy) Name() string { return ScalePolicyIdle }
+
+func (ip *idlePolicy) Decide(s PoolStats) int {
+	if s.Waiting > 0 {
+		return 0
+	}
+	expired := 0
+	for _, d := range s.IdleFor {
+		if d >= ip.after {
+			expired++
+		}
+	}
+	surplus := min(s.Available-s.WarmBuffer, s.Workers-s.Min)
+	return -max(0, min(expired, surplus))
+}
+
+// latencyPolicy scales on the recent p95 Acquire wait. While it exceeds
+// target it adds workers — one per waiting caller, at least one — but holds
+// off while earlier additions are still booting so it does not overshoot. It
+// removes one worker per tick while waits are near zero (under a tenth of
+// target), nobody is waiting and utilization is below lowUtilization.
+type latencyPolicy struct {
+	target         time.Duration
+	lowUtilization float64 // busy / workers
+}
+
+func (lp *latencyPolicy) Name() string { return ScalePolicyLatency }
+
+func (lp *latencyPolicy) Decide(s PoolStats) int {
+	if s.Booting > 0 {
+		return 0
+	}
+	if s.WaitP95 > lp.target {
+		if s.Workers >= s.Max {
+			return 0
+		}
+		return max(1, s.Waiting)
+	}
+	if s.Workers <= s.Min || s.Waiting > 0 || s.Available <= s.WarmBuffer {
+		return 0
+	}
+	utilization := float64(s.Busy) / float64(s.Workers)
+	if s.WaitP95 < lp.target/10 && utilization < lp.lowUtilization {
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultScaleWindowLead is how long before a scale window opens its
+// limits already apply, so the extra workers have booted by the time the
+// load arrives.
+const defaultScaleWindowLead = 5 * time.Minute
+
+// ScaleWindow is one --scale-window entry: a daily time range, in the
+// orchestrator's local time, during which the default pool's min and max
+// workers are overridden. It is written as
+//
+//	name=HH:MM-HH:MM min=N max=N [days=mon-fri]
+//
+// An end before the start runs past midnight ("22:00-06:00"); equal start
+// and end mean the whole day. days limits the weekdays the window opens on:
+// a single day, a range such as mon-fri or fri-mon, or days joined with "+"
+// (sat+sun). Without it the window opens every day.
+type ScaleWindow struct {
+	Name       string
+	Start, End int // minutes after midnight
+	Days       [7]bool
+	Min, Max   int
+}
+
+var weekdayNames = [7]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// parseScaleWindow parses the text form described on ScaleWindow.
+func parseScaleWindow(s string) (ScaleWindow, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return ScaleWindow{}, fmt.Errorf("scale-window is empty")
+	}
+	var sw ScaleWindow
+	name, hours, ok := strings.Cut(fields[0], "=")
+	if !ok || name == "" {
+		return ScaleWindow{}, fmt.Errorf("scale-window %q must start with name=HH:MM-HH:MM", s)
+	}
+	sw.Name = name
+	from, to, ok := strings.Cut(hours, "-")
+	var err error
+	if ok {
+		if sw.Start, err = parseClock(from); err == nil {
+			sw.End, err = parseClock(to)
+		}
+	}
+	if !ok || err != nil {
+		return ScaleWindow{}, fmt.Errorf("scale-window %q: hours must be HH:MM-HH:MM", s)
+	}
+	sw.Min, sw.Max = -1, -1
+	for _, f := range fields[1:] {
+		key, val, _ := strings.Cut(f, "=")
+		switch key {
+		case "min", "max":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 0 {
+				return ScaleWindow{}, fmt.Errorf("scale-window %q: %s must be a non-negative integer", s, key)
+			}
+			if key == "min" {
+				sw.Min = n
+			} else {
+				sw.Max = n
+			}
+		case "days":
+			if sw.Days, err = parseDays(val); err != nil {
+				return ScaleWindow{}, fmt.Errorf("scale-window %q: %w", s, err)
+			}
+		default:
+			return ScaleWindow{}, fmt.Errorf("scale-window %q: unknown field %q (want min, max or days)", s, f)
+		}
+	}
+	switch {
+	case sw.Min < 0 || sw.Max < 0:
+		return ScaleWindow{}, fmt.Errorf("scale-window %q: min and max are required", s)
+	case sw.Max < 1 || sw.Max < sw.Min || sw.Max > maxPoolCapacity:
+		return ScaleWindow{}, fmt.Errorf("scale-window %q: need 0 <= min <= max, 1 <= max <= %d", s, maxPoolCapacity)
+	}
+	if sw.Days == [7]bool{} {
+		sw.Days = [7]bool{true, true, true, true, true, true, true}
+	}
+	return sw, nil
+}
+
+// parseClock parses HH:MM into minutes after midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseDays parses a days= value: mon, mon-fri, fri-mon or sat+sun.
+func parseDays(s string) ([7]bool, error) {
+	var days [7]bool
+	day := func(name string) (int, error) {
+		if i := slices.Index(weekdayNames[:], strings.ToLower(name)); i >= 0 {
+			return i, nil
+		}
+		return 0, fmt.Errorf("unknown day %q (want sun, mon, … sat)", name)
+	}
+	for _, part := range strings.Split(s, "+") {
+		from, to, isRange := strings.Cut(part, "-")
+		a, err := day(from)
+		if err != nil {
+			return days, err
+		}
+		b := a
+		if isRange {
+			if b, err = day(to); err != nil {
+				return days, err
+			}
+		}
+		for i := a; ; i = (i + 1) % 7 {
+			days[i] = true
+			if i == b {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// String returns the window in its text form.
+func (sw ScaleWindow) String() string {
+	s := fmt.Sprintf("%s=%02d:%02d-%02d:%02d min=%d max=%d", sw.Name, sw.Start/60, sw.Start%60, sw.End/60, sw.End%60, sw.Min, sw.Max)
+	if sw.Days != [7]bool{true, true, true, true, true, true, true} {
+		var names []string
+		for i, on := range sw.Days {
+			if on {
+				names = append(names, weekdayNames[i])
+			}
+		}
+		s += " days=" + strings.Join(names, "+")
+	}
+	return s
+}
+
+// openAt reports whether the window applies at t: from lead before one of
+// its openings until it closes. The openings of yesterday, today and
+// tomorrow are checked, so a window past midnight, or a lead reaching back
+// across it, is covered.
+func (sw ScaleWindow) openAt(t time.Time, lead time.Duration) bool {
+	length := sw.End - sw.Start
+	if length <= 0 {
+		length += 24 * 60
+	}
+	for _, offset := range []int{-1, 0, 1} {
+		day := time.Date(t.Year(), t.Month(), t.Day()+offset, 0, 0, 0, 0, t.Location())
+		if !sw.Days[day.Weekday()] {
+			continue
+		}
+		open := day.Add(time.Duration(sw.Start) * time.Minute)
+		closes := open.Add(time.Duration(length) * time.Minute)
+		if !t.Before(open.Add(-lead)) && t.Before(closes) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScaleLimits are the min and max workers in force, and the scale windows
+// that set them.
+type ScaleLimits struct {
+	Min, Max int
+	Window   string   // the window whose min applies; "" outside every window
+	Active   []string // every window that applies, Window first
+}
+
+// scaleLimitsAt resolves the limits at t. Outside every window they are the
+// base ones. Inside, the windows override them: overlapping windows take the
+// largest min and the largest max, and max is raised to at least min.
+func scaleLimitsAt(windows []ScaleWindow, lead time.Duration, t time.Time, baseMin, baseMax int) ScaleLimits {
+	var open []ScaleWindow
+	for _, sw := range windows {
+		if sw.openAt(t, lead) {
+			open = append(open, sw)
+		}
+	}
+	if len(open) == 0 {
+		return ScaleLimits{Min: baseMin, Max: baseMax}
+	}
+	slices.SortStableFunc(open, func(a, b ScaleWindow) int { return b.Min - a.Min })
+	l := ScaleLimits{Min: open[0].Min, Window: open[0].Name}
+	for _, sw := range open {
+		l.Max = max(l.Max, sw.Max)
+		l.Active = append(l.Active, sw.Name)
+	}
+	l.Max = max(l.Max, l.Min)
+	return l
+}
@@ -1,47 +1,95 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
-)
 
-const sessionTTL = 60 * time.Second
+	"steel-orchestrator/clock"
+)
 
 // SessionEntry tracks a session's mapping to a worker and its last access time.
 type SessionEntry struct {
 	SessionID    string
 	Worker       *Worker
 	LastAccessed time.Time
+	WebhookURL   string // POSTed to when the session expires or is lost; "" = none
+	Alias        string // set by POST /sessions/{id}/alias; "" = none
 }
 
 // SessionManager handles session-to-worker mapping and TTL expiration.
 type SessionManager struct {
 	mu       sync.RWMutex
 	sessions map[string]*SessionEntry
+	aliases  map[string]string // alias → session ID, one per session
+	events   *EventLog
+	ttl      atomic.Int64 // time.Duration; changeable at runtime
+	sweep    time.Duration
+	clk      clock.Clock
+
+	// max caps live sessions plus reserved, the creates that have claimed a
+	// slot and are still waiting for their worker (0 = unlimited).
+	max          int
+	reserved     int
+	limitRejects atomic.Int64
+
+	webhooks *WebhookNotifier // nil = no session webhooks
+
+	// gone remembers sessions lost for a reason the client should be told
+	// (410 Gone) instead of seeing them vanish (404). Pruned after one TTL.
+	gone map[string]goneSession
 }
 
 // NewSessionManager creates a new SessionManager and starts the TTL sweeper.
-func NewSessionManager() (*SessionManager, error) {
+// Sessions expire after ttl of inactivity; the sweeper runs every sweep.
+// A nil clk means the wall clock.
+func NewSessionManager(ttl, sweep time.Duration, events *EventLog, clk clock.Clock) (*SessionManager, error) {
+	if sweep <= 0 {
+		return nil, fmt.Errorf("sweep interval must be positive, got %s", sweep)
+	}
 	sm := &SessionManager{
 		sessions: make(map[string]*SessionEntry),
+		aliases:  make(map[string]string),
+		gone:     make(map[string]goneSession),
+		events:   events,
+		sweep:    sweep,
+		clk:      clock.Or(clk),
 	}
+	sm.ttl.Store(int64(ttl))
 	// starting ttlsweeper as goroutine
 	go sm.ttlSweeper()
 	return sm, nil
 }
 
-// Add registers a new session mapping.
-func (sm *SessionManager) Add(sessionID string, worker *Worker) {
+// errSessionExists is returned by Add when the session ID is already mapped.
+var errSessionExists = errors.New("session already exists")
+
+// Add registers a new session mapping, with the create's webhook_url if it
+// had one. An ID that is already mapped is left alone and errSessionExists
+// returned, so a duplicate can never orphan the session it would replace.
+func (sm *SessionManager) Add(sessionID string, worker *Worker, webhookURL string) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
+	if existing, ok := sm.sessions[sessionID]; ok {
+		logger("session").Error("duplicate session ID — keeping the existing mapping", "session_id", sessionID,
+			"worker_id", existing.Worker.ID, "duplicate_worker_id", worker.ID)
+		return fmt.Errorf("%w: %s on worker %d", errSessionExists, sessionID, existing.Worker.ID)
+	}
 	sm.sessions[sessionID] = &SessionEntry{
 		SessionID:    sessionID,
 		Worker:       worker,
-		LastAccessed: time.Now(),
+		LastAccessed: sm.clk.Now(),
+		WebhookURL:   webhookURL,
+	}
+	if debugEnabled() {
+		logger("session").Debug("registered session", "session_id", sessionID, "worker_id", worker.ID, "port", worker.Port)
 	}
-	log.Printf("[session] registered session %s → worker %d", sessionID, worker.ID)
+	return nil
 }
 
 // Get looks up a session, updates its last access time, and returns the worker.
@@ -55,10 +103,140 @@ func (sm *SessionManager) Get(sessionID string) *Worker {
 		return nil
 	}
 
-	entry.LastAccessed = time.Now()
+	entry.LastAccessed = sm.clk.Now()
 	return entry.Worker
 }
 
+// Restore registers a session recovered from the state file, keeping its
+// original last access time so the TTL carries on from where it was, and
+// its alias unless another session has claimed it meanwhile.
+func (sm *SessionManager) Restore(sessionID string, worker *Worker, lastAccessed time.Time, webhookURL, alias string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if _, taken := sm.aliases[alias]; taken {
+		alias = ""
+	}
+	sm.sessions[sessionID] = &SessionEntry{
+		SessionID:    sessionID,
+		Worker:       worker,
+		LastAccessed: lastAccessed,
+		WebhookURL:   webhookURL,
+		Alias:        alias,
+	}
+	if alias != "" {
+		sm.aliases[alias] = sessionID
+	}
+}
+
+// maxAliasLength bounds a session alias.
+const maxAliasLength = 128
+
+var (
+	// errAliasTaken is returned by SetAlias when the alias names another
+	// session, or is itself a session ID.
+	errAliasTaken = errors.New("alias already in use")
+	// errNoSession is returned by SetAlias when the session does not exist.
+	errNoSession = errors.New("session not found")
+)
+
+// validateAlias checks an alias is 1-128 letters, digits, '.', '_' or '-',
+// so it is a single URL path segment and never needs escaping.
+func validateAlias(alias string) error {
+	if alias == "" || len(alias) > maxAliasLength {
+		return fmt.Errorf("alias must be 1-%d characters", maxAliasLength)
+	}
+	for _, c := range alias {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-') {
+			return fmt.Errorf("alias may only contain letters, digits, '.', '_' and '-'")
+		}
+	}
+	return nil
+}
+
+// SetAlias gives a session a second name that /sessions/{alias} routes
+// resolve to it. A session has at most one alias: setting another replaces
+// it. An alias is unique across sessions, and one equal to a live session
+// ID is refused too, since that ID would shadow it.
+func (sm *SessionManager) SetAlias(sessionID, alias string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	entry, ok := sm.sessions[sessionID]
+	if !ok {
+		return errNoSession
+	}
+	if owner, taken := sm.aliases[alias]; taken && owner != sessionID {
+		return fmt.Errorf("%w: %q names session %s", errAliasTaken, alias, owner)
+	}
+	if _, isID := sm.sessions[alias]; isID && alias != sessionID {
+		return fmt.Errorf("%w: %q is a session ID", errAliasTaken, alias)
+	}
+	if entry.Alias != "" {
+		delete(sm.aliases, entry.Alias)
+	}
+	entry.Alias = alias
+	sm.aliases[alias] = sessionID
+	return nil
+}
+
+// Resolve returns the session ID that idOrAlias names: itself if it is a
+// live session ID, else the session it is an alias of. An unknown name is
+// returned unchanged, so not-found handling sees what the client sent.
+func (sm *SessionManager) Resolve(idOrAlias string) string {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	if _, ok := sm.sessions[idOrAlias]; ok {
+		return idOrAlias
+	}
+	if id, ok := sm.aliases[idOrAlias]; ok {
+		return id
+	}
+	return idOrAlias
+}
+
+// deleteLocked removes a session's mapping and its alias. sm.mu must be
+// held for writing.
+func (sm *SessionManager) deleteLocked(entry *SessionEntry) {
+	delete(sm.sessions, entry.SessionID)
+	if entry.Alias != "" {
+		delete(sm.aliases, entry.Alias)
+	}
+}
+
+// SetWebhooks sets the notifier for session webhooks.
+func (sm *SessionManager) SetWebhooks(n *WebhookNotifier) {
+	sm.webhooks = n
+}
+
+// Lookup returns a copy of the session's entry without refreshing its
+// last access time.
+func (sm *SessionManager) Lookup(sessionID string) (SessionEntry, bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	entry, ok := sm.sessions[sessionID]
+	if !ok {
+		return SessionEntry{}, false
+	}
+	return *entry, true
+}
+
+// List returns copies of all session entries ordered by session ID, without
+// refreshing their last access times.
+func (sm *SessionManager) List() []SessionEntry {
+	sm.mu.RLock()
+	out := make([]SessionEntry, 0, len(sm.sessions))
+	for _, entry := range sm.sessions {
+		out = append(out, *entry)
+	}
+	sm.mu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].SessionID < out[j].SessionID })
+	return out
+}
+
 // Remove deletes a session mapping and frees the worker.
 func (sm *SessionManager) Remove(sessionID string) *Worker {
 	sm.mu.Lock()
@@ -69,40 +247,153 @@ func (sm *SessionManager) Remove(sessionID string) *Worker {
 		return nil
 	}
 
-	delete(sm.sessions, sessionID)
+	sm.deleteLocked(entry)
+	return entry.Worker
+}
+
+// Lost removes a session whose worker crashed and fires its webhook.
+func (sm *SessionManager) Lost(sessionID string) *Worker {
+	sm.mu.Lock()
+	entry, ok := sm.sessions[sessionID]
+	if ok {
+		sm.deleteLocked(entry)
+	}
+	sm.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	sm.webhooks.Notify(entry.WebhookURL, sessionID, webhookWorkerCrash)
+	return entry.Worker
+}
+
+// goneSession is a tombstone left by Fail.
+type goneSession struct {
+	reason string
+	at     time.Time
+}
+
+// Fail removes a session whose worker was killed for reason (e.g.
+// "memory_limit") and remembers it for one TTL, so requests for it get 410
+// Gone with that reason rather than 404. Its webhook is fired as for Lost.
+func (sm *SessionManager) Fail(sessionID, reason string) *Worker {
+	sm.mu.Lock()
+	sm.gone[sessionID] = goneSession{reason: reason, at: sm.clk.Now()}
+	entry, ok := sm.sessions[sessionID]
+	if ok {
+		sm.deleteLocked(entry)
+	}
+	sm.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	sm.webhooks.Notify(entry.WebhookURL, sessionID, webhookWorkerCrash)
 	return entry.Worker
 }
 
-// ttlSweeper runs every 5 seconds as goroutine and expires stale sessions.
+// Gone returns the reason a session was lost, if Fail recorded it within the
+// last TTL.
+func (sm *SessionManager) Gone(sessionID string) (reason string, ok bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	g, ok := sm.gone[sessionID]
+	return g.reason, ok
+}
+
+// ttlSweeper runs every sweep interval as goroutine and expires stale sessions.
 func (sm *SessionManager) ttlSweeper() {
-	ticker := time.NewTicker(5 * time.Second)
+	ticker := sm.clk.NewTicker(sm.sweep)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for range ticker.C() {
 		sm.expireStale()
 	}
 }
 
 // expireStale removes sessions that have exceeded the TTL.
 func (sm *SessionManager) expireStale() {
+	ttl := sm.TTL()
 	sm.mu.Lock()
 	var expired []*SessionEntry
-	for id, entry := range sm.sessions {
-		if time.Since(entry.LastAccessed) > sessionTTL {
+	for _, entry := range sm.sessions {
+		if sm.clk.Since(entry.LastAccessed) > ttl {
 			expired = append(expired, entry)
-			delete(sm.sessions, id)
+			sm.deleteLocked(entry)
+		}
+	}
+	for id, g := range sm.gone {
+		if sm.clk.Since(g.at) > ttl {
+			delete(sm.gone, id)
 		}
 	}
 	sm.mu.Unlock()
 
 	// Delete expired sessions from their workers (outside the lock)
 	for _, entry := range expired {
-		log.Printf("[session] TTL expired for session %s (worker %d)", entry.SessionID, entry.Worker.ID)
-		deleteSessionFromWorker(entry.Worker, entry.SessionID)
-		entry.Worker.SetSessionID("")
+		logger("session").Info("session TTL expired", "session_id", entry.SessionID, "worker_id", entry.Worker.ID, "port", entry.Worker.Port)
+		sm.events.Record(EventSessionExpired, "session_id", entry.SessionID, "worker_id", entry.Worker.ID, "idle_for", sm.clk.Since(entry.LastAccessed).Round(time.Second).String())
+		deleteSessionFromWorker(context.Background(), entry.Worker, entry.SessionID)
+		entry.Worker.RemoveSession(entry.SessionID)
+		sm.webhooks.Notify(entry.WebhookURL, entry.SessionID, webhookTTLExpired)
+	}
+}
+
+// TTL returns the current inactivity timeout.
+func (sm *SessionManager) TTL() time.Duration {
+	return time.Duration(sm.ttl.Load())
+}
+
+// SetTTL changes the inactivity timeout; it applies to existing sessions on
+// the next sweep.
+func (sm *SessionManager) SetTTL(d time.Duration) {
+	sm.ttl.Store(int64(d))
+}
+
+// errSessionLimit is returned by Reserve when --max-sessions is reached.
+var errSessionLimit = errors.New("session limit reached")
+
+// SetMaxSessions sets the --max-sessions cap (0 = unlimited). Call it before
+// serving requests.
+func (sm *SessionManager) SetMaxSessions(n int) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.max = n
+}
+
+// MaxSessions returns the --max-sessions cap (0 = unlimited).
+func (sm *SessionManager) MaxSessions() int {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.max
+}
+
+// Reserve claims a slot under --max-sessions for a create about to acquire a
+// worker, returning errSessionLimit when live sessions and other reserved
+// creates already fill the cap. Checking and claiming under one lock is what
+// keeps concurrent creates from overshooting. The caller must call release
+// once the create has either been added or given up; release after Add
+// briefly counts the session twice, which errs on the side of the limit.
+func (sm *SessionManager) Reserve() (release func(), err error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.max > 0 && len(sm.sessions)+sm.reserved >= sm.max {
+		sm.limitRejects.Add(1)
+		return nil, errSessionLimit
 	}
+	sm.reserved++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			sm.mu.Lock()
+			sm.reserved--
+			sm.mu.Unlock()
+		})
+	}, nil
 }
 
+// LimitRejections returns how many creates Reserve has turned away.
+func (sm *SessionManager) LimitRejections() int64 { return sm.limitRejects.Load() }
+
 // Count returns the number of active sessions.
 func (sm *SessionManager) Count() int {
 	sm.mu.RLock()
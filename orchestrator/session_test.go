@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"steel-orchestrator/clock/clocktest"
+)
+
+func newTestSessions(t *testing.T, ttl, sweep time.Duration) (*SessionManager, *clocktest.Fake) {
+	t.Helper()
+	clk := clocktest.NewFake(time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC))
+	sm, err := NewSessionManager(ttl, sweep, NewEventLog(defaultEventHistorySize), clk)
+	if err != nil {
+		t.Fatalf("NewSessionManager: %v", err)
+	}
+	clk.BlockUntil(1) // the sweeper's ticker
+	return sm, clk
+}
+
+func TestSessionExpiresAfterTTL(t *testing.T) {
+	sm, clk := newTestSessions(t, time.Minute, 10*time.Second)
+	w := &Worker{ID: 1}
+	if err := sm.Add("s1", w, ""); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	// A read 50 s in refreshes the session, so it lives past the first TTL.
+	clk.Advance(50 * time.Second)
+	if sm.Get("s1") == nil {
+		t.Fatal("session expired before its TTL")
+	}
+	clk.Advance(50 * time.Second)
+	if _, ok := sm.Lookup("s1"); !ok {
+		t.Fatal("session expired 50s after it was last read, TTL is 60s")
+	}
+
+	clk.Advance(20 * time.Second)
+	waitFor(t, "session to expire", func() bool {
+		_, ok := sm.Lookup("s1")
+		return !ok
+	})
+	if sm.Count() != 0 {
+		t.Errorf("Count() = %d after expiry, want 0", sm.Count())
+	}
+}
+
+func TestSessionGoneIsForgottenAfterTTL(t *testing.T) {
+	sm, clk := newTestSessions(t, time.Minute, 10*time.Second)
+	if err := sm.Add("s1", &Worker{ID: 1}, ""); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	sm.Fail("s1", string(exitMemoryLimit))
+	if reason, ok := sm.Gone("s1"); !ok || reason != string(exitMemoryLimit) {
+		t.Fatalf("Gone(s1) = %q, %v, want %q, true", reason, ok, exitMemoryLimit)
+	}
+
+	clk.Advance(70 * time.Second)
+	waitFor(t, "tombstone to be pruned", func() bool {
+		_, ok := sm.Gone("s1")
+		return !ok
+	})
+}
+
+func TestSessionAddKeepsExistingMapping(t *testing.T) {
+	sm, _ := newTestSessions(t, time.Minute, 10*time.Second)
+	first, second := &Worker{ID: 1}, &Worker{ID: 2}
+	if err := sm.Add("s1", first, ""); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	err := sm.Add("s1", second, "")
+	if !errors.Is(err, errSessionExists) {
+		t.Fatalf("duplicate Add = %v, want errSessionExists", err)
+	}
+	if got := sm.Get("s1"); got != first {
+		t.Errorf("s1 maps to worker %d after a duplicate Add, want %d", got.ID, first.ID)
+	}
+	if sm.Count() != 1 {
+		t.Errorf("Count() = %d, want 1", sm.Count())
+	}
+}
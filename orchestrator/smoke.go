@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// smokeTestCreate is the --ready-smoke-test value that creates a session
+// and deletes it again; any other non-empty value is a path to GET.
+const smokeTestCreate = "create"
+
+// smokeTest checks that a worker which has just passed its health probe can
+// actually serve, catching the "healthy but useless" worker whose HTTP
+// server is up while its browser failed to launch. With "create" it creates
+// a session and deletes it at once; with a path it expects a 2xx from GET.
+// It returns nil when no smoke test is configured.
+func (w *Worker) smokeTest() error {
+	switch w.smoke {
+	case "":
+		return nil
+	case smokeTestCreate:
+		ctx := context.Background()
+		body, code, err := forwardCreateSession(ctx, w, []byte("{}"))
+		if err != nil {
+			return err
+		}
+		if code/100 != 2 {
+			return fmt.Errorf("create answered %d", code)
+		}
+		var s struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(body, &s); err != nil || s.ID == "" {
+			return fmt.Errorf("create returned no session id")
+		}
+		code, err = deleteSessionFromWorker(ctx, w, s.ID)
+		if err != nil {
+			return err
+		}
+		if code/100 != 2 {
+			return fmt.Errorf("delete of smoke-test session %s answered %d", s.ID, code)
+		}
+		return nil
+	default:
+		ctx, cancel := context.WithTimeout(context.Background(), workerTimeouts.Get)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.BaseURL()+w.smoke, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("GET %s answered %d", w.smoke, resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+// failSmokeTest kills a worker whose smoke test failed, unless it has
+// restarted or exited since. The exit is reported as exitSmokeFailed and
+// restarts like a crash, so a worker that keeps failing it is eventually
+// replaced as crash-looping.
+func (w *Worker) failSmokeTest(gen uint64, err error) {
+	w.mu.Lock()
+	if w.gen != gen || w.state != WorkerStateStarting {
+		w.mu.Unlock()
+		return
+	}
+	w.state = WorkerStateUnhealthy
+	w.smokeFailed = true
+	w.mu.Unlock()
+
+	w.logger().Error("passed its health probe but failed the smoke test — killing", "smoke_test", w.smoke, "error", err)
+	if w.pool != nil {
+		w.pool.smokeFailures.Add(1)
+	}
+	w.Kill("smoke test failed")
+}
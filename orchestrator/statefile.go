@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stateSnapshotInterval is how often the session map is written to --state-file.
+const stateSnapshotInterval = 5 * time.Second
+
+// stateFileVersion is bumped whenever persistedState changes incompatibly.
+const stateFileVersion = 1
+
+// persistedState is the on-disk form of --state-file.
+type persistedState struct {
+	Version  int                `json:"version"`
+	SavedAt  time.Time          `json:"saved_at"`
+	Sessions []persistedSession `json:"sessions"`
+}
+
+// persistedSession is enough to find a worker again after a restart.
+type persistedSession struct {
+	SessionID    string    `json:"session_id"`
+	Port         int       `json:"port"`
+	PID          int       `json:"pid"`
+	Pool         string    `json:"pool,omitempty"` // --worker-pool tag; absent in older files = default
+	LastAccessed time.Time `json:"last_accessed"`
+	WebhookURL   string    `json:"webhook_url,omitempty"`
+	Alias        string    `json:"alias,omitempty"`
+}
+
+// SaveState writes every session mapping to path. The file is replaced
+// atomically, so a crash mid-write leaves the previous snapshot intact.
+func (sm *SessionManager) SaveState(path string) error {
+	st := persistedState{Version: stateFileVersion, SavedAt: sm.clk.Now().UTC(), Sessions: []persistedSession{}}
+	for _, e := range sm.List() {
+		st.Sessions = append(st.Sessions, persistedSession{
+			SessionID:    e.SessionID,
+			Port:         e.Worker.Port,
+			PID:          e.Worker.PID(),
+			Pool:         e.Worker.Tag(),
+			LastAccessed: e.LastAccessed,
+			WebhookURL:   e.WebhookURL,
+			Alias:        e.Alias,
+		})
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp state file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace state file: %w", err)
+	}
+	return nil
+}
+
+// RunStateSnapshots saves the session map to path every
+// stateSnapshotInterval until the process exits.
+func (sm *SessionManager) RunStateSnapshots(path string) {
+	ticker := sm.clk.NewTicker(stateSnapshotInterval)
+	defer ticker.Stop()
+
+	for range ticker.C() {
+		if err := sm.SaveState(path); err != nil {
+			logger("session").Warn("state snapshot failed", "path", path, "error", err)
+		}
+	}
+}
+
+// restoreState re-attaches the sessions recorded in path to the workers that
+// are still alive and healthy. Entries whose worker is gone are dropped. A
+// missing file is not an error: it is the first run. Each worker rejoins the
+// pool it was saved from; one whose --worker-pool no longer exists is dropped.
+func restoreState(path string, pools *PoolSet, sessions *SessionManager) (restored, dropped int, err error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("read state file: %w", err)
+	}
+	var st persistedState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return 0, 0, fmt.Errorf("parse state file %s: %w", path, err)
+	}
+	if st.Version != stateFileVersion {
+		return 0, 0, fmt.Errorf("state file %s has version %d, want %d", path, st.Version, stateFileVersion)
+	}
+
+	// A worker holding several sessions is adopted once, with all of them.
+	type process struct {
+		pool      string
+		port, pid int
+	}
+	var order []process
+	byProcess := make(map[process][]persistedSession)
+	for _, s := range st.Sessions {
+		k := process{s.Pool, s.Port, s.PID}
+		if _, seen := byProcess[k]; !seen {
+			order = append(order, k)
+		}
+		byProcess[k] = append(byProcess[k], s)
+	}
+
+	log := logger("session")
+	for _, k := range order {
+		group := byProcess[k]
+		ids := make([]string, len(group))
+		for i, s := range group {
+			ids[i] = s.SessionID
+		}
+		pool, ok := pools.Get(k.pool)
+		if !ok {
+			log.Warn("dropping persisted sessions", "session_ids", ids, "port", k.port, "pid", k.pid, "pool", k.pool, "error", "no such worker pool")
+			dropped += len(group)
+			continue
+		}
+		w, err := pool.Adopt(ids, k.port, k.pid)
+		if err != nil {
+			log.Warn("dropping persisted sessions", "session_ids", ids, "port", k.port, "pid", k.pid, "error", err)
+			dropped += len(group)
+			continue
+		}
+		for _, s := range group {
+			sessions.Restore(s.SessionID, w, s.LastAccessed, s.WebhookURL, s.Alias)
+		}
+		restored += len(group)
+	}
+	return restored, dropped, nil
+}
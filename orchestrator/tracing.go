@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Tracing is a minimal OpenTelemetry-compatible tracer: spans are exported
+// in OTLP/HTTP JSON to --otel-endpoint, and span context travels to workers
+// in W3C traceparent headers. Every span is sampled. With no endpoint the
+// package-level tracer is nil and every call below returns at once.
+
+const (
+	// traceBatchSize is the most spans sent in one export request.
+	traceBatchSize = 512
+	// traceFlushInterval bounds how long a finished span waits for export.
+	traceFlushInterval = 2 * time.Second
+	// traceQueueSize bounds finished spans waiting for export; past it new
+	// spans are dropped rather than blocking a request.
+	traceQueueSize = 4096
+	// traceExportTimeout bounds one export request.
+	traceExportTimeout = 5 * time.Second
+)
+
+// tracer is set once at startup from --otel-endpoint; nil disables tracing.
+var tracer *Tracer
+
+// Tracer batches finished spans and posts them to an OTLP/HTTP collector.
+// A nil *Tracer is valid and records nothing.
+type Tracer struct {
+	url     string
+	spans   chan *span
+	dropped atomic.Int64
+	done    chan struct{}
+}
+
+// newTracer starts exporting to endpoint, the collector's base URL (for
+// example http://otel-collector:4318); /v1/traces is added unless present.
+func newTracer(endpoint string) *Tracer {
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	t := &Tracer{url: url, spans: make(chan *span, traceQueueSize), done: make(chan struct{})}
+	go t.exportLoop()
+	return t
+}
+
+// Shutdown exports the spans still queued, giving up when ctx is done.
+func (t *Tracer) Shutdown(ctx context.Context) {
+	if t == nil {
+		return
+	}
+	close(t.spans)
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+		logger("tracing").Warn("gave up exporting queued spans", "error", ctx.Err())
+	}
+}
+
+func (t *Tracer) exportLoop() {
+	defer close(t.done)
+	ticker := time.NewTicker(traceFlushInterval)
+	defer ticker.Stop()
+	batch := make([]*span, 0, traceBatchSize)
+	for {
+		select {
+		case s, ok := <-t.spans:
+			if !ok {
+				t.export(batch)
+				return
+			}
+			batch = append(batch, s)
+			if len(batch) < traceBatchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+		t.export(batch)
+		batch = batch[:0]
+	}
+}
+
+// export posts one batch. Failures are logged and the batch is dropped:
+// tracing must never hold up or fail the requests it describes.
+func (t *Tracer) export(batch []*span) {
+	if n := t.dropped.Swap(0); n > 0 {
+		logger("tracing").Warn("dropped spans — export queue full", "dropped", n)
+	}
+	if len(batch) == 0 {
+		return
+	}
+	spans := make([]otlpSpan, len(batch))
+	for i, s := range batch {
+		spans[i] = s.otlp()
+	}
+	body, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpKeyValue{otlpAttr("service.name", traceServiceName)}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: traceServiceName}, Spans: spans}},
+	}}})
+	if err != nil {
+		logger("tracing").Warn("failed to encode spans", "spans", len(batch), "error", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), traceExportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		logger("tracing").Warn("failed to build span export request", "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		logger("tracing").Warn("span export failed", "url", t.url, "spans", len(batch), "error", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		logger("tracing").Warn("span export rejected", "url", t.url, "spans", len(batch), "status", resp.StatusCode)
+	}
+}
+
+// traceServiceName is the service.name resource attribute on every span.
+const traceServiceName = "steel-orchestrator"
+
+// spanKind values from the OTLP protocol.
+const (
+	spanKindInternal = 1
+	spanKindServer   = 2
+	spanKindClient   = 3
+)
+
+// span is one timed operation. A nil *span is valid and records nothing, so
+// call sites need no tracing-enabled checks.
+type span struct {
+	tracer  *Tracer
+	traceID [16]byte
+	spanID  [8]byte
+	parent  [8]byte // zero for a root span
+	name    string
+	kind    int
+	start   time.Time
+	end     time.Time
+	attrs   []any // alternating key, value
+	err     string
+}
+
+type spanKey struct{}
+
+// startSpan starts a span named name as a child of the span in ctx (a new
+// trace if there is none) and returns a context carrying it. kv are
+// alternating attribute keys and values.
+func startSpan(ctx context.Context, name string, kind int, kv ...any) (context.Context, *span) {
+	if tracer == nil {
+		return ctx, nil
+	}
+	s := &span{tracer: tracer, name: name, kind: kind, start: time.Now(), attrs: kv}
+	if parent, ok := ctx.Value(spanKey{}).(*span); ok {
+		s.traceID, s.parent = parent.traceID, parent.spanID
+	} else {
+		_, _ = rand.Read(s.traceID[:])
+	}
+	_, _ = rand.Read(s.spanID[:])
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// SetAttr adds attributes, as alternating keys and values.
+func (s *span) SetAttr(kv ...any) {
+	if s == nil {
+		return
+	}
+	s.attrs = append(s.attrs, kv...)
+}
+
+// SetError marks the span failed. A nil err is ignored.
+func (s *span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.err = err.Error()
+}
+
+// End finishes the span and queues it for export.
+func (s *span) End() {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	select {
+	case s.tracer.spans <- s:
+	default:
+		s.tracer.dropped.Add(1)
+	}
+}
+
+// traceparent formats the W3C traceparent header for s.
+func (s *span) traceparent() string {
+	return "00-" + hex.EncodeToString(s.traceID[:]) + "-" + hex.EncodeToString(s.spanID[:]) + "-01"
+}
+
+// parseTraceparent extracts the trace and parent span IDs from a W3C
+// traceparent header.
+func parseTraceparent(h string) (traceID [16]byte, spanID [8]byte, ok bool) {
+	parts := strings.Split(strings.TrimSpace(h), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return traceID, spanID, false
+	}
+	if _, err := hex.Decode(traceID[:], []byte(parts[1])); err != nil || traceID == [16]byte{} {
+		return traceID, spanID, false
+	}
+	if _, err := hex.Decode(spanID[:], []byte(parts[2])); err != nil || spanID == [8]byte{} {
+		return traceID, spanID, false
+	}
+	return traceID, spanID, true
+}
+
+// injectTraceparent adds the traceparent header for the span in ctx to an
+// outgoing worker request.
+func injectTraceparent(ctx context.Context, req *http.Request) {
+	if tracer == nil {
+		return
+	}
+	if s, ok := ctx.Value(spanKey{}).(*span); ok {
+		req.Header.Set("traceparent", s.traceparent())
+	}
+}
+
+// serveTraced runs h inside a server span named name, continuing the trace
+// from the request's traceparent header if it has one, and records the
+// response status. With tracing off it just calls h.
+func serveTraced(w http.ResponseWriter, r *http.Request, name string, h http.HandlerFunc) {
+	if tracer == nil {
+		h(w, r)
+		return
+	}
+	ctx := r.Context()
+	if traceID, spanID, ok := parseTraceparent(r.Header.Get("traceparent")); ok {
+		// A remote parent: a span that only carries the IDs, never ended.
+		ctx = context.WithValue(ctx, spanKey{}, &span{traceID: traceID, spanID: spanID})
+	}
+	ctx, s := startSpan(ctx, name, spanKindServer,
+		"http.request.method", r.Method, "url.path", r.URL.Path, "request_id", requestID(ctx))
+	defer s.End()
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	h(rec, r.WithContext(ctx))
+	s.SetAttr("http.response.status_code", rec.status)
+	if rec.status >= http.StatusInternalServerError {
+		s.SetError(fmt.Errorf("HTTP %d", rec.status))
+	}
+}
+
+// statusRecorder remembers the status code written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter { return r.ResponseWriter }
+
+// OTLP/HTTP JSON encoding, just the parts the orchestrator emits.
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"` // 0 unset, 2 error
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func (s *span) otlp() otlpSpan {
+	o := otlpSpan{
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+		SpanID:            hex.EncodeToString(s.spanID[:]),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+	}
+	if s.parent != [8]byte{} {
+		o.ParentSpanID = hex.EncodeToString(s.parent[:])
+	}
+	for i := 0; i+1 < len(s.attrs); i += 2 {
+		o.Attributes = append(o.Attributes, otlpAttr(fmt.Sprint(s.attrs[i]), s.attrs[i+1]))
+	}
+	if s.err != "" {
+		o.Status = otlpStatus{Code: 2, Message: s.err}
+	}
+	return o
+}
+
+// otlpAttr encodes one attribute as an OTLP AnyValue.
+func otlpAttr(key string, v any) otlpKeyValue {
+	var val map[string]any
+	switch v := v.(type) {
+	case string:
+		val = map[string]any{"stringValue": v}
+	case bool:
+		val = map[string]any{"boolValue": v}
+	case int:
+		val = map[string]any{"intValue": strconv.Itoa(v)}
+	case int64:
+		val = map[string]any{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		val = map[string]any{"doubleValue": v}
+	default:
+		val = map[string]any{"stringValue": fmt.Sprint(v)}
+	}
+	return otlpKeyValue{Key: key, Value: val}
+}
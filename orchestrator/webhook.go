@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+// Session webhooks tell a client its session is gone before it finds out
+// from a 404: a create may carry "webhook_url", which is POSTed to when the
+// session is reaped by the TTL sweeper or lost with its worker.
+const (
+	// webhookQueueSize bounds notifications waiting to be sent; past it new
+	// ones are dropped rather than blocking the sweeper or crash handler.
+	webhookQueueSize = 256
+	// webhookSenders is how many notifications are sent at once.
+	webhookSenders = 4
+	// webhookTimeout bounds one webhook request.
+	webhookTimeout = 5 * time.Second
+)
+
+// Reasons sent in a session webhook.
+const (
+	webhookTTLExpired  = "ttl_expired"
+	webhookWorkerCrash = "worker_crash"
+)
+
+// webhookEvent is the body POSTed to a session's webhook_url.
+type webhookEvent struct {
+	url       string
+	SessionID string    `json:"session_id"`
+	Reason    string    `json:"reason"`
+	Time      time.Time `json:"time"`
+}
+
+// WebhookNotifier sends session webhooks from a small pool of senders, fire
+// and forget: failures are logged and never retried. A nil *WebhookNotifier
+// is valid and sends nothing.
+type WebhookNotifier struct {
+	queue   chan webhookEvent
+	dropped atomic.Int64
+}
+
+// newWebhookNotifier starts the senders.
+func newWebhookNotifier() *WebhookNotifier {
+	n := &WebhookNotifier{queue: make(chan webhookEvent, webhookQueueSize)}
+	for range webhookSenders {
+		go n.sendLoop()
+	}
+	return n
+}
+
+// Notify queues a webhook to target for sessionID; a "" target is ignored.
+// It never blocks: with the queue full the notification is dropped.
+func (n *WebhookNotifier) Notify(target, sessionID, reason string) {
+	if n == nil || target == "" {
+		return
+	}
+	select {
+	case n.queue <- webhookEvent{url: target, SessionID: sessionID, Reason: reason, Time: time.Now().UTC()}:
+	default:
+		n.dropped.Add(1)
+		logger("webhook").Warn("dropped session webhook — queue full", "session_id", sessionID, "reason", reason)
+	}
+}
+
+func (n *WebhookNotifier) sendLoop() {
+	for ev := range n.queue {
+		n.send(ev)
+	}
+}
+
+func (n *WebhookNotifier) send(ev webhookEvent) {
+	log := logger("webhook").With("session_id", ev.SessionID, "reason", ev.Reason, "url", ev.url)
+	body, err := json.Marshal(ev)
+	if err != nil {
+		log.Warn("failed to encode session webhook", "error", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ev.url, bytes.NewReader(body))
+	if err != nil {
+		log.Warn("failed to build session webhook", "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		log.Warn("session webhook failed", "error", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		log.Warn("session webhook rejected", "status", resp.StatusCode)
+		return
+	}
+	log.Debug("session webhook sent")
+}
+
+// checkWebhookURL accepts an absolute http or https URL.
+func checkWebhookURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("webhook_url must be an absolute http or https URL")
+	}
+	return nil
+}
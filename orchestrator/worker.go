@@ -1,13 +1,20 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/exec"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+
+	"steel-orchestrator/clock"
 )
 
 type WorkerState int
@@ -18,6 +25,7 @@ const (
 	WorkerStateBusy
 	WorkerStateUnhealthy
 	WorkerStateDead
+	WorkerStateFailed // crash-looped and was removed from the pool for good
 )
 
 func (s WorkerState) String() string {
@@ -32,41 +40,196 @@ func (s WorkerState) String() string {
 		return "unhealthy"
 	case WorkerStateDead:
 		return "dead"
+	case WorkerStateFailed:
+		return "failed"
 	default:
 		return "unknown"
 	}
 }
 
+// LaunchConfig describes how to start a steel-browser process.
+type LaunchConfig struct {
+	BinaryPath string
+	Args       []string // extra command-line arguments, e.g. --headless
+	Env        []string // extra KEY=VALUE pairs appended to the inherited environment
+	Limits     ResourceLimits
+
+	// ReadyTimeout is how long a freshly started process has to pass its
+	// health probe before it is marked unhealthy.
+	ReadyTimeout time.Duration
+
+	// Health is used by both the readiness poll and the periodic health loop.
+	Health HealthProbe
+
+	// SmokeTest, if set, is run once the health probe passes and must
+	// succeed before the worker is marked Available: "create", or a path.
+	SmokeTest string
+}
+
+// HealthProbe describes how to ask a worker whether it is healthy.
+// The zero value means GET /health expecting 200.
+type HealthProbe struct {
+	Path     string
+	Statuses []int // response codes that count as healthy
+}
+
+// path returns the probe path, defaulting to /health.
+func (h HealthProbe) path() string {
+	if h.Path == "" {
+		return "/health"
+	}
+	return h.Path
+}
+
+// healthy reports whether a probe response code counts as healthy.
+func (h HealthProbe) healthy(code int) bool {
+	if len(h.Statuses) == 0 {
+		return code == http.StatusOK
+	}
+	return slices.Contains(h.Statuses, code)
+}
+
+// readyPollInterval is the gap between health probes while a worker boots.
+const readyPollInterval = 200 * time.Millisecond
+
 // Worker represents a single steel-browser process.
 type Worker struct {
 	ID         int
 	Port       int
-	BinaryPath string
+	BinaryPath string // set from the pool's rollout on every Start
+	Args       []string
+	Env        []string
+	Limits     ResourceLimits
+
+	readyTimeout time.Duration
+	health       HealthProbe
+	smoke        string // post-ready smoke test; see smokeTest
 
 	mu        sync.Mutex
-	cmd       *exec.Cmd
+	cmd       *exec.Cmd   // nil for a worker adopted from a previous orchestrator
+	proc      *os.Process // the worker process, whether spawned or adopted
 	state     WorkerState
-	sessionID string // current session held by this worker
-	pool      *Pool  // back-reference to the pool for Release
+	sessions  []string  // sessions held by this worker, oldest first
+	claims    int       // slots taken by Acquire callers whose session is not added yet
+	capacity  int       // concurrent sessions the worker can hold (--sessions-per-worker)
+	readyAt   time.Time // when the worker last passed waitForReady
+	startedAt time.Time // when the current process was spawned or adopted
+	idleSince time.Time // when the worker last went without sessions or claims; zero while it holds any
+	gen       uint64    // bumped by every Start; ties a waitForReady to its process
+	pool      *Pool     // back-reference to the pool for Release
 
-	// OnCrash is called when the worker crashes with an active session.
-	// The callback receives the session ID so the session manager can clean up.
-	OnCrash func(sessionID string)
+	// OnCrash is called when the worker crashes with active sessions.
+	// The callback receives every session lost so the session manager can
+	// clean up, and how the worker died.
+	OnCrash func(sessionIDs []string, kind exitKind)
 
-	// draining indicates this worker should not be restarted after it exits.
-	// Set by the pool during scale-down or graceful shutdown.
+	// draining retires the worker: it is not restarted after it exits, Start
+	// refuses it, and it is never released back to the pool. Set by Drain
+	// and Stop, and never cleared.
 	draining bool
+
+	// retiring is set on a busy worker that has outlived the pool's max
+	// age, or is due in a rolling restart: when its last session ends it is
+	// restarted instead of re-queued. retireReason says which. Reset on
+	// every Start.
+	retiring     bool
+	retireReason string
+
+	// surplus marks a worker above a lowered max: when its last session ends
+	// it is removed from the pool instead of re-queued. Set and cleared by
+	// Pool.SetMax.
+	surplus bool
+
+	// cordoned keeps the worker out of the available queue, so it takes no
+	// new sessions but keeps the ones it has, across restarts. Set by
+	// Pool.Cordon and cleared by Pool.Uncordon.
+	cordoned bool
+
+	// quarantinedAt is when a failed health probe took the worker out of the
+	// available queue for Pool.watchQuarantine to re-probe; zero when not
+	// quarantined. Cleared on re-admission and by Start.
+	quarantinedAt time.Time
+
+	// queued is true while the worker sits in the pool's available queue.
+	// Guarded by mu together with draining so Release cannot enqueue a
+	// worker twice or enqueue one that is being removed.
+	queued bool
+
+	// intentionalStop is set by Stop before killing so monitor treats the exit
+	// as deliberate: no crash event, no restart, no restart delay.
+	intentionalStop bool
+
+	// killRequested is set by Kill so monitor can tell our own SIGKILL apart
+	// from one sent by the kernel OOM killer. Reset on every Start.
+	killRequested bool
+	oomBaseline   int64 // cgroup oom_kill count at Start
+
+	// memoryKill marks the pending Kill as a memory-limit breach so the exit
+	// is reported as exitMemoryLimit. Reset on every Start.
+	memoryKill bool
+	rss        int64 // resident set size in bytes at the last sample; 0 = unknown
+
+	// notReady marks the pending Kill as a readiness timeout so the exit is
+	// reported as exitNotReady. Reset on every Start.
+	notReady bool
+
+	// smokeFailed marks the pending Kill as a failed smoke test so the exit
+	// is reported as exitSmokeFailed. Reset on every Start.
+	smokeFailed bool
+
+	// CPU usage, sampled every health sweep. cpuUsed is the process's
+	// cumulative user+system time as of cpuAt; cpuPercent is the usage
+	// between the last two samples (100 = one core). Reset on every Start.
+	cpuUsed    time.Duration
+	cpuAt      time.Time
+	cpuPercent float64
+
+	lastProbe  probeResult // the most recent health probe, across restarts
+	deepStreak int         // failed deep checks in a row; reset by a passing one or Start
+
+	requests atomic.Int64 // requests proxied to this worker, across restarts
+
+	// restartCount counts restart attempts by monitor over the worker's
+	// life; unlike failures it is never reset.
+	restartCount int
+
+	// restartTokens is the worker's restart budget under
+	// --max-restarts-per-minute, refilled continuously since restartRefill.
+	restartTokens float64
+	restartRefill time.Time
+
+	// failures counts unplanned exits and failed restarts in a row; a
+	// process that ran for crashLoopWindow starts a fresh count. lastError
+	// describes the most recent one.
+	failures  int
+	lastError string
+
+	// exited is closed when the current process exits; each Start and
+	// adoption replaces it. nil until the worker first runs.
+	exited chan struct{}
 }
 
 // NewWorker creates a new worker instance (does not start it).
-func NewWorker(id, port int, binaryPath string, pool *Pool) *Worker {
-	return &Worker{
+func NewWorker(id, port int, launch LaunchConfig, pool *Pool) *Worker {
+	w := &Worker{
 		ID:         id,
 		Port:       port,
-		BinaryPath: binaryPath,
-		state:      WorkerStateDead,
-		pool:       pool,
+		BinaryPath: launch.BinaryPath,
+		Args:       launch.Args,
+		Env:        launch.Env,
+		Limits:     launch.Limits,
+
+		readyTimeout: launch.ReadyTimeout,
+		health:       launch.Health,
+		smoke:        launch.SmokeTest,
+		state:        WorkerStateDead,
+		capacity:     1,
+		pool:         pool,
+	}
+	if pool != nil && pool.sessionsPerWorker > 1 {
+		w.capacity = pool.sessionsPerWorker
 	}
+	return w
 }
 
 // Start spawns the steel-browser process and begins monitoring it.
@@ -77,84 +240,369 @@ func (w *Worker) Start() error {
 	if w.state != WorkerStateDead && w.state != WorkerStateUnhealthy {
 		return fmt.Errorf(":%-5d already running (state=%s)", w.Port, w.state)
 	}
+	if w.draining {
+		return fmt.Errorf(":%-5d has been drained or stopped", w.Port)
+	}
 
-	cmd := exec.Command(w.BinaryPath)
-	cmd.Env = append(os.Environ(), fmt.Sprintf("PORT=%d", w.Port))
+	if w.pool != nil {
+		w.BinaryPath = w.pool.nextBinary()
+	}
+	cmd := exec.Command(w.BinaryPath, w.Args...)
+	// PORT goes last so a stray PORT in the extra env cannot override it.
+	cmd.Env = append(os.Environ(), w.Env...)
+	cmd.Env = append(cmd.Env, fmt.Sprintf("PORT=%d", w.Port))
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	if err := cmd.Start(); err != nil {
+	cleanup, err := w.prepareLimits(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to apply resource limits for :%-5d: %w", w.Port, err)
+	}
+	err = cmd.Start()
+	cleanup()
+	if err != nil {
 		return fmt.Errorf("failed to start :%-5d: %w", w.Port, err)
 	}
+	if err := w.applyLimits(cmd.Process.Pid); err != nil {
+		w.logger().Warn("could not apply resource limits", "pid", cmd.Process.Pid, "error", err)
+	}
 
 	w.cmd = cmd
+	w.proc = cmd.Process
+	w.exited = make(chan struct{})
+	w.killRequested = false
+	w.memoryKill = false
+	w.notReady = false
+	w.smokeFailed = false
+	w.rss = 0
+	w.cpuUsed, w.cpuAt, w.cpuPercent = 0, time.Time{}, 0
+	w.retiring, w.retireReason = false, ""
+	w.quarantinedAt = time.Time{}
+	w.lastProbe.Streak, w.deepStreak = 0, 0
+	w.startedAt = w.clock().Now()
 	w.state = WorkerStateStarting
-	w.sessionID = ""
+	w.sessions, w.claims = nil, 0
+	w.gen++
+	gen := w.gen
 
-	log.Printf("[worker :%-5d] starting (pid=%d)", w.Port, cmd.Process.Pid)
+	w.logger().Info("starting", "pid", cmd.Process.Pid)
+	w.events().Record(EventWorkerStarted, "worker_id", w.ID, "port", w.Port, "pid", cmd.Process.Pid)
+	w.audit().Record(AuditWorkerStart, w, "", "pid", cmd.Process.Pid)
 
 	// Monitor for process exit in background
 	go w.monitor()
 
 	// Wait for the worker to become healthy
-	go w.waitForReady()
+	go w.waitForReady(gen)
 
 	return nil
 }
 
+// exitKind classifies why a worker process exited.
+type exitKind string
+
+const (
+	exitIntentional exitKind = "intentional"       // Stop() during drain, shutdown or scale-down
+	exitRecycled    exitKind = "recycled"          // Kill() after a failed health check or forward
+	exitOOM         exitKind = "oom"               // terminated by the kernel OOM killer
+	exitMemoryLimit exitKind = "memory_limit"      // Kill() after RSS exceeded the pool's memory limit
+	exitNotReady    exitKind = "not_ready"         // Kill() after the process never passed its readiness probe
+	exitSmokeFailed exitKind = "smoke_test_failed" // Kill() after the process passed its health probe but failed the smoke test
+	exitCrash       exitKind = "crash"             // non-zero exit or signal we did not send
+)
+
+// exitInfo describes a process exit as observed by monitor.
+type exitInfo struct {
+	Kind   exitKind
+	Code   int    // exit code, or -1 if the process was signaled
+	Signal string // terminating signal, if any
+}
+
+// classifyExit inspects the cmd.Wait error together with what the
+// orchestrator itself asked for to decide how the worker died.
+func classifyExit(err error, intentional, killRequested, oom bool) exitInfo {
+	info := exitInfo{Kind: exitCrash}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		info.Code = exitErr.ExitCode()
+	}
+	if sig, ok := exitSignal(err); ok {
+		info.Signal = sig.String()
+	}
+
+	switch {
+	case intentional:
+		info.Kind = exitIntentional
+	case oom:
+		info.Kind = exitOOM
+	case killRequested:
+		info.Kind = exitRecycled
+	}
+	return info
+}
+
 // monitor waits for the process to exit and handles restart.
 func (w *Worker) monitor() {
-	err := w.cmd.Wait()
+	w.handleExit(w.cmd.Wait())
+}
 
+// errAdoptedExited is the exit "error" of an adopted worker. It is not our
+// child, so its real exit status is not available.
+var errAdoptedExited = errors.New("adopted worker process exited")
+
+// adoptedPollInterval is how often watchAdopted checks that the process is alive.
+const adoptedPollInterval = time.Second
+
+// watchAdopted stands in for monitor on a worker adopted from a previous
+// orchestrator: the process cannot be waited on, so its exit is detected by
+// polling.
+func (w *Worker) watchAdopted() {
+	for processAlive(w.proc) {
+		w.clock().Sleep(adoptedPollInterval)
+	}
+	w.handleExit(errAdoptedExited)
+}
+
+// processAlive reports whether p still exists, via the null signal.
+func processAlive(p *os.Process) bool {
+	return p.Signal(syscall.Signal(0)) == nil
+}
+
+// handleExit cleans up after the process has exited and restarts it unless
+// the exit was intentional or the worker is draining.
+func (w *Worker) handleExit(err error) {
 	w.mu.Lock()
-	prevSession := w.sessionID
+	prevSessions := w.sessions
+	killRequested := w.killRequested
+	memoryKill := w.memoryKill
+	notReady := w.notReady
+	smokeFailed := w.smokeFailed
+	intentional := w.intentionalStop
+	isDraining := w.draining
 	w.state = WorkerStateDead
-	w.sessionID = ""
+	w.sessions, w.claims = nil, 0
+	if w.exited != nil {
+		close(w.exited)
+	}
 	w.mu.Unlock()
+	if w.pool != nil {
+		w.pool.unqueueDead(w)
+	}
+
+	uptime := w.clock().Since(w.StartedAt())
+	exit := classifyExit(err, intentional, killRequested, w.oomKilled(err, killRequested))
+	switch {
+	case exit.Kind == exitRecycled && memoryKill:
+		exit.Kind = exitMemoryLimit
+	case exit.Kind == exitRecycled && notReady:
+		exit.Kind = exitNotReady
+	case exit.Kind == exitRecycled && smokeFailed:
+		exit.Kind = exitSmokeFailed
+	}
+	log := w.logger().With("exit_kind", string(exit.Kind), "exit_code", exit.Code, "signal", exit.Signal)
 
-	if prevSession != "" {
-		log.Printf("[worker :%-5d] crashed with active session %s", w.Port, prevSession)
-		// Notify session manager to clean up the stale mapping
+	if len(prevSessions) > 0 {
+		log.Warn("exited with active sessions", "session_ids", prevSessions)
+		// Notify session manager to clean up the stale mappings
 		if w.OnCrash != nil {
-			w.OnCrash(prevSession)
+			w.OnCrash(prevSessions, exit.Kind)
 		}
 	}
-	w.mu.Lock()
-	isDraining := w.draining
-	w.mu.Unlock()
 
+	// Intentional stops skip the restart path (and its sleep) entirely.
+	if exit.Kind == exitIntentional {
+		log.Info("stopped")
+		return
+	}
 	if isDraining {
-		log.Printf("[worker :%-5d] draining — not restarting", w.Port)
+		log.Info("draining — not restarting")
 		return
 	}
 
-	log.Printf("[worker :%-5d] process exited: %v — restarting in 1s", w.Port, err)
+	if w.pool != nil {
+		w.pool.counters.crashes.Add(1)
+	}
+	attempts := w.noteFailure(fmt.Sprintf("%s: %v", exit.Kind, err), uptime >= crashLoopWindow)
+	delay := w.restartBackoff(attempts)
+	log = log.With("attempt", attempts)
+	if !w.crashLooped(attempts) {
+		log = log.With("delay", delay)
+	}
+	switch exit.Kind {
+	case exitOOM:
+		log.Error("killed by OOM killer (memory limit exceeded) — restarting", "memory_limit_bytes", w.Limits.MemoryBytes)
+	case exitRecycled:
+		log.Info("killed for recycling — restarting")
+	case exitMemoryLimit:
+		log.Warn("killed for exceeding the memory limit — restarting")
+	case exitNotReady:
+		log.Warn("killed after never becoming ready — restarting")
+	case exitSmokeFailed:
+		log.Warn("killed after failing its smoke test — restarting")
+	default:
+		log.Warn("process crashed — restarting", "error", err)
+	}
+	w.events().Record(EventWorkerCrashed, "worker_id", w.ID, "port", w.Port, "error", fmt.Sprint(err), "session_ids", prevSessions,
+		"exit_kind", string(exit.Kind), "exit_code", exit.Code, "signal", exit.Signal, "attempt", attempts)
+	w.audit().Record(AuditWorkerCrash, w, string(exit.Kind), "error", fmt.Sprint(err), "session_ids", prevSessions,
+		"exit_code", exit.Code, "signal", exit.Signal, "attempt", attempts)
+
+	for {
+		if w.crashLooped(attempts) {
+			w.pool.replaceFailed(w, attempts, failedCrashLoop)
+			return
+		}
+		w.clock().Sleep(delay)
+		if w.isDraining() {
+			// Stopped (e.g. by shutdown) during the restart delay.
+			log.Info("stopped during restart delay — not restarting")
+			return
+		}
+		if !w.takeRestartToken() {
+			w.pool.replaceFailed(w, attempts, failedRestartRate)
+			return
+		}
+
+		w.mu.Lock()
+		w.restartCount++
+		w.mu.Unlock()
+		err := w.Start()
+		if err == nil {
+			break
+		}
+		attempts = w.noteFailure(fmt.Sprintf("restart: %v", err), false)
+		delay = w.restartBackoff(attempts)
+		w.logger().Error("failed to restart", "error", err, "attempt", attempts, "delay", delay)
+	}
+	if w.pool != nil {
+		w.pool.counters.restarts.Add(1)
+	}
+	w.events().Record(EventWorkerRestarted, "worker_id", w.ID, "port", w.Port, "attempt", attempts)
+	w.audit().Record(AuditWorkerRestart, w, string(exit.Kind), "attempt", attempts)
+}
+
+// Restart backoff: the first restart waits --restart-delay (restartBaseDelay
+// by default), each further consecutive failure doubles it up to
+// restartMaxDelay, and every delay is jittered by restartJitter so workers
+// failing together spread out.
+const (
+	restartBaseDelay = time.Second
+	restartMaxDelay  = 30 * time.Second
+	restartJitter    = 0.2
+)
 
-	time.Sleep(1 * time.Second)
+// crashLoopWindow is how long a process must run before its exit no longer
+// counts toward its worker's consecutive failures.
+const crashLoopWindow = time.Minute
 
-	if err := w.Start(); err != nil {
-		log.Printf("[worker :%-5d] failed to restart: %v", w.Port, err)
+// restartBackoff returns the delay before restart attempt n (1-based).
+func (w *Worker) restartBackoff(n int) time.Duration {
+	d := restartBaseDelay
+	if w.pool != nil {
+		d = w.pool.restartDelay
 	}
+	for i := 1; i < n && d < restartMaxDelay; i++ {
+		d *= 2
+	}
+	return jittered(min(d, restartMaxDelay), restartJitter)
 }
 
-// waitForReady polls /health until the worker responds.
+// takeRestartToken spends one restart from the worker's token bucket, which
+// holds --max-restarts-per-minute tokens and refills at that rate, and
+// reports false if it is empty. Without a limit it always succeeds.
+func (w *Worker) takeRestartToken() bool {
+	if w.pool == nil || w.pool.restartRate <= 0 {
+		return true
+	}
+	limit := float64(w.pool.restartRate)
+	now := w.clock().Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.restartRefill.IsZero() {
+		w.restartTokens = limit
+	} else {
+		w.restartTokens = min(limit, w.restartTokens+now.Sub(w.restartRefill).Minutes()*limit)
+	}
+	w.restartRefill = now
+	if w.restartTokens < 1 {
+		return false
+	}
+	w.restartTokens--
+	return true
+}
+
+// noteFailure records an unplanned exit or failed restart and returns how
+// many have happened in a row. stable resets the count first: the process
+// had been running long enough that this is not part of a loop. Every
+// failure also counts toward the pool's degraded detection.
+func (w *Worker) noteFailure(msg string, stable bool) int {
+	w.mu.Lock()
+	if stable {
+		w.failures = 0
+	}
+	w.failures++
+	w.lastError = msg
+	n := w.failures
+	w.mu.Unlock()
+	if w.pool != nil {
+		w.pool.noteWorkerFailure(w, msg)
+	}
+	return n
+}
+
+// crashLooped reports whether attempts consecutive failures reach the pool's
+// crash-loop limit, so the worker should be replaced rather than restarted.
+func (w *Worker) crashLooped(attempts int) bool {
+	return w.pool != nil && w.pool.crashLoopLimit > 0 && attempts >= w.pool.crashLoopLimit
+}
+
+// Failures returns the worker's consecutive failure count and the most
+// recent failure, for /status.
+func (w *Worker) Failures() (n int, lastError string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.failures, w.lastError
+}
+
+// waitForReady polls the health probe until the worker passes it. gen is
+// the Start it belongs to; once the process exits or a later Start begins,
+// it is stale and gives up quietly without touching the worker. A process
+// still not ready after readyTimeout is killed and counted as a readiness
+// failure: monitor restarts it with backoff, and past the crash-loop limit
+// the pool replaces the worker.
 // run as a goroutine
-func (w *Worker) waitForReady() {
+func (w *Worker) waitForReady(gen uint64) {
 	client := &http.Client{Timeout: 1 * time.Second}
-	url := fmt.Sprintf("http://localhost:%d/health", w.Port)
+	url := w.BaseURL() + w.health.path()
 
-	for i := 0; i < 30; i++ {
+	deadline := time.Now().Add(w.readyTimeout)
+	for time.Now().Before(deadline) {
+		if !w.booting(gen) {
+			return
+		}
 		resp, err := client.Get(url)
-		if err == nil && resp.StatusCode == http.StatusOK {
+		if err == nil && w.health.healthy(resp.StatusCode) {
 			resp.Body.Close()
+			if err := w.smokeTest(); err != nil {
+				w.failSmokeTest(gen, err)
+				return
+			}
 			w.mu.Lock()
-			if w.state == WorkerStateStarting {
-				w.state = WorkerStateAvailable
-				log.Printf("[worker :%-5d] ready", w.Port)
+			if w.gen != gen || w.state != WorkerStateStarting {
+				w.mu.Unlock()
+				return // restarted or exited since this probe began
 			}
+			w.state = WorkerStateAvailable
+			w.readyAt = w.clock().Now()
+			w.idleSince = w.readyAt
+			w.logger().Info("ready", "boot_time", w.readyAt.Sub(w.startedAt).Round(time.Millisecond).String())
 			w.mu.Unlock()
-			// Push to the pool's available channel so queued requests can proceed
-			if w.pool != nil {
+			w.audit().Record(AuditWorkerReady, w, "")
+			// Hand it to the longest-waiting Acquire, or park it in the idle
+			// queue, unless it restarted in the meantime: the newer Start's
+			// waitForReady releases it then.
+			if w.pool != nil && w.isGen(gen) {
+				w.pool.markReady(w)
 				w.pool.Release(w)
 			}
 			return
@@ -162,47 +610,451 @@ func (w *Worker) waitForReady() {
 		if resp != nil {
 			resp.Body.Close()
 		}
-		time.Sleep(200 * time.Millisecond)
+		time.Sleep(readyPollInterval)
 	}
 
-	log.Printf("[worker :%-5d] failed to become ready after 6s", w.Port)
 	w.mu.Lock()
+	if w.gen != gen || w.state != WorkerStateStarting {
+		w.mu.Unlock()
+		return // exited (and maybe failed for good) while booting
+	}
 	w.state = WorkerStateUnhealthy
+	w.notReady = true
 	w.mu.Unlock()
+
+	w.logger().Error("failed to become ready — killing", "ready_timeout", w.readyTimeout.String())
+	if w.pool != nil {
+		w.pool.readyFailures.Add(1)
+	}
+	w.Kill("not ready")
+}
+
+// booting reports whether gen is still the worker's latest Start and its
+// process has not yet become ready.
+func (w *Worker) booting(gen uint64) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.gen == gen && w.state == WorkerStateStarting
+}
+
+// isGen reports whether gen is still the worker's latest Start.
+func (w *Worker) isGen(gen uint64) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.gen == gen
 }
 
-// HealthCheck pings the worker's /health endpoint. Returns true if healthy.
+// healthProbeTimeout bounds a single health probe.
+const healthProbeTimeout = 2 * time.Second
+
+// HealthCheck probes the worker's health endpoint. Returns true if healthy.
 func (w *Worker) HealthCheck() bool {
-	client := &http.Client{Timeout: 2 * time.Second}
-	url := fmt.Sprintf("http://localhost:%d/health", w.Port)
+	return w.healthCheck(context.Background()).Healthy
+}
 
-	resp, err := client.Get(url)
-	if err != nil {
-		return false
+// healthCheck is HealthCheck giving up when ctx is done, if that comes
+// before healthProbeTimeout. It records the result for /status and keeps
+// the worker's streak of failed probes.
+func (w *Worker) healthCheck(ctx context.Context) probeResult {
+	res, _ := w.probe(ctx, w.health.path(), w.health.healthy)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !res.Healthy {
+		res.Streak = w.lastProbe.Streak + 1
 	}
-	defer resp.Body.Close()
-	return resp.StatusCode == http.StatusOK
+	w.lastProbe = res
+	return res
 }
 
-// Kill forcefully terminates the worker process.
-func (w *Worker) Kill() {
+// probe GETs path on the worker within healthProbeTimeout and classifies
+// the outcome, healthy if ok accepts the status. The status is 0 if the
+// request failed.
+func (w *Worker) probe(ctx context.Context, path string, ok func(status int) bool) (probeResult, int) {
+	ctx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+	defer cancel()
+	start := w.clock().Now()
+	res := probeResult{At: start}
+	status := 0
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.BaseURL()+path, nil)
+	if err == nil {
+		var resp *http.Response
+		if resp, err = http.DefaultClient.Do(req); err == nil {
+			status = resp.StatusCode
+			res.Healthy = ok(status)
+			resp.Body.Close()
+			if !res.Healthy {
+				res.Failure = probeBadStatus
+			}
+		}
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, syscall.ECONNREFUSED):
+		res.Failure = probeRefused
+	case errors.Is(err, context.DeadlineExceeded):
+		res.Failure = probeTimeout
+	default:
+		res.Failure = probeError
+	}
+	res.Latency = w.clock().Since(start)
+	return res, status
+}
+
+// Ways a health probe can fail.
+const (
+	probeRefused   = "refused"    // nothing listening: the worker is most likely dead
+	probeTimeout   = "timeout"    // no answer in time: maybe just busy or pausing
+	probeBadStatus = "bad_status" // answered with a status not in --health-status
+	probeError     = "error"      // any other transport error
+)
+
+// probeResult is the outcome of a worker's most recent health probe.
+type probeResult struct {
+	At      time.Time // zero until the first probe
+	Latency time.Duration
+	Healthy bool
+	Failure string // why it failed, one of the probe* constants; "" if healthy
+	Streak  int    // failed probes in a row, this one included; reset by a healthy probe or Start
+}
+
+// fatal reports whether the failure kills the worker at once, whatever its
+// streak: nothing is listening, or its session API has lost a session.
+func (r probeResult) fatal() bool {
+	return r.Failure == probeRefused || r.Failure == probeSessionMissing
+}
+
+// LastProbe returns the worker's most recent health probe.
+func (w *Worker) LastProbe() probeResult {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lastProbe
+}
+
+// Kill forcefully terminates the worker process. reason is recorded in the
+// logs and the audit trail.
+func (w *Worker) Kill(reason string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.proc != nil {
+		pid := w.proc.Pid
+		w.logger().Info("killing", "pid", pid, "reason", reason)
+		w.audit().Record(AuditWorkerKill, w, reason, "pid", pid)
+		w.killRequested = true
+		_ = w.proc.Kill()
+	}
+}
+
+// Terminate drains the worker as a deliberate, final stop like Stop, but asks
+// the process to exit with SIGTERM so it can shut down cleanly. Pool.Shutdown
+// follows up with Kill if it has not exited in time.
+func (w *Worker) Terminate(reason string) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	w.draining = true
+	w.intentionalStop = true
+	if w.proc != nil && w.state != WorkerStateDead {
+		w.logger().Info("terminating", "pid", w.proc.Pid, "reason", reason)
+		w.audit().Record(AuditWorkerKill, w, reason, "pid", w.proc.Pid, "signal", "SIGTERM")
+		_ = w.proc.Signal(syscall.SIGTERM)
+	}
+}
 
-	if w.cmd != nil && w.cmd.Process != nil {
-		log.Printf("[worker :%-5d] killing (pid=%d)", w.Port, w.cmd.Process.Pid)
-		_ = w.cmd.Process.Kill()
+// Done returns a channel that is closed when the worker's current process
+// exits. It is already closed if no process is running.
+func (w *Worker) Done() <-chan struct{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.exited == nil {
+		return closedChan
 	}
+	return w.exited
+}
+
+var closedChan = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// Detach leaves the worker process running but forgets it: monitor treats
+// the eventual exit as intentional and the worker is never released or
+// restarted. Used on shutdown with --state-file so a session survives for
+// the next orchestrator to adopt.
+func (w *Worker) Detach() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.draining = true
+	w.intentionalStop = true
 }
 
-// Drain marks the worker so that monitor() will not restart it after exit.
-// Used by the pool during scale-down or graceful shutdown.
+// ReadyAt returns when the worker last became ready, or the zero time if it
+// never has (including workers adopted from a previous run).
+func (w *Worker) ReadyAt() time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.readyAt
+}
+
+// IdleFor returns how long an Available worker has held no session or
+// claim as of now, and 0 for one that holds any or is in another state.
+func (w *Worker) IdleFor(now time.Time) time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state != WorkerStateAvailable || w.loadLocked() > 0 || w.idleSince.IsZero() {
+		return 0
+	}
+	return now.Sub(w.idleSince)
+}
+
+// PID returns the worker's process ID, or 0 if it has never started.
+func (w *Worker) PID() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.proc == nil {
+		return 0
+	}
+	return w.proc.Pid
+}
+
+// Stop drains and kills the worker as a deliberate, final stop (scale-down or
+// shutdown). monitor() sees intentionalStop and exits without restarting.
+func (w *Worker) Stop(reason string) {
+	w.mu.Lock()
+	w.draining = true
+	w.intentionalStop = true
+	w.mu.Unlock()
+	w.Kill(reason)
+}
+
+// Drain marks the worker so that monitor() will not restart it after exit
+// and Release will no longer return it to the pool. Used by the pool during
+// scale-down; Stop drains implicitly.
 func (w *Worker) Drain() {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	w.draining = true
 }
 
+// sampleRSS reads the worker process's current resident set size and keeps
+// it for RSS.
+func (w *Worker) sampleRSS() (int64, error) {
+	rss, err := processRSS(w.PID())
+	if err != nil {
+		return 0, err
+	}
+	w.mu.Lock()
+	w.rss = rss
+	w.mu.Unlock()
+	return rss, nil
+}
+
+// RSS returns the resident set size in bytes from the last health sweep, or
+// 0 if it has not been sampled since the worker started.
+func (w *Worker) RSS() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rss
+}
+
+// sampleCPU reads the process's cumulative CPU time and, given an earlier
+// sample of the same process, updates the usage reported by CPUPercent.
+func (w *Worker) sampleCPU() error {
+	used, err := processCPUTime(w.PID())
+	if err != nil {
+		return err
+	}
+	now := time.Now() // CPU time is real time, whatever the pool's clock
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if wall := now.Sub(w.cpuAt); !w.cpuAt.IsZero() && wall > 0 {
+		w.cpuPercent = 100 * float64(used-w.cpuUsed) / float64(wall)
+	}
+	w.cpuUsed, w.cpuAt = used, now
+	return nil
+}
+
+// CPUPercent returns the worker's CPU usage between its last two samples,
+// where 100 is one core fully busy, or 0 before the second sample.
+func (w *Worker) CPUPercent() float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.cpuPercent
+}
+
+// killForMemory kills a worker whose RSS exceeded the pool's memory limit.
+// The exit is reported as exitMemoryLimit and the worker is restarted.
+func (w *Worker) killForMemory() {
+	w.mu.Lock()
+	w.memoryKill = true
+	w.mu.Unlock()
+	w.Kill("memory limit exceeded")
+}
+
+// Requests returns how many requests have been proxied to this worker since
+// it was created, across restarts.
+func (w *Worker) Requests() int64 {
+	return w.requests.Load()
+}
+
+// Binary returns the binary the worker's current process was started from.
+func (w *Worker) Binary() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.BinaryPath
+}
+
+// Restarts returns how many times monitor has restarted the worker's
+// process since the worker was created.
+func (w *Worker) Restarts() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.restartCount
+}
+
+// StartedAt returns when the current worker process was spawned, or adopted
+// from a previous orchestrator.
+func (w *Worker) StartedAt() time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.startedAt
+}
+
+// markRetiring flags the worker to be restarted once its session ends, for
+// reason. It returns false if it was already flagged.
+func (w *Worker) markRetiring(reason string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.retiring {
+		return false
+	}
+	w.retiring, w.retireReason = true, reason
+	return true
+}
+
+// retiringReason returns the reason given to markRetiring.
+func (w *Worker) retiringReason() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.retireReason
+}
+
+// setSurplus sets or clears the surplus flag.
+func (w *Worker) setSurplus(v bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.surplus = v
+}
+
+// isSurplus reports whether the worker is marked surplus.
+func (w *Worker) isSurplus() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.surplus
+}
+
+// takeSurplus clears the surplus flag and reports whether it was set, so
+// exactly one caller goes on to remove the worker.
+func (w *Worker) takeSurplus() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	was := w.surplus
+	w.surplus = false
+	return was
+}
+
+// windingDown reports whether the worker is retiring or surplus: it takes no
+// new sessions and leaves once its last one ends.
+func (w *Worker) windingDown() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.retiring || w.surplus
+}
+
+// isRetiring reports whether markRetiring has been called since the last Start.
+func (w *Worker) isRetiring() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.retiring
+}
+
+// isDraining reports whether Drain or Stop has been called.
+func (w *Worker) isDraining() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.draining
+}
+
+// markQueued claims the worker's single place in the available queue. It
+// returns false if the worker is already queued, is draining, cordoned or
+// quarantined, or is Busy (every session slot held by an Acquire caller or
+// a session).
+func (w *Worker) markQueued() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.queued || w.draining || w.cordoned || !w.quarantinedAt.IsZero() || w.state == WorkerStateBusy {
+		return false
+	}
+	w.queued = true
+	return true
+}
+
+// setCordoned sets or clears the cordoned flag and reports whether it
+// changed.
+func (w *Worker) setCordoned(v bool) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	was := w.cordoned
+	w.cordoned = v
+	return was != v
+}
+
+// startQuarantine quarantines the worker as of at. It returns false if it
+// is already quarantined or is draining.
+func (w *Worker) startQuarantine(at time.Time) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.quarantinedAt.IsZero() || w.draining {
+		return false
+	}
+	w.quarantinedAt = at
+	return true
+}
+
+// endQuarantine lifts the quarantine that began at since, reporting false
+// if it has already been lifted or a restart started a new one.
+func (w *Worker) endQuarantine(since time.Time) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.quarantinedAt.Equal(since) {
+		return false
+	}
+	w.quarantinedAt = time.Time{}
+	return true
+}
+
+// QuarantinedAt returns when the worker was quarantined, or the zero time
+// if it is not.
+func (w *Worker) QuarantinedAt() time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.quarantinedAt
+}
+
+// Cordoned reports whether the worker is cordoned.
+func (w *Worker) Cordoned() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.cordoned
+}
+
+// clearQueued records that the worker has been taken off the available queue.
+func (w *Worker) clearQueued() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.queued = false
+}
+
 // State returns the current worker state (thread-safe).
 func (w *Worker) State() WorkerState {
 	w.mu.Lock()
@@ -217,31 +1069,170 @@ func (w *Worker) SetState(s WorkerState) {
 	w.state = s
 }
 
-// SessionID returns the current session ID (thread-safe).
-func (w *Worker) SessionID() string {
+// Sessions returns the sessions the worker holds, oldest first (thread-safe).
+func (w *Worker) Sessions() []string {
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	return w.sessionID
+	return slices.Clone(w.sessions)
 }
 
-// SetSessionID updates the session ID and marks the worker busy/available.
-// When clearing a session (id == ""), the worker is released back to the pool.
-func (w *Worker) SetSessionID(id string) {
+// HasSession reports whether the worker holds sessionID.
+func (w *Worker) HasSession(sessionID string) bool {
 	w.mu.Lock()
-	w.sessionID = id
-	if id == "" {
-		w.state = WorkerStateAvailable
-	} else {
+	defer w.mu.Unlock()
+	return slices.Contains(w.sessions, sessionID)
+}
+
+// Capacity returns how many sessions the worker can hold at once.
+func (w *Worker) Capacity() int { return w.capacity }
+
+// Load returns the worker's taken slots: sessions plus pending claims.
+func (w *Worker) Load() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.loadLocked()
+}
+
+func (w *Worker) loadLocked() int { return len(w.sessions) + w.claims }
+
+// freeSlots returns how many more sessions an Available worker can take;
+// 0 in any other state.
+func (w *Worker) freeSlots() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state != WorkerStateAvailable {
+		return 0
+	}
+	return max(w.capacity-w.loadLocked(), 0)
+}
+
+// AddSession records a session created on the worker, turning the slot its
+// Acquire claimed into the session's. The worker is Busy once every slot is
+// taken.
+func (w *Worker) AddSession(sessionID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.claims > 0 {
+		w.claims--
+	}
+	w.sessions = append(w.sessions, sessionID)
+	if w.loadLocked() >= w.capacity {
 		w.state = WorkerStateBusy
 	}
+}
+
+// RemoveSession forgets a session that has ended and frees its slot. A
+// session the worker does not hold changes nothing, so a second remove, or
+// one for a worker that has since restarted, can never free a slot another
+// caller holds.
+func (w *Worker) RemoveSession(sessionID string) {
+	w.mu.Lock()
+	i := slices.Index(w.sessions, sessionID)
+	if i < 0 {
+		w.mu.Unlock()
+		return
+	}
+	w.sessions = slices.Delete(w.sessions, i, i+1)
+	w.freeSlotLocked()
+}
+
+// Unclaim gives back a slot taken by Acquire when no session was created on
+// it (the worker rejected the create, or the session turned out to be a
+// duplicate).
+func (w *Worker) Unclaim() {
+	w.mu.Lock()
+	if w.claims == 0 {
+		w.mu.Unlock()
+		return
+	}
+	w.claims--
+	w.freeSlotLocked()
+}
+
+// freeSlotLocked finishes RemoveSession and Unclaim: a Busy worker with a
+// free slot turns Available, and an Available one is released back to the
+// pool, where it is queued unless it already is. A worker that has since
+// died or turned unhealthy is left alone. Called with w.mu held; unlocks it.
+func (w *Worker) freeSlotLocked() {
+	if w.loadLocked() == 0 {
+		w.idleSince = w.clock().Now()
+	}
+	release := false
+	switch w.state {
+	case WorkerStateBusy:
+		if w.loadLocked() < w.capacity {
+			w.state = WorkerStateAvailable
+			release = true
+		}
+	case WorkerStateAvailable:
+		release = true
+	}
 	w.mu.Unlock()
 
-	// Release back to pool when session is cleared
-	if id == "" && w.pool != nil {
+	if release && w.pool != nil {
 		w.pool.Release(w)
 	}
 }
 
+// claim takes a slot on an Available worker for the Acquire caller it is
+// being handed to, and reports whether it was Available with a slot free.
+// The worker turns Busy when that was its last free slot; from then until a
+// slot is freed, nothing can queue it again.
+func (w *Worker) claim() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state != WorkerStateAvailable || w.loadLocked() >= w.capacity {
+		return false
+	}
+	w.claims++
+	w.idleSince = time.Time{}
+	if w.loadLocked() >= w.capacity {
+		w.state = WorkerStateBusy
+	}
+	return true
+}
+
+// logger returns the worker logger tagged with the worker's identity.
+func (w *Worker) logger() *slog.Logger {
+	l := logger("worker").With("worker_id", w.ID, "port", w.Port)
+	if w.pool != nil && w.pool.tag != "" {
+		l = l.With("pool", w.pool.tag)
+	}
+	return l
+}
+
+// Tag returns the tag of the pool the worker belongs to.
+func (w *Worker) Tag() string {
+	if w.pool == nil {
+		return defaultPoolTag
+	}
+	return w.pool.Tag()
+}
+
+// events returns the pool's event log, or nil if the worker has no pool.
+func (w *Worker) events() *EventLog {
+	if w.pool == nil {
+		return nil
+	}
+	return w.pool.events
+}
+
+// audit returns the pool's audit log, or nil if the worker has no pool.
+func (w *Worker) audit() *AuditLog {
+	if w.pool == nil {
+		return nil
+	}
+	return w.pool.audit
+}
+
+// clock returns the pool's clock, or the wall clock for a standalone worker.
+func (w *Worker) clock() clock.Clock {
+	if w.pool == nil {
+		return clock.Real
+	}
+	return w.pool.clk
+}
+
 // BaseURL returns the worker's base URL.
 func (w *Worker) BaseURL() string {
 	return fmt.Sprintf("http://localhost:%d", w.Port)
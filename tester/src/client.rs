@@ -96,6 +96,26 @@ impl OrchestratorClient {
             .map_err(|e| format!("failed to read health response: {e}"))
     }
 
+    /// GET /status — pool and worker state as raw JSON.
+    pub async fn status(&self) -> Result<serde_json::Value, String> {
+        let resp = self
+            .http
+            .get(format!("{}/status", self.base_url))
+            .send()
+            .await
+            .map_err(|e| format!("GET /status request failed: {e}"))?;
+
+        let status = resp.status();
+        if !status.is_success() {
+            let body = resp.text().await.unwrap_or_default();
+            return Err(format!("GET /status returned {status}: {body}"));
+        }
+
+        resp.json::<serde_json::Value>()
+            .await
+            .map_err(|e| format!("failed to parse status response: {e}"))
+    }
+
     /// POST /debug/crash-worker?session_id=:id — kills the worker holding the session (testing only).
     pub async fn crash_worker(&self, session_id: &str) -> Result<(), String> {
         let resp = self
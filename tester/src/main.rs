@@ -45,6 +45,7 @@ async fn main() {
     runner.add_group("Concurrency", tests::concurrent::tests());
     runner.add_group("TTL Expiration", tests::ttl::tests());
     runner.add_group("Recovery", tests::recovery::tests());
+    runner.add_group("Scaling", tests::scaling::tests());
 
     // Run all tests
     let (passed, total) = runner.run(&client).await;
@@ -2,3 +2,4 @@ pub mod crud;
 pub mod concurrent;
 pub mod ttl;
 pub mod recovery;
+pub mod scaling;
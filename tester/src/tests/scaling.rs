@@ -0,0 +1,72 @@
+use crate::client::OrchestratorClient;
+use crate::runner::TestCase;
+
+/// Register auto-scaling test cases.
+pub fn tests() -> Vec<TestCase> {
+    vec![TestCase {
+        name: "Scale-up when all workers are busy".to_string(),
+        func: Box::new(|client: &OrchestratorClient| {
+            Box::pin(test_scale_up(client))
+        }),
+    }]
+}
+
+/// Read `worker_count` and `max_workers` from GET /status.
+async fn pool_size(client: &OrchestratorClient) -> Result<(u64, u64), String> {
+    let status = client.status().await?;
+    let count = status["worker_count"]
+        .as_u64()
+        .ok_or("status has no worker_count")?;
+    let max = status["max_workers"]
+        .as_u64()
+        .ok_or("status has no max_workers")?;
+    Ok((count, max))
+}
+
+/// Occupy every worker plus one more and verify the pool grew to serve it.
+///
+/// Strategy:
+///   1. Read the current pool size from /status.
+///   2. Create one session more than there are workers, without deleting any,
+///      so the last create can only succeed on a newly spawned worker.
+///   3. Verify /status reports more workers than before.
+async fn test_scale_up(client: &OrchestratorClient) -> Result<(), String> {
+    let (before, max) = pool_size(client)
+        .await
+        .map_err(|e| format!("phase 1: {e}"))?;
+    if before >= max {
+        return Err(format!(
+            "phase 1: pool already at max_workers ({max}); run with max above min"
+        ));
+    }
+
+    let mut session_ids = Vec::new();
+    let mut result = Ok(());
+    for i in 0..=before {
+        let data = serde_json::json!({"user": format!("scale_{i}")});
+        match client.create_session(data).await {
+            Ok(session) => session_ids.push(session.id),
+            Err(e) => {
+                result = Err(format!("phase 2: create {} failed: {e}", i + 1));
+                break;
+            }
+        }
+    }
+
+    if result.is_ok() {
+        result = match pool_size(client).await {
+            Ok((after, _)) if after > before => Ok(()),
+            Ok((after, _)) => Err(format!(
+                "phase 3: expected more than {before} workers, got {after}"
+            )),
+            Err(e) => Err(format!("phase 3: {e}")),
+        };
+    }
+
+    // Cleanup (always, even if a phase failed)
+    for id in &session_ids {
+        let _ = client.delete_session(id).await;
+    }
+
+    result
+}